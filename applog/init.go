@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/daprclient"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
 	"runtime"
 	"strings"
 	"time"
@@ -39,6 +40,24 @@ func Init(daprClient daprclient.DaprDddClient, aAppId string, level Level) {
 	appId = aAppId
 }
 
+//
+// GetLogger
+// @Description: 获取当前使用的日志记录器
+// @return Logger
+//
+func GetLogger() Logger {
+	return log
+}
+
+//
+// SetLogger
+// @Description: 设置日志记录器，用于测试时替换或还原日志记录器
+// @param logger 日志记录器
+//
+func SetLogger(logger Logger) {
+	log = logger
+}
+
 //
 // DoEventLog
 // @Description: 执行日志记录
@@ -52,9 +71,9 @@ func Init(daprClient daprclient.DaprDddClient, aAppId string, level Level) {
 func DoEventLog(ctx context.Context, structNameFunc func() string, event Event, funcName string, fun DoAction) error {
 	err := fun()
 	if err == nil {
-		_, _ = InfoEvent(event.GetTenantId(), structNameFunc(), funcName, "success", event.GetEventId(), event.GetCommandId(), "")
+		_, _ = writeEventLog(ctx, event.GetTenantId(), structNameFunc(), funcName, INFO, "success", event.GetEventId(), event.GetCommandId(), "", false)
 	} else {
-		_, _ = ErrorEvent(event.GetTenantId(), structNameFunc(), funcName, "error", event.GetEventId(), event.GetCommandId(), "")
+		_, _ = writeEventLog(ctx, event.GetTenantId(), structNameFunc(), funcName, ERROR, "error", event.GetEventId(), event.GetCommandId(), "", false)
 	}
 	return nil
 }
@@ -240,6 +259,8 @@ func writeEventLog(ctx context.Context, tenantId, structName, funcName string, l
 		EventId:   eventId,
 		CommandId: commandId,
 		PubAppId:  pubAppId,
+
+		CorrelationId: ddd_context.GetCorrelationId(ctx),
 	}
 
 	/*
@@ -356,6 +377,8 @@ func writeAppLog(ctx context.Context, tenantId, className, funcName string, leve
 		Time:     &timeNow,
 		Status:   true,
 		Message:  message,
+
+		CorrelationId: ddd_context.GetCorrelationId(ctx),
 	}
 
 	logrus.WithFields(logrus.Fields{
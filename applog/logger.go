@@ -102,6 +102,8 @@ type WriteEventLogRequest struct {
 	EventId  string `json:"eventId"`
 	// EventType string `json:"eventType"`
 	CommandId string `json:"commandId"`
+
+	CorrelationId string `json:"correlationId,omitempty"` // 业务链路id
 }
 
 type WriteEventLogResponse struct {
@@ -166,6 +168,8 @@ type WriteAppLogRequest struct {
 	Time     *time.Time `json:"time"`
 	Status   bool       `json:"status"`
 	Message  string     `json:"message"`
+
+	CorrelationId string `json:"correlationId,omitempty"` // 业务链路id
 }
 
 type WriteAppLogResponse struct {
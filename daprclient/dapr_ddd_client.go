@@ -4,13 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_utils"
 	dapr_sdk_client "github.com/liuxd6825/go-sdk/client"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
 	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -155,6 +158,7 @@ func (c *daprDddClient) InvokeService(ctx context.Context, appID, methodName, ve
 		}
 	}()
 	var respBytes []byte
+	ctx = newCorrelationOutgoingContext(ctx)
 
 	if request != nil {
 		reqBytes, err := json.Marshal(request)
@@ -189,6 +193,26 @@ func (c *daprDddClient) InvokeService(ctx context.Context, appID, methodName, ve
 	return nil, nil
 }
 
+//
+//  newCorrelationOutgoingContext
+//  @Description: 将上下文中的业务链路id与起因id写入grpc metadata，随服务调用转发给被调用方
+//  @param ctx
+//  @return context.Context
+//
+func newCorrelationOutgoingContext(ctx context.Context) context.Context {
+	var pairs []string
+	if correlationId := ddd_context.GetCorrelationId(ctx); len(correlationId) > 0 {
+		pairs = append(pairs, strings.ToLower(ddd_context.CorrelationIdHeader), correlationId)
+	}
+	if causationId := ddd_context.GetCausationId(ctx); len(causationId) > 0 {
+		pairs = append(pairs, strings.ToLower(ddd_context.CausationIdHeader), causationId)
+	}
+	if len(pairs) == 0 {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
 func (c *daprDddClient) DaprClient() (dapr_sdk_client.Client, error) {
 	return c.grpcClient, nil
 }
@@ -74,6 +74,7 @@ type EventRecord struct {
 	EventType      string                 `json:"eventType"`
 	EventVersion   string                 `json:"eventVersion"`
 	SequenceNumber uint64                 `json:"sequenceNumber"`
+	Metadata       map[string]string      `json:"metadata"`
 }
 
 // NewEventRecordByJsonBytes 通过json反序列化EventRecord
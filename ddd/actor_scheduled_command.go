@@ -0,0 +1,74 @@
+package ddd
+
+import (
+	"context"
+	dapr "github.com/liuxd6825/go-sdk/client"
+	"time"
+)
+
+//
+// ScheduledCommandActor
+// @Description: 定时命令Actor客户端，每个租户对应一个Actor实例
+//
+type ScheduledCommandActor struct {
+	tenantId string
+	Schedule func(ctx context.Context, req *ScheduleCommandRequest) (*ScheduleCommandResponse, error)
+	Cancel   func(ctx context.Context, req *CancelScheduledCommandRequest) (*CancelScheduledCommandResponse, error)
+	List     func(ctx context.Context, req *ListScheduledCommandsRequest) (*ListScheduledCommandsResponse, error)
+}
+
+func (a *ScheduledCommandActor) Type() string {
+	return scheduledCommandActorType
+}
+
+func (a *ScheduledCommandActor) ID() string {
+	return a.tenantId
+}
+
+//
+// ScheduledCommand
+// @Description: 定时执行的命令
+//
+type ScheduledCommand struct {
+	TenantId      string            `json:"tenantId"`
+	CommandId     string            `json:"commandId"`
+	CommandType   string            `json:"commandType"` // 命令注册键，格式为 聚合根类型.命令类型
+	AggregateType string            `json:"aggregateType"`
+	AggregateId   string            `json:"aggregateId"`
+	Command       []byte            `json:"command"`
+	Metadata      map[string]string `json:"metadata"`
+	DueTime       time.Time         `json:"dueTime"`
+	CreatedTime   time.Time         `json:"createdTime"`
+	Attempts      int               `json:"attempts"`  // 执行失败的次数
+	LastError     string            `json:"lastError"` // 最近一次执行失败的错误
+}
+
+type ScheduleCommandRequest struct {
+	Command *ScheduledCommand `json:"command"`
+}
+
+type ScheduleCommandResponse struct {
+}
+
+type CancelScheduledCommandRequest struct {
+	CommandId string `json:"commandId"`
+}
+
+type CancelScheduledCommandResponse struct {
+	Found bool `json:"found"`
+}
+
+type ListScheduledCommandsRequest struct {
+}
+
+type ListScheduledCommandsResponse struct {
+	Commands []*ScheduledCommand `json:"commands"`
+}
+
+func NewScheduledCommandClient(client dapr.Client, tenantId string) *ScheduledCommandActor {
+	actor := &ScheduledCommandActor{
+		tenantId: tenantId,
+	}
+	client.ImplActorClientStub(actor)
+	return actor
+}
@@ -0,0 +1,255 @@
+package ddd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/applog"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
+	"github.com/liuxd6825/go-sdk/actor"
+	dapr "github.com/liuxd6825/go-sdk/client"
+	"sort"
+	"time"
+)
+
+const scheduledCommandActorType = "ddd.ScheduledCommandActorType"
+
+const scheduledCommandsStateName = "scheduledCommands"
+
+const (
+	scheduledCommandMaxAttempts   = 5           // 定时命令最多执行次数，超过后保留在定时命令列表中不再执行
+	scheduledCommandRetryInterval = time.Minute // 定时命令执行失败后的首次重试间隔，之后每次加倍
+)
+
+//
+// ScheduledCommandActorService
+// @Description: 定时命令Actor服务，使用Actor状态保存租户的定时命令，使用Actor提醒触发命令执行
+//
+type ScheduledCommandActorService struct {
+	actor.ServerImplBase
+	daprClient dapr.Client
+}
+
+func (s *ScheduledCommandActorService) Type() string {
+	return scheduledCommandActorType
+}
+
+//
+// Schedule
+// @Description: 保存定时命令，并注册Actor提醒。相同命令id重复调用时覆盖原有定时命令
+// @receiver s
+// @param ctx
+// @param req
+// @return *ScheduleCommandResponse
+// @return error
+//
+func (s *ScheduledCommandActorService) Schedule(ctx context.Context, req *ScheduleCommandRequest) (*ScheduleCommandResponse, error) {
+	if req == nil || req.Command == nil {
+		return nil, errors.New("ScheduledCommandActorService.Schedule() command is nil")
+	}
+	commands, err := s.getCommands()
+	if err != nil {
+		return nil, err
+	}
+	cmd := req.Command
+	commands[cmd.CommandId] = cmd
+	if err := s.setCommands(commands); err != nil {
+		return nil, err
+	}
+	if err := s.registerReminder(ctx, cmd); err != nil {
+		return nil, err
+	}
+	return &ScheduleCommandResponse{}, nil
+}
+
+//
+// Cancel
+// @Description: 取消定时命令
+// @receiver s
+// @param ctx
+// @param req
+// @return *CancelScheduledCommandResponse
+// @return error
+//
+func (s *ScheduledCommandActorService) Cancel(ctx context.Context, req *CancelScheduledCommandRequest) (*CancelScheduledCommandResponse, error) {
+	commands, err := s.getCommands()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := commands[req.CommandId]; !ok {
+		return &CancelScheduledCommandResponse{Found: false}, nil
+	}
+	err = s.daprClient.UnregisterActorReminder(ctx, &dapr.UnregisterActorReminderRequest{
+		ActorType: s.Type(),
+		ActorID:   s.ID(),
+		Name:      req.CommandId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	delete(commands, req.CommandId)
+	if err := s.setCommands(commands); err != nil {
+		return nil, err
+	}
+	return &CancelScheduledCommandResponse{Found: true}, nil
+}
+
+//
+// List
+// @Description: 获取租户的所有定时命令，按执行时间排序
+// @receiver s
+// @param ctx
+// @param req
+// @return *ListScheduledCommandsResponse
+// @return error
+//
+func (s *ScheduledCommandActorService) List(ctx context.Context, req *ListScheduledCommandsRequest) (*ListScheduledCommandsResponse, error) {
+	commands, err := s.getCommands()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ScheduledCommand, 0, len(commands))
+	for _, cmd := range commands {
+		list = append(list, cmd)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].DueTime.Before(list[j].DueTime)
+	})
+	return &ListScheduledCommandsResponse{Commands: list}, nil
+}
+
+//
+// ReminderCall
+// @Description: Actor提醒回调，执行到期的定时命令。执行成功后从定时命令列表中移除；
+// 执行失败时记录失败次数与错误，并按加倍的间隔重新注册提醒，失败次数达到上限后保留在定时命令列表中不再执行，可通过 List 查看、Cancel 移除
+// @receiver s
+// @param reminderName 提醒名称，即命令id
+// @param state
+// @param dueTime
+// @param period
+//
+func (s *ScheduledCommandActorService) ReminderCall(reminderName string, state []byte, dueTime string, period string) {
+	commands, err := s.getCommands()
+	if err != nil {
+		_, _ = applog.Error(s.ID(), "ddd.ScheduledCommandActorService", "ReminderCall", err.Error())
+		return
+	}
+	cmd, ok := commands[reminderName]
+	if !ok {
+		return
+	}
+	ctx := context.Background()
+	execErr := executeScheduledCommand(ctx, cmd)
+	if execErr == nil {
+		delete(commands, reminderName)
+		if err := s.setCommands(commands); err != nil {
+			// 命令已执行，提醒已结束，不会再次执行；列表中的残留可通过 Cancel 移除
+			_, _ = applog.Error(cmd.TenantId, "ddd.ScheduledCommandActorService", "ReminderCall",
+				fmt.Sprintf("commandId=%s; commandType=%s; executed but not removed: %s", cmd.CommandId, cmd.CommandType, err.Error()))
+		}
+		return
+	}
+
+	cmd.Attempts++
+	cmd.LastError = execErr.Error()
+	retry := cmd.Attempts < scheduledCommandMaxAttempts
+	if retry {
+		cmd.DueTime = time.Now().Add(getScheduledCommandRetryDelay(cmd.Attempts))
+	}
+	_, _ = applog.Error(cmd.TenantId, "ddd.ScheduledCommandActorService", "ReminderCall",
+		fmt.Sprintf("commandId=%s; commandType=%s; attempts=%d; retry=%v; error=%s", cmd.CommandId, cmd.CommandType, cmd.Attempts, retry, execErr.Error()))
+	if err := s.setCommands(commands); err != nil {
+		_, _ = applog.Error(cmd.TenantId, "ddd.ScheduledCommandActorService", "ReminderCall",
+			fmt.Sprintf("commandId=%s; commandType=%s; save attempts error: %s", cmd.CommandId, cmd.CommandType, err.Error()))
+	}
+	if !retry {
+		return
+	}
+	if err := s.registerReminder(ctx, cmd); err != nil {
+		_, _ = applog.Error(cmd.TenantId, "ddd.ScheduledCommandActorService", "ReminderCall",
+			fmt.Sprintf("commandId=%s; commandType=%s; register retry reminder error: %s", cmd.CommandId, cmd.CommandType, err.Error()))
+	}
+}
+
+//
+//  registerReminder
+//  @Description: 注册定时命令的Actor提醒，在命令的执行时间触发
+//
+func (s *ScheduledCommandActorService) registerReminder(ctx context.Context, cmd *ScheduledCommand) error {
+	return s.daprClient.RegisterActorReminder(ctx, &dapr.RegisterActorReminderRequest{
+		ActorType: s.Type(),
+		ActorID:   s.ID(),
+		Name:      cmd.CommandId,
+		DueTime:   getReminderDueTime(cmd.DueTime),
+	})
+}
+
+func (s *ScheduledCommandActorService) getCommands() (map[string]*ScheduledCommand, error) {
+	commands := make(map[string]*ScheduledCommand)
+	stateManager := s.GetStateManager()
+	if stateManager == nil {
+		return nil, errors.New("ScheduledCommandActorService stateManager is nil")
+	}
+	ok, err := stateManager.Contains(scheduledCommandsStateName)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if err := stateManager.Get(scheduledCommandsStateName, &commands); err != nil {
+			return nil, err
+		}
+	}
+	return commands, nil
+}
+
+func (s *ScheduledCommandActorService) setCommands(commands map[string]*ScheduledCommand) error {
+	if err := s.GetStateManager().Set(scheduledCommandsStateName, commands); err != nil {
+		return err
+	}
+	return s.SaveState()
+}
+
+//
+//  executeScheduledCommand
+//  @Description: 还原命令与上下文，通过 CommandAggregate 执行命令
+//  @param ctx
+//  @param scheduled 定时命令
+//  @return error
+//
+func executeScheduledCommand(ctx context.Context, scheduled *ScheduledCommand) error {
+	cmd, err := NewCommand(scheduled.CommandType, scheduled.Command)
+	if err != nil {
+		return err
+	}
+	aggregate, err := NewAggregate(scheduled.AggregateType)
+	if err != nil {
+		return err
+	}
+	ctx = ddd_context.NewContext(ctx, scheduled.Metadata, nil)
+	return CommandAggregate(ctx, aggregate, cmd)
+}
+
+//
+//  getScheduledCommandRetryDelay
+//  @Description: 第attempts次执行失败后的重试间隔，首次为 scheduledCommandRetryInterval，之后每次加倍
+//
+func getScheduledCommandRetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	return scheduledCommandRetryInterval * time.Duration(1<<(attempts-1))
+}
+
+func getReminderDueTime(at time.Time) string {
+	d := time.Until(at)
+	if d < 0 {
+		d = 0
+	}
+	return d.String()
+}
+
+func NewScheduledCommandActorService(daprClient dapr.Client) *ScheduledCommandActorService {
+	return &ScheduledCommandActorService{
+		daprClient: daprClient,
+	}
+}
@@ -3,6 +3,8 @@ package ddd
 import (
 	"errors"
 	"fmt"
+	"sort"
+	"sync"
 )
 
 // Aggregate 聚合根接口类
@@ -17,28 +19,235 @@ type NewAggregateFunc func() Aggregate
 
 type AggregateTypes map[string]NewAggregateFunc
 
-var aggregateTypes = AggregateTypes{}
+//
+// AggregateTypeInfo
+// @Description: 聚合根类型描述信息
+//
+type AggregateTypeInfo struct {
+	AggregateType   string                    `json:"aggregateType"`
+	EventTypes      []string                  `json:"eventTypes"`
+	CommandTypes    []string                  `json:"commandTypes"`
+	EventStorageKey string                    `json:"eventStorageKey"`
+	PubsubName      string                    `json:"pubsubName"`
+	Snapshot        *AggregateSnapshotOptions `json:"snapshot"`
+}
+
+//
+// AggregateSnapshotOptions
+// @Description: 聚合根快照设置
+//
+type AggregateSnapshotOptions struct {
+	Enabled        bool `json:"enabled"`        // 是否生成快照
+	EventsMinCount int  `json:"eventsMinCount"` // 未快照的事件数量超过此值时生成快照
+}
+
+type RegisterAggregateOption func(*AggregateTypeInfo)
+
+//
+// AggregateEventTypes
+// @Description: 设置聚合根的领域事件类型
+// @param eventTypes 事件类型
+// @return RegisterAggregateOption
+//
+func AggregateEventTypes(eventTypes ...string) RegisterAggregateOption {
+	return func(info *AggregateTypeInfo) {
+		info.EventTypes = append(info.EventTypes, eventTypes...)
+	}
+}
+
+//
+// AggregateCommandTypes
+// @Description: 设置聚合根的命令类型
+// @param commandTypes 命令类型
+// @return RegisterAggregateOption
+//
+func AggregateCommandTypes(commandTypes ...string) RegisterAggregateOption {
+	return func(info *AggregateTypeInfo) {
+		info.CommandTypes = append(info.CommandTypes, commandTypes...)
+	}
+}
+
+//
+// AggregateEventStorageKey
+// @Description: 设置聚合根使用的事件存储器
+// @param eventStorageKey 事件存储器key
+// @return RegisterAggregateOption
+//
+func AggregateEventStorageKey(eventStorageKey string) RegisterAggregateOption {
+	return func(info *AggregateTypeInfo) {
+		info.EventStorageKey = eventStorageKey
+	}
+}
+
+//
+// AggregatePubsubName
+// @Description: 设置聚合根领域事件发布使用的pubsub
+// @param pubsubName pubsub名称
+// @return RegisterAggregateOption
+//
+func AggregatePubsubName(pubsubName string) RegisterAggregateOption {
+	return func(info *AggregateTypeInfo) {
+		info.PubsubName = pubsubName
+	}
+}
+
+//
+// AggregateSnapshot
+// @Description: 设置聚合根快照
+// @param enabled 是否生成快照
+// @param eventsMinCount 未快照的事件数量超过此值时生成快照，小于等于0时使用默认值
+// @return RegisterAggregateOption
+//
+func AggregateSnapshot(enabled bool, eventsMinCount int) RegisterAggregateOption {
+	return func(info *AggregateTypeInfo) {
+		if eventsMinCount <= 0 {
+			eventsMinCount = snapshotEventsMinCount
+		}
+		info.Snapshot = &AggregateSnapshotOptions{Enabled: enabled, EventsMinCount: eventsMinCount}
+	}
+}
+
+// 聚合根类型注册表
+type aggregateTypeRegistry struct {
+	mu       sync.RWMutex
+	newFuncs AggregateTypes
+	infos    map[string]*AggregateTypeInfo
+}
+
+var aggregateTypes = &aggregateTypeRegistry{
+	newFuncs: AggregateTypes{},
+	infos:    make(map[string]*AggregateTypeInfo),
+}
 
-func RegisterAggregateType(aggregateType string, fn NewAggregateFunc) {
+//
+// RegisterAggregateType
+// @Description: 注册聚合根类型
+// @param aggregateType 聚合根类型
+// @param fn 新建聚合根方法
+// @param opts 聚合根描述选项
+//
+func RegisterAggregateType(aggregateType string, fn NewAggregateFunc, opts ...RegisterAggregateOption) {
 	if aggregateType == "" {
 		panic(errors.New("aggregateType is cannot be empty"))
 	}
 	if fn == nil {
 		panic(errors.New("fn is cannot be nil"))
 	}
-	if t := aggregateTypes[aggregateType]; t != nil {
+	info := &AggregateTypeInfo{
+		AggregateType: aggregateType,
+		EventTypes:    make([]string, 0),
+		CommandTypes:  make([]string, 0),
+		Snapshot:      &AggregateSnapshotOptions{Enabled: true, EventsMinCount: snapshotEventsMinCount},
+	}
+	for _, opt := range opts {
+		opt(info)
+	}
+
+	aggregateTypes.mu.Lock()
+	defer aggregateTypes.mu.Unlock()
+	if t := aggregateTypes.newFuncs[aggregateType]; t != nil {
 		panic(errors.New(fmt.Sprintf("aggregateType %s already exists", aggregateType)))
 	}
-	aggregateTypes[aggregateType] = fn
+	aggregateTypes.newFuncs[aggregateType] = fn
+	aggregateTypes.infos[aggregateType] = info
 }
 
 func NewAggregate(aggregateType string) (Aggregate, error) {
 	if aggregateType == "" {
 		return nil, errors.New("aggregateType is cannot be empty")
 	}
-	fn := aggregateTypes[aggregateType]
+	aggregateTypes.mu.RLock()
+	fn := aggregateTypes.newFuncs[aggregateType]
+	aggregateTypes.mu.RUnlock()
 	if fn == nil {
 		return nil, errors.New(fmt.Sprintf("aggregateType %s not registered ", aggregateType))
 	}
 	return fn(), nil
 }
+
+//
+// GetAggregateTypeInfo
+// @Description: 获取聚合根类型描述信息，命令类型包含通过 RegisterCommandType 注册的命令
+// @param aggregateType 聚合根类型
+// @return *AggregateTypeInfo
+// @return bool 是否已注册
+//
+func GetAggregateTypeInfo(aggregateType string) (*AggregateTypeInfo, bool) {
+	aggregateTypes.mu.RLock()
+	info, ok := aggregateTypes.infos[aggregateType]
+	aggregateTypes.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	return info.clone(), true
+}
+
+//
+// GetAggregateTypeInfos
+// @Description: 获取所有已注册的聚合根类型描述信息，按聚合根类型排序
+// @return []*AggregateTypeInfo
+//
+func GetAggregateTypeInfos() []*AggregateTypeInfo {
+	aggregateTypes.mu.RLock()
+	res := make([]*AggregateTypeInfo, 0, len(aggregateTypes.infos))
+	for _, info := range aggregateTypes.infos {
+		res = append(res, info)
+	}
+	aggregateTypes.mu.RUnlock()
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].AggregateType < res[j].AggregateType
+	})
+	for i, info := range res {
+		res[i] = info.clone()
+	}
+	return res
+}
+
+//
+//  getAggregateTypeInfo
+//  @Description: 获取聚合根类型描述信息的副本，未注册时返回默认设置
+//  @param aggregateType 聚合根类型
+//  @return *AggregateTypeInfo
+//
+func getAggregateTypeInfo(aggregateType string) *AggregateTypeInfo {
+	aggregateTypes.mu.RLock()
+	defer aggregateTypes.mu.RUnlock()
+	if info, ok := aggregateTypes.infos[aggregateType]; ok {
+		res := *info
+		if info.Snapshot != nil {
+			snapshot := *info.Snapshot
+			res.Snapshot = &snapshot
+		}
+		return &res
+	}
+	return &AggregateTypeInfo{
+		AggregateType: aggregateType,
+		Snapshot:      &AggregateSnapshotOptions{Enabled: true, EventsMinCount: snapshotEventsMinCount},
+	}
+}
+
+func (i *AggregateTypeInfo) clone() *AggregateTypeInfo {
+	res := *i
+	res.EventTypes = append(make([]string, 0, len(i.EventTypes)), i.EventTypes...)
+	res.CommandTypes = append(make([]string, 0, len(i.CommandTypes)), i.CommandTypes...)
+	for _, commandType := range getRegisteredCommandTypes(i.AggregateType) {
+		if !containsString(res.CommandTypes, commandType) {
+			res.CommandTypes = append(res.CommandTypes, commandType)
+		}
+	}
+	if i.Snapshot != nil {
+		snapshot := *i.Snapshot
+		res.Snapshot = &snapshot
+	}
+	return &res
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package ddd
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+)
+
+type catalogAggregate struct {
+}
+
+func (a *catalogAggregate) GetTenantId() string         { return "" }
+func (a *catalogAggregate) GetAggregateId() string      { return "" }
+func (a *catalogAggregate) GetAggregateType() string    { return "test.CatalogAggregate" }
+func (a *catalogAggregate) GetAggregateVersion() string { return "1.0" }
+
+func TestRegisterAggregateType_Options(t *testing.T) {
+	RegisterAggregateType("test.CatalogAggregate", func() Aggregate { return &catalogAggregate{} },
+		AggregateEventTypes("test.CatalogCreatedEvent"),
+		AggregateCommandTypes("CreateCatalogCommand"),
+		AggregateEventStorageKey("catalog"),
+		AggregatePubsubName("catalog-pubsub"),
+		AggregateSnapshot(false, 0),
+	)
+
+	info, ok := GetAggregateTypeInfo("test.CatalogAggregate")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"test.CatalogCreatedEvent"}, info.EventTypes)
+	assert.Equal(t, []string{"CreateCatalogCommand"}, info.CommandTypes)
+	assert.Equal(t, "catalog", info.EventStorageKey)
+	assert.Equal(t, "catalog-pubsub", info.PubsubName)
+	assert.Equal(t, &AggregateSnapshotOptions{Enabled: false, EventsMinCount: snapshotEventsMinCount}, info.Snapshot)
+
+	// 返回的是副本，修改后不影响注册表
+	info.EventTypes[0] = "changed"
+	info, _ = GetAggregateTypeInfo("test.CatalogAggregate")
+	assert.Equal(t, "test.CatalogCreatedEvent", info.EventTypes[0])
+	typeInfo := getAggregateTypeInfo("test.CatalogAggregate")
+	typeInfo.PubsubName = "changed"
+	typeInfo.Snapshot.Enabled = true
+	assert.Equal(t, "catalog-pubsub", getAggregateTypeInfo("test.CatalogAggregate").PubsubName)
+	assert.False(t, getAggregateTypeInfo("test.CatalogAggregate").Snapshot.Enabled)
+
+	assert.Equal(t, "", getAggregateTypeInfo("test.UnknownAggregate").EventStorageKey)
+	assert.Panics(t, func() {
+		RegisterAggregateType("test.CatalogAggregate", func() Aggregate { return &catalogAggregate{} })
+	})
+}
+
+func TestRegisterAggregateType_Concurrent(t *testing.T) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			aggregateType := "test.ConcurrentAggregate" + string(rune('A'+i))
+			RegisterAggregateType(aggregateType, func() Aggregate { return &catalogAggregate{} })
+			_, err := NewAggregate(aggregateType)
+			assert.NoError(t, err)
+			_ = GetAggregateTypeInfos()
+		}(i)
+	}
+	wg.Wait()
+	_, ok := GetAggregateTypeInfo("test.ConcurrentAggregateA")
+	assert.True(t, ok)
+}
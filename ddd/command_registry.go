@@ -0,0 +1,192 @@
+package ddd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type NewCommandFunc func() Command
+
+var _commandTypeRegistry = newCommandTypeRegistry()
+
+// 命令类型注册表，按 聚合根类型.命令类型 注册，不同聚合根可以有同名的命令
+type commandTypeRegistry struct {
+	mu      sync.RWMutex
+	typeMap map[string]*commandRegistryItem
+	goTypes map[reflect.Type]*commandRegistryItem
+}
+
+type commandRegistryItem struct {
+	commandKey    string
+	commandType   string
+	aggregateType string
+	newFunc       NewCommandFunc
+}
+
+func newCommandTypeRegistry() *commandTypeRegistry {
+	return &commandTypeRegistry{
+		typeMap: make(map[string]*commandRegistryItem),
+		goTypes: make(map[reflect.Type]*commandRegistryItem),
+	}
+}
+
+//
+// RegisterCommandType
+// @Description: 注册命令类型，命令类型名称为命令结构体名称，与聚合根命令处理方法名称一致。
+// 注册键为 聚合根类型.命令类型，同一命令结构体只能注册到一个聚合根
+// @param aggregateType 处理命令的聚合根类型
+// @param newFunc 新建命令对象方法
+// @return error
+//
+func RegisterCommandType(aggregateType string, newFunc NewCommandFunc) error {
+	if err := assert.NotEmpty(aggregateType, assert.NewOptions("ddd.RegisterCommandType() aggregateType is empty")); err != nil {
+		return err
+	}
+	if err := assert.NotNil(newFunc, assert.NewOptions("ddd.RegisterCommandType() newFunc is nil")); err != nil {
+		return err
+	}
+	cmd := newFunc()
+	if err := assert.NotNil(cmd, assert.NewOptions("ddd.RegisterCommandType() newFunc() return nil")); err != nil {
+		return err
+	}
+	return _commandTypeRegistry.add(aggregateType, GetCommandType(cmd), reflect.TypeOf(cmd), newFunc)
+}
+
+//
+// GetCommandType
+// @Description: 获取命令类型名称
+// @param cmd 命令
+// @return string
+//
+func GetCommandType(cmd Command) string {
+	t := reflect.TypeOf(cmd)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+//
+// GetCommandKey
+// @Description: 获取命令的注册键，格式为 聚合根类型.命令类型
+// @param cmd 命令，需先使用 RegisterCommandType 注册
+// @return string
+// @return error
+//
+func GetCommandKey(cmd Command) (string, error) {
+	item, err := _commandTypeRegistry.getByGoType(reflect.TypeOf(cmd))
+	if err != nil {
+		return "", err
+	}
+	return item.commandKey, nil
+}
+
+//
+// NewCommand
+// @Description: 按注册的命令类型新建命令，并使用json数据填充
+// @param commandKey 命令注册键，格式为 聚合根类型.命令类型
+// @param data json数据
+// @return Command
+// @return error
+//
+func NewCommand(commandKey string, data []byte) (Command, error) {
+	item, err := _commandTypeRegistry.get(commandKey)
+	if err != nil {
+		return nil, err
+	}
+	cmd := item.newFunc()
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, cmd); err != nil {
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+//
+// GetCommandAggregateType
+// @Description: 获取处理命令的聚合根类型
+// @param commandKey 命令注册键，格式为 聚合根类型.命令类型
+// @return string
+// @return error
+//
+func GetCommandAggregateType(commandKey string) (string, error) {
+	item, err := _commandTypeRegistry.get(commandKey)
+	if err != nil {
+		return "", err
+	}
+	return item.aggregateType, nil
+}
+
+func getCommandKey(aggregateType string, commandType string) string {
+	return aggregateType + "." + commandType
+}
+
+func (r *commandTypeRegistry) add(aggregateType string, commandType string, goType reflect.Type, newFunc NewCommandFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	commandKey := getCommandKey(aggregateType, commandType)
+	if _, ok := r.typeMap[commandKey]; ok {
+		return errors.New(fmt.Sprintf("commandType %s already exists", commandKey))
+	}
+	if item, ok := r.goTypes[goType]; ok {
+		return errors.New(fmt.Sprintf("command %s is already registered as %s", goType.String(), item.commandKey))
+	}
+	item := &commandRegistryItem{
+		commandKey:    commandKey,
+		commandType:   commandType,
+		aggregateType: aggregateType,
+		newFunc:       newFunc,
+	}
+	r.typeMap[commandKey] = item
+	r.goTypes[goType] = item
+	return nil
+}
+
+func (r *commandTypeRegistry) get(commandKey string) (*commandRegistryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.typeMap[commandKey]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("没有注册的命令类型 %s", commandKey))
+	}
+	return item, nil
+}
+
+func (r *commandTypeRegistry) getByGoType(goType reflect.Type) (*commandRegistryItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	item, ok := r.goTypes[goType]
+	if !ok {
+		return nil, errors.New(fmt.Sprintf("没有注册的命令类型 %v", goType))
+	}
+	return item, nil
+}
+
+//
+//  getRegisteredCommandTypes
+//  @Description: 获取聚合根已注册的命令类型，按名称排序
+//  @param aggregateType 聚合根类型
+//  @return []string
+//
+func getRegisteredCommandTypes(aggregateType string) []string {
+	return _commandTypeRegistry.getByAggregateType(aggregateType)
+}
+
+func (r *commandTypeRegistry) getByAggregateType(aggregateType string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]string, 0)
+	for _, item := range r.typeMap {
+		if item.aggregateType == aggregateType {
+			res = append(res, item.commandType)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
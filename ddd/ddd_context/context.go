@@ -10,14 +10,18 @@ type ctxMetadataKey struct {
 type ctxServerKey struct {
 }
 
+const (
+	CorrelationIdHeader = "X-Correlation-Id" // 请求头中的业务链路id
+	CausationIdHeader   = "X-Causation-Id"   // 请求头中的起因id，即触发当前处理的命令或事件id
+)
+
 type ServerContext interface {
 	SetResponseHeader(key string, value string)
 	URLParamDefault(name, def string) string
 }
 
 func NewContext(parent context.Context, metadata map[string]string, serverCtx ServerContext) context.Context {
-	ctx, _ := context.WithCancel(parent)
-	ctx = setMetadata(ctx, metadata)
+	ctx := setMetadata(parent, metadata)
 	return context.WithValue(ctx, ctxServerKey{}, serverCtx)
 }
 
@@ -55,3 +59,58 @@ func SetMetadataValue(ctx context.Context, name string, value string) {
 func setMetadata(ctx context.Context, metadata map[string]string) context.Context {
 	return context.WithValue(ctx, ctxMetadataKey{}, metadata)
 }
+
+//
+// GetCorrelationId
+// @Description: 获取业务链路id
+// @param ctx 上下文
+// @return string
+//
+func GetCorrelationId(ctx context.Context) string {
+	return getMetadataValue(ctx, CorrelationIdHeader)
+}
+
+//
+// GetCausationId
+// @Description: 获取起因id
+// @param ctx 上下文
+// @return string
+//
+func GetCausationId(ctx context.Context) string {
+	return getMetadataValue(ctx, CausationIdHeader)
+}
+
+//
+// NewCorrelationContext
+// @Description: 新建带有业务链路id与起因id的上下文，复制原有的metadata，不修改父上下文
+// @param parent 父上下文
+// @param correlationId 业务链路id，为空时保留原值
+// @param causationId 起因id，为空时保留原值
+// @return context.Context
+//
+func NewCorrelationContext(parent context.Context, correlationId string, causationId string) context.Context {
+	metadata := make(map[string]string)
+	if header, ok := parent.Value(ctxMetadataKey{}).(map[string]string); ok {
+		for k, v := range header {
+			metadata[k] = v
+		}
+	}
+	if len(correlationId) > 0 {
+		metadata[CorrelationIdHeader] = correlationId
+	}
+	if len(causationId) > 0 {
+		metadata[CausationIdHeader] = causationId
+	}
+	return setMetadata(parent, metadata)
+}
+
+func getMetadataValue(ctx context.Context, name string) string {
+	if ctx == nil {
+		return ""
+	}
+	header, ok := ctx.Value(ctxMetadataKey{}).(map[string]string)
+	if !ok {
+		return ""
+	}
+	return header[name]
+}
@@ -0,0 +1,22 @@
+package ddd_context
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNewCorrelationContext(t *testing.T) {
+	metadata := map[string]string{CorrelationIdHeader: "corr-1", "Tenant": "t1"}
+	ctx := NewContext(context.Background(), metadata, nil)
+	assert.Equal(t, "corr-1", GetCorrelationId(ctx))
+	assert.Equal(t, "", GetCausationId(ctx))
+
+	child := NewCorrelationContext(ctx, "", "event-1")
+	assert.Equal(t, "corr-1", GetCorrelationId(child))
+	assert.Equal(t, "event-1", GetCausationId(child))
+	assert.Equal(t, "t1", (*GetMetadataContext(child))["Tenant"])
+
+	// 父上下文的元数据不应被修改
+	assert.Equal(t, "", GetCausationId(ctx))
+}
@@ -0,0 +1,33 @@
+package ddd_errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+//
+// VersionConflictError
+// @Description: 乐观锁版本冲突，更新时数据库中的版本号与实体版本号不一致
+//
+type VersionConflictError struct {
+	TenantId string `json:"tenantId"`
+	Id       string `json:"id"`
+	Version  int64  `json:"version"` // 更新时期望的版本号
+}
+
+func NewVersionConflictError(tenantId string, id string, version int64) *VersionConflictError {
+	return &VersionConflictError{
+		TenantId: tenantId,
+		Id:       id,
+		Version:  version,
+	}
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict, id %s expected version %d has been modified or not exist", e.Id, e.Version)
+}
+
+func IsErrorVersionConflict(err error) bool {
+	var conflictError *VersionConflictError
+	return errors.As(err, &conflictError)
+}
@@ -0,0 +1,168 @@
+package ddd_repository
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+//
+// MetricType
+// @Description: 统计指标类型
+//
+type MetricType string
+
+const (
+	MetricCount MetricType = "count" // 计数
+	MetricSum   MetricType = "sum"   // 求和
+	MetricAvg   MetricType = "avg"   // 平均值
+	MetricMin   MetricType = "min"   // 最小值
+	MetricMax   MetricType = "max"   // 最大值
+)
+
+//
+// Metric
+// @Description: 统计指标
+//
+type Metric struct {
+	Name  string     `json:"name"`  // 结果中的指标名称
+	Type  MetricType `json:"type"`  // 指标类型
+	Field string     `json:"field"` // 统计的字段，计数时可为空
+}
+
+func NewCountMetric(name string) Metric {
+	return Metric{Name: name, Type: MetricCount}
+}
+
+func NewSumMetric(name string, field string) Metric {
+	return Metric{Name: name, Type: MetricSum, Field: field}
+}
+
+func NewAvgMetric(name string, field string) Metric {
+	return Metric{Name: name, Type: MetricAvg, Field: field}
+}
+
+func NewMinMetric(name string, field string) Metric {
+	return Metric{Name: name, Type: MetricMin, Field: field}
+}
+
+func NewMaxMetric(name string, field string) Metric {
+	return Metric{Name: name, Type: MetricMax, Field: field}
+}
+
+//
+// ValidateMetrics
+// @Description: 校验统计指标，名称不能为空且不能重复，除计数外必须指定字段
+// @param metrics 统计指标
+// @return error
+//
+func ValidateMetrics(metrics []Metric) error {
+	if len(metrics) == 0 {
+		return errors.New("metrics is empty")
+	}
+	names := make(map[string]bool)
+	for _, m := range metrics {
+		if m.Name == "" {
+			return errors.New("metric name is empty")
+		}
+		if names[m.Name] {
+			return errors.New(fmt.Sprintf("metric name %s is duplicate", m.Name))
+		}
+		names[m.Name] = true
+		switch m.Type {
+		case MetricCount:
+		case MetricSum, MetricAvg, MetricMin, MetricMax:
+			if m.Field == "" {
+				return errors.New(fmt.Sprintf("metric %s field is empty", m.Name))
+			}
+		default:
+			return errors.New(fmt.Sprintf("metric %s type %s is not supported", m.Name, m.Type))
+		}
+	}
+	return nil
+}
+
+//
+// AggregateRow
+// @Description: 分组统计结果行
+//
+type AggregateRow struct {
+	Group   map[string]interface{} `json:"group"`   // 分组字段的值，键为分组字段
+	Metrics map[string]interface{} `json:"metrics"` // 指标的值，键为指标名称
+}
+
+func NewAggregateRow() *AggregateRow {
+	return &AggregateRow{
+		Group:   make(map[string]interface{}),
+		Metrics: make(map[string]interface{}),
+	}
+}
+
+func (r *AggregateRow) GetGroup(field string) interface{} {
+	return r.Group[field]
+}
+
+func (r *AggregateRow) GetGroupString(field string) string {
+	value := r.Group[field]
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func (r *AggregateRow) GetMetric(name string) interface{} {
+	return r.Metrics[name]
+}
+
+//
+// GetInt
+// @Description: 获取整数类型的指标值
+// @param name 指标名称
+// @return int64
+//
+func (r *AggregateRow) GetInt(name string) int64 {
+	value, _ := toFloat64(r.Metrics[name])
+	return int64(value)
+}
+
+//
+// GetFloat
+// @Description: 获取浮点类型的指标值
+// @param name 指标名称
+// @return float64
+//
+func (r *AggregateRow) GetFloat(name string) float64 {
+	value, _ := toFloat64(r.Metrics[name])
+	return value
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	case []byte:
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,140 @@
+package ddd_cache
+
+import (
+	"container/list"
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"sync"
+	"time"
+)
+
+//
+// Backend
+// @Description: 缓存存储，值为实体的json数据
+//
+type Backend interface {
+	//
+	// Get
+	// @Description: 获取缓存
+	// @param ctx 上下文
+	// @param key 键
+	// @return []byte 值
+	// @return bool 是否存在
+	// @return error
+	//
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+
+	//
+	// Set
+	// @Description: 设置缓存
+	// @param ctx 上下文
+	// @param key 键
+	// @param value 值
+	// @param ttl 有效时长，为0时不过期
+	// @return error
+	//
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+
+	//
+	// Delete
+	// @Description: 删除缓存
+	// @param ctx 上下文
+	// @param key 键
+	// @return error
+	//
+	Delete(ctx context.Context, key string) error
+}
+
+type memoryEntry struct {
+	key      string
+	value    []byte
+	expireAt time.Time
+}
+
+//
+// MemoryBackend
+// @Description: 进程内的LRU缓存，超过容量时淘汰最久未使用的数据，过期时间按 ddd_repository.GetClock() 计算
+//
+type MemoryBackend struct {
+	capacity int
+	items    map[string]*list.Element
+	lru      *list.List
+	mu       sync.Mutex
+}
+
+//
+// NewMemoryBackend
+// @Description: 新建进程内LRU缓存
+// @param capacity 最多缓存的数量，小于1时为1
+// @return *MemoryBackend
+//
+func NewMemoryBackend(capacity int) *MemoryBackend {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &MemoryBackend{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+func (b *MemoryBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	element, ok := b.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expireAt.IsZero() && !ddd_repository.GetClock().Now().Before(entry.expireAt) {
+		b.remove(element)
+		return nil, false, nil
+	}
+	b.lru.MoveToFront(element)
+	return entry.value, true, nil
+}
+
+func (b *MemoryBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expireAt = ddd_repository.GetClock().Now().Add(ttl)
+	}
+	if element, ok := b.items[key]; ok {
+		element.Value = entry
+		b.lru.MoveToFront(element)
+		return nil
+	}
+	b.items[key] = b.lru.PushFront(entry)
+	for b.lru.Len() > b.capacity {
+		b.remove(b.lru.Back())
+	}
+	return nil
+}
+
+func (b *MemoryBackend) Delete(ctx context.Context, key string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if element, ok := b.items[key]; ok {
+		b.remove(element)
+	}
+	return nil
+}
+
+//
+// Len
+// @Description: 当前缓存的数量，包括已过期但未清除的数据
+// @return int
+//
+func (b *MemoryBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lru.Len()
+}
+
+func (b *MemoryBackend) remove(element *list.Element) {
+	b.lru.Remove(element)
+	delete(b.items, element.Value.(*memoryEntry).key)
+}
@@ -0,0 +1,82 @@
+package ddd_cache
+
+import (
+	"context"
+	"encoding/json"
+	dapr_sdk_client "github.com/liuxd6825/go-sdk/client"
+	"strconv"
+	"time"
+)
+
+//
+// DaprStateBackend
+// @Description: 使用Dapr状态存储的缓存，多个实例共享缓存数据，状态存储需要支持 ttlInSeconds
+//
+type DaprStateBackend struct {
+	client    dapr_sdk_client.Client
+	storeName string
+}
+
+//
+// NewDaprStateBackend
+// @Description: 新建Dapr状态存储缓存
+// @param client Dapr客户端
+// @param storeName 状态存储名称
+// @return *DaprStateBackend
+//
+func NewDaprStateBackend(client dapr_sdk_client.Client, storeName string) *DaprStateBackend {
+	return &DaprStateBackend{client: client, storeName: storeName}
+}
+
+func (b *DaprStateBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	item, err := b.client.GetState(ctx, b.storeName, key, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if item == nil || len(item.Value) == 0 {
+		return nil, false, nil
+	}
+	return item.Value, true, nil
+}
+
+func (b *DaprStateBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	var metadata map[string]string
+	if seconds := int64(ttl / time.Second); seconds > 0 {
+		metadata = map[string]string{"ttlInSeconds": strconv.FormatInt(seconds, 10)}
+	}
+	return b.client.SaveState(ctx, b.storeName, key, value, metadata)
+}
+
+func (b *DaprStateBackend) Delete(ctx context.Context, key string) error {
+	return b.client.DeleteState(ctx, b.storeName, key, nil)
+}
+
+//
+// DaprInvalidator
+// @Description: 通过Dapr发布订阅广播缓存失效通知。订阅方收到通知后调用 Invalidate
+//
+type DaprInvalidator struct {
+	client     dapr_sdk_client.Client
+	pubsubName string
+	topic      string
+}
+
+//
+// NewDaprInvalidator
+// @Description: 新建Dapr缓存失效广播
+// @param client Dapr客户端
+// @param pubsubName 发布订阅组件名称
+// @param topic 主题
+// @return *DaprInvalidator
+//
+func NewDaprInvalidator(client dapr_sdk_client.Client, pubsubName string, topic string) *DaprInvalidator {
+	return &DaprInvalidator{client: client, pubsubName: pubsubName, topic: topic}
+}
+
+func (i *DaprInvalidator) Publish(ctx context.Context, invalidation *Invalidation) error {
+	data, err := json.Marshal(invalidation)
+	if err != nil {
+		return err
+	}
+	return i.client.PublishEvent(ctx, i.pubsubName, i.topic, data, dapr_sdk_client.PublishEventWithContentType("application/json"))
+}
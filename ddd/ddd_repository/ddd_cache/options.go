@@ -0,0 +1,90 @@
+package ddd_cache
+
+import "time"
+
+const (
+	defaultCapacity = 1000
+	defaultTTL      = 5 * time.Minute
+)
+
+//
+// Options
+// @Description: 缓存设置
+//
+type Options struct {
+	Prefix      *string        // 缓存键的前缀，共用缓存存储的仓储须不同，默认为实体类型名称
+	TTL         *time.Duration // 缓存有效时长，默认为5分钟
+	Backend     Backend        // 缓存存储，默认为容量1000的进程内LRU缓存
+	Invalidator Invalidator    // 缓存失效广播，使用进程内缓存且有多个实例时设置
+}
+
+func NewOptions() *Options {
+	return &Options{}
+}
+
+func (o *Options) SetPrefix(prefix string) *Options {
+	o.Prefix = &prefix
+	return o
+}
+
+func (o *Options) GetPrefix() string {
+	if o.Prefix == nil {
+		return ""
+	}
+	return *o.Prefix
+}
+
+func (o *Options) SetTTL(ttl time.Duration) *Options {
+	o.TTL = &ttl
+	return o
+}
+
+func (o *Options) GetTTL() time.Duration {
+	if o.TTL == nil {
+		return defaultTTL
+	}
+	return *o.TTL
+}
+
+func (o *Options) SetBackend(backend Backend) *Options {
+	o.Backend = backend
+	return o
+}
+
+func (o *Options) GetBackend() Backend {
+	if o.Backend == nil {
+		return NewMemoryBackend(defaultCapacity)
+	}
+	return o.Backend
+}
+
+func (o *Options) SetInvalidator(invalidator Invalidator) *Options {
+	o.Invalidator = invalidator
+	return o
+}
+
+func (o *Options) GetInvalidator() Invalidator {
+	return o.Invalidator
+}
+
+func MergeOptions(opts ...*Options) *Options {
+	res := &Options{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if o.Prefix != nil {
+			res.Prefix = o.Prefix
+		}
+		if o.TTL != nil {
+			res.TTL = o.TTL
+		}
+		if o.Backend != nil {
+			res.Backend = o.Backend
+		}
+		if o.Invalidator != nil {
+			res.Invalidator = o.Invalidator
+		}
+	}
+	return res
+}
@@ -0,0 +1,333 @@
+package ddd_cache
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// Invalidation
+// @Description: 缓存失效通知，租户下的缓存全部失效
+//
+type Invalidation struct {
+	Prefix   string `json:"prefix"`
+	TenantId string `json:"tenantId"`
+}
+
+//
+// Invalidator
+// @Description: 缓存失效广播，将本实例的失效通知发送给其它实例
+//
+type Invalidator interface {
+	Publish(ctx context.Context, invalidation *Invalidation) error
+}
+
+//
+// Repository
+// @Description: 缓存仓储，包装任意 ddd_repository.Repository[T]，缓存 FindById 与 FindOneByMap 找到的数据。
+// 缓存按租户划分版本，通过本仓储写入时租户的版本失效，之前的缓存不再使用。
+// 在工作单元中写入时，事务提交或回滚后再次失效，事务中读取的未提交数据不会继续使用
+//
+type Repository[T ddd.Entity] struct {
+	ddd_repository.Repository[T]
+	newFun      func() T
+	prefix      string
+	ttl         time.Duration
+	backend     Backend
+	invalidator Invalidator
+	closeOnce   sync.Once
+}
+
+var (
+	cachesLock sync.RWMutex
+	caches     = make(map[string][]*cacheEntry)
+)
+
+// 处理失效通知的缓存，前缀与存储相同的缓存仓储共用一项
+type cacheEntry struct {
+	backend Backend
+	refs    int
+}
+
+//
+// NewRepository
+// @Description: 新建缓存仓储。仓储注册后处理前缀相同的失效通知，不再使用时调用 Close 注销
+// @param newFun 新建实体方法
+// @param repos 被缓存的仓储
+// @param opts 缓存设置
+// @return *Repository[T]
+//
+func NewRepository[T ddd.Entity](newFun func() T, repos ddd_repository.Repository[T], opts ...*Options) *Repository[T] {
+	opt := MergeOptions(opts...)
+	prefix := opt.GetPrefix()
+	if len(prefix) == 0 {
+		prefix = strings.TrimPrefix(reflect.TypeOf(newFun()).String(), "*")
+	}
+	r := &Repository[T]{
+		Repository:  repos,
+		newFun:      newFun,
+		prefix:      prefix,
+		ttl:         opt.GetTTL(),
+		backend:     opt.GetBackend(),
+		invalidator: opt.GetInvalidator(),
+	}
+	registerCache(prefix, r.backend)
+	return r
+}
+
+//
+// Close
+// @Description: 注销缓存仓储，不再处理失效通知，可重复调用。按请求新建的缓存仓储使用后应关闭
+//
+func (r *Repository[T]) Close() {
+	r.closeOnce.Do(func() {
+		unregisterCache(r.prefix, r.backend)
+	})
+}
+
+func registerCache(prefix string, backend Backend) {
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	for _, entry := range caches[prefix] {
+		if isSameBackend(entry.backend, backend) {
+			entry.refs++
+			return
+		}
+	}
+	caches[prefix] = append(caches[prefix], &cacheEntry{backend: backend, refs: 1})
+}
+
+func unregisterCache(prefix string, backend Backend) {
+	cachesLock.Lock()
+	defer cachesLock.Unlock()
+	list := caches[prefix]
+	for i, entry := range list {
+		if !isSameBackend(entry.backend, backend) {
+			continue
+		}
+		entry.refs--
+		if entry.refs > 0 {
+			return
+		}
+		list = append(list[:i:i], list[i+1:]...)
+		if len(list) == 0 {
+			delete(caches, prefix)
+		} else {
+			caches[prefix] = list
+		}
+		return
+	}
+}
+
+// 不可比较的存储类型视为不同的存储
+func isSameBackend(a, b Backend) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+//
+// Invalidate
+// @Description: 处理其它实例广播的缓存失效通知，使前缀相同的缓存仓储中租户的缓存失效，不再广播
+// @param ctx 上下文
+// @param invalidation 失效通知
+// @return error
+//
+func Invalidate(ctx context.Context, invalidation *Invalidation) error {
+	cachesLock.RLock()
+	list := caches[invalidation.Prefix]
+	cachesLock.RUnlock()
+	key := getVersionKey(invalidation.Prefix, invalidation.TenantId)
+	for _, entry := range list {
+		if err := entry.backend.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository[T]) FindById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
+	return r.findOne(ctx, tenantId, "id:"+id, opts, func() *ddd_repository.FindOneResult[T] {
+		return r.Repository.FindById(ctx, tenantId, id, opts...)
+	})
+}
+
+func (r *Repository[T]) FindOneByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
+	load := func() *ddd_repository.FindOneResult[T] {
+		return r.Repository.FindOneByMap(ctx, tenantId, filterMap, opts...)
+	}
+	filter, err := json.Marshal(filterMap)
+	if err != nil {
+		return load()
+	}
+	return r.findOne(ctx, tenantId, "map:"+string(filter), opts, load)
+}
+
+func (r *Repository[T]) Insert(ctx context.Context, entity T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	defer r.invalidate(ctx, entity.GetTenantId())
+	return r.Repository.Insert(ctx, entity, opts...)
+}
+
+func (r *Repository[T]) Update(ctx context.Context, entity T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	defer r.invalidate(ctx, entity.GetTenantId())
+	return r.Repository.Update(ctx, entity, opts...)
+}
+
+func (r *Repository[T]) InsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	defer r.invalidateEntities(ctx, entities)
+	return r.Repository.InsertMany(ctx, entities, opts...)
+}
+
+func (r *Repository[T]) UpdateMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	defer r.invalidateEntities(ctx, entities)
+	return r.Repository.UpdateMany(ctx, entities, opts...)
+}
+
+func (r *Repository[T]) UpsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	defer r.invalidateEntities(ctx, entities)
+	return r.Repository.UpsertMany(ctx, entities, opts...)
+}
+
+func (r *Repository[T]) Delete(ctx context.Context, entity ddd.Entity, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	defer r.invalidate(ctx, entity.GetTenantId())
+	return r.Repository.Delete(ctx, entity, opts...)
+}
+
+func (r *Repository[T]) DeleteByIds(ctx context.Context, tenantId string, ids []string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	defer r.invalidate(ctx, tenantId)
+	return r.Repository.DeleteByIds(ctx, tenantId, ids, opts...)
+}
+
+func (r *Repository[T]) DeleteById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	defer r.invalidate(ctx, tenantId)
+	return r.Repository.DeleteById(ctx, tenantId, id, opts...)
+}
+
+func (r *Repository[T]) DeleteAll(ctx context.Context, tenantId string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	defer r.invalidate(ctx, tenantId)
+	return r.Repository.DeleteAll(ctx, tenantId, opts...)
+}
+
+func (r *Repository[T]) DeleteByMap(ctx context.Context, tenantId string, data map[string]interface{}, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	defer r.invalidate(ctx, tenantId)
+	return r.Repository.DeleteByMap(ctx, tenantId, data, opts...)
+}
+
+//
+// InvalidateTenant
+// @Description: 立即使租户的缓存失效并广播，用于不经过本仓储的写入
+// @param ctx 上下文
+// @param tenantId 租户id
+// @return error
+//
+func (r *Repository[T]) InvalidateTenant(ctx context.Context, tenantId string) error {
+	if err := r.invalidateLocal(ctx, tenantId); err != nil {
+		return err
+	}
+	if r.invalidator == nil {
+		return nil
+	}
+	return r.invalidator.Publish(ctx, &Invalidation{Prefix: r.prefix, TenantId: tenantId})
+}
+
+func (r *Repository[T]) invalidateLocal(ctx context.Context, tenantId string) error {
+	return r.backend.Delete(ctx, r.getVersionKey(tenantId))
+}
+
+// 写入后使缓存失效，在工作单元中时提交或回滚后再次失效并广播。缓存的错误不影响写入结果
+func (r *Repository[T]) invalidate(ctx context.Context, tenantId string) {
+	if _, ok := ddd_repository.GetUnitOfWork(ctx); ok {
+		_ = r.invalidateLocal(ctx, tenantId)
+	}
+	ddd_repository.OnCommit(ctx, func(ctx context.Context) {
+		_ = r.InvalidateTenant(ctx, tenantId)
+	})
+	ddd_repository.OnRollback(ctx, func(ctx context.Context, err error) {
+		_ = r.InvalidateTenant(ctx, tenantId)
+	})
+}
+
+func (r *Repository[T]) invalidateEntities(ctx context.Context, entities []T) {
+	tenantIds := make(map[string]bool)
+	for _, entity := range entities {
+		if tenantIds[entity.GetTenantId()] {
+			continue
+		}
+		tenantIds[entity.GetTenantId()] = true
+		r.invalidate(ctx, entity.GetTenantId())
+	}
+}
+
+//
+//  findOne
+//  @Description: 从缓存读取，没有时从仓储读取并缓存找到的数据。缓存出错时直接从仓储读取
+//  @param tenantId 租户id
+//  @param key 租户下的缓存键
+//  @param opts 查询选项，改变查询结果的选项不使用缓存
+//  @param load 从仓储读取
+//
+func (r *Repository[T]) findOne(ctx context.Context, tenantId string, key string, opts []*ddd_repository.FindOptions, load func() *ddd_repository.FindOneResult[T]) *ddd_repository.FindOneResult[T] {
+	if len(tenantId) == 0 || !isCacheable(opts...) {
+		return load()
+	}
+	version, err := r.getVersion(ctx, tenantId)
+	if err != nil {
+		return load()
+	}
+	key = r.prefix + ":" + tenantId + ":" + version + ":" + key
+	if data, ok, err := r.backend.Get(ctx, key); err == nil && ok {
+		entity := r.newFun()
+		if err := json.Unmarshal(data, entity); err == nil {
+			return ddd_repository.NewFindOneResult[T](entity, true, nil)
+		}
+	}
+	result := load()
+	if entity, ok, err := result.Result(); err == nil && ok {
+		if data, err := json.Marshal(entity); err == nil {
+			_ = r.backend.Set(ctx, key, data, r.ttl)
+		}
+	}
+	return result
+}
+
+//
+//  getVersion
+//  @Description: 获取租户的缓存版本，没有时新建
+//
+func (r *Repository[T]) getVersion(ctx context.Context, tenantId string) (string, error) {
+	key := r.getVersionKey(tenantId)
+	data, ok, err := r.backend.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return string(data), nil
+	}
+	version := uuid.NewString()
+	if err := r.backend.Set(ctx, key, []byte(version), 0); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func (r *Repository[T]) getVersionKey(tenantId string) string {
+	return getVersionKey(r.prefix, tenantId)
+}
+
+func getVersionKey(prefix string, tenantId string) string {
+	return prefix + ":" + tenantId + ":version"
+}
+
+// 返回字段、包含已删除数据、排序规则与跳过数量会改变查询结果，不使用缓存
+func isCacheable(opts ...*ddd_repository.FindOptions) bool {
+	opt := ddd_repository.MergeFindOptions(opts...)
+	return !opt.GetIncludeDeleted() && (opt.Fields == nil || len(*opt.Fields) == 0) && opt.Collation == nil && opt.Skip == nil
+}
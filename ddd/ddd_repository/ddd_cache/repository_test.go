@@ -0,0 +1,121 @@
+package ddd_cache
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository/ddd_memory"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type Country struct {
+	Id       string `json:"id"`
+	TenantId string `json:"tenantId"`
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+}
+
+func (c *Country) GetTenantId() string { return c.TenantId }
+func (c *Country) GetId() string       { return c.Id }
+
+var _ ddd_repository.Repository[*Country] = (*Repository[*Country])(nil)
+
+type testInvalidator struct {
+	invalidations []*Invalidation
+}
+
+func (i *testInvalidator) Publish(ctx context.Context, invalidation *Invalidation) error {
+	i.invalidations = append(i.invalidations, invalidation)
+	return nil
+}
+
+func TestRepository_FindById(t *testing.T) {
+	ctx := context.Background()
+	newCountry := func() *Country { return &Country{} }
+	memory := ddd_memory.NewRepository[*Country](newCountry, ddd_memory.NewMemoryDB(), "countries")
+	invalidator := &testInvalidator{}
+	repos := NewRepository[*Country](newCountry, memory, NewOptions().SetPrefix("test.country").SetInvalidator(invalidator))
+
+	assert.NoError(t, repos.Insert(ctx, &Country{Id: "1", TenantId: "t1", Code: "CN", Name: "China"}).GetError())
+	assert.Equal(t, []*Invalidation{{Prefix: "test.country", TenantId: "t1"}}, invalidator.invalidations)
+
+	country, ok, err := repos.FindById(ctx, "t1", "1").Result()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "China", country.Name)
+	country, _, _ = repos.FindOneByMap(ctx, "t1", map[string]interface{}{"code": "CN"}).Result()
+	assert.Equal(t, "China", country.Name)
+
+	// 绕过缓存修改数据，缓存中仍是旧数据
+	assert.NoError(t, memory.Update(ctx, &Country{Id: "1", TenantId: "t1", Code: "CN", Name: "PRC"}).GetError())
+	country, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "China", country.Name)
+	country, _, _ = repos.FindOneByMap(ctx, "t1", map[string]interface{}{"code": "CN"}).Result()
+	assert.Equal(t, "China", country.Name)
+	country, _, _ = repos.FindById(ctx, "t1", "1", ddd_repository.NewFindOptions().SetFields("id,name")).Result()
+	assert.Equal(t, "PRC", country.Name)
+
+	// 其它实例广播的失效通知
+	assert.NoError(t, Invalidate(ctx, &Invalidation{Prefix: "test.country", TenantId: "t1"}))
+	country, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "PRC", country.Name)
+
+	assert.NoError(t, repos.Update(ctx, &Country{Id: "1", TenantId: "t1", Code: "CN", Name: "China"}).GetError())
+	country, _, _ = repos.FindOneByMap(ctx, "t1", map[string]interface{}{"code": "CN"}).Result()
+	assert.Equal(t, "China", country.Name)
+
+	assert.NoError(t, repos.DeleteById(ctx, "t1", "1").GetError())
+	_, ok, err = repos.FindById(ctx, "t1", "1").Result()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Len(t, invalidator.invalidations, 3)
+}
+
+func TestRepository_Close(t *testing.T) {
+	newCountry := func() *Country { return &Country{} }
+	memory := ddd_memory.NewRepository[*Country](newCountry, ddd_memory.NewMemoryDB(), "countries")
+	backend := NewMemoryBackend(10)
+	opts := NewOptions().SetPrefix("test.close").SetBackend(backend)
+	first := NewRepository[*Country](newCountry, memory, opts)
+	second := NewRepository[*Country](newCountry, memory, opts)
+	other := NewRepository[*Country](newCountry, memory, NewOptions().SetPrefix("test.close"))
+	assert.Len(t, caches["test.close"], 2)
+	assert.Equal(t, 2, caches["test.close"][0].refs)
+
+	first.Close()
+	first.Close()
+	assert.Len(t, caches["test.close"], 2)
+	second.Close()
+	assert.Len(t, caches["test.close"], 1)
+	other.Close()
+	_, ok := caches["test.close"]
+	assert.False(t, ok)
+}
+
+func TestMemoryBackend(t *testing.T) {
+	now := time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC)
+	ddd_repository.SetClock(ddd_repository.ClockFunc(func() time.Time { return now }))
+	defer ddd_repository.SetClock(nil)
+	ctx := context.Background()
+
+	backend := NewMemoryBackend(2)
+	assert.NoError(t, backend.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.NoError(t, backend.Set(ctx, "b", []byte("2"), 0))
+	_, ok, _ := backend.Get(ctx, "a")
+	assert.True(t, ok)
+	assert.NoError(t, backend.Set(ctx, "c", []byte("3"), 0))
+	_, ok, _ = backend.Get(ctx, "b")
+	assert.False(t, ok, "least recently used is evicted")
+	assert.Equal(t, 2, backend.Len())
+
+	now = now.Add(time.Minute)
+	_, ok, _ = backend.Get(ctx, "a")
+	assert.False(t, ok, "expired")
+	value, ok, _ := backend.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("3"), value)
+
+	assert.NoError(t, backend.Delete(ctx, "c"))
+	assert.Equal(t, 0, backend.Len())
+}
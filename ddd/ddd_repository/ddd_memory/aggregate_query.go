@@ -0,0 +1,196 @@
+package ddd_memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"sort"
+)
+
+//
+// Count
+// @Description: 统计租户下与过滤条件匹配的数量
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件，为空时统计全部
+// @return int64 数量
+// @return error
+//
+func (r *Repository[T]) Count(ctx context.Context, tenantId string, filter string) (int64, error) {
+	docs, err := r.findByFilter(ctx, tenantId, filter)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+//
+// Aggregate
+// @Description: 分组统计
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param groupBy 分组字段，为空时统计全部数据
+// @param metrics 统计指标
+// @return []*ddd_repository.AggregateRow 按分组字段排序的结果行
+// @return error
+//
+func (r *Repository[T]) Aggregate(ctx context.Context, tenantId string, filter string, groupBy []string, metrics []ddd_repository.Metric) ([]*ddd_repository.AggregateRow, error) {
+	if err := ddd_repository.ValidateMetrics(metrics); err != nil {
+		return nil, err
+	}
+	docs, err := r.findByFilter(ctx, tenantId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	type group struct {
+		values []interface{}
+		docs   []*document
+	}
+	groups := make([]*group, 0)
+	groupMap := make(map[string]*group)
+	for _, doc := range docs {
+		values := make([]interface{}, len(groupBy))
+		for i, field := range groupBy {
+			values[i], _ = getFieldValue(doc.fields, field)
+		}
+		key, err := json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := groupMap[string(key)]
+		if !ok {
+			g = &group{values: values}
+			groupMap[string(key)] = g
+			groups = append(groups, g)
+		}
+		g.docs = append(g.docs, doc)
+	}
+	if len(groupBy) == 0 && len(groups) == 0 {
+		groups = append(groups, &group{})
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		for k := range groupBy {
+			if c, _ := compareValues(groups[i].values[k], groups[j].values[k]); c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+
+	rows := make([]*ddd_repository.AggregateRow, len(groups))
+	for i, g := range groups {
+		row := ddd_repository.NewAggregateRow()
+		for j, field := range groupBy {
+			row.Group[field] = g.values[j]
+		}
+		for _, metric := range metrics {
+			row.Metrics[metric.Name] = getMetricValue(g.docs, metric)
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+//
+// Distinct
+// @Description: 获取字段的不重复值，数组字段取数组中的元素
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param field 字段
+// @param filter rsql过滤条件
+// @return []interface{} 排序后的值
+// @return error
+//
+func (r *Repository[T]) Distinct(ctx context.Context, tenantId string, field string, filter string) ([]interface{}, error) {
+	docs, err := r.findByFilter(ctx, tenantId, filter)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]interface{}, 0)
+	add := func(value interface{}) {
+		for _, item := range res {
+			if equalsValue(item, value) {
+				return
+			}
+		}
+		res = append(res, value)
+	}
+	for _, doc := range docs {
+		value, ok := getFieldValue(doc.fields, field)
+		if !ok {
+			continue
+		}
+		if list, ok := value.([]interface{}); ok {
+			for _, item := range list {
+				add(item)
+			}
+			continue
+		}
+		add(value)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		c, _ := compareValues(res[i], res[j])
+		return c < 0
+	})
+	return res, nil
+}
+
+func (r *Repository[T]) findByFilter(ctx context.Context, tenantId string, filter string) ([]*document, error) {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return nil, err
+	}
+	predicate, err := newRsqlPredicate(filter)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]*document, 0)
+	for _, doc := range r.db.list(ctx, r.collection, tenantId) {
+		if predicate(doc.fields) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+func getMetricValue(docs []*document, metric ddd_repository.Metric) interface{} {
+	if metric.Type == ddd_repository.MetricCount {
+		return int64(len(docs))
+	}
+	var sum float64
+	var count int64
+	var result interface{}
+	for _, doc := range docs {
+		value, _ := getFieldValue(doc.fields, metric.Field)
+		if value == nil {
+			continue
+		}
+		switch metric.Type {
+		case ddd_repository.MetricSum, ddd_repository.MetricAvg:
+			if f, ok := toFloat(value); ok {
+				sum += f
+				count++
+			}
+		case ddd_repository.MetricMin:
+			if c, ok := compareValues(value, result); result == nil || (ok && c < 0) {
+				result = value
+			}
+		case ddd_repository.MetricMax:
+			if c, ok := compareValues(value, result); result == nil || (ok && c > 0) {
+				result = value
+			}
+		}
+	}
+	switch metric.Type {
+	case ddd_repository.MetricSum:
+		return sum
+	case ddd_repository.MetricAvg:
+		if count == 0 {
+			return nil
+		}
+		return sum / float64(count)
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package ddd_memory
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+)
+
+//
+// findStream
+// @Description: 内存数据的迭代器，查询时取得数据快照，遍历时逐条解码
+//
+type findStream[T ddd.Entity] struct {
+	docs    []*document
+	index   int
+	decode  func(doc *document) (T, error)
+	current T
+	err     error
+}
+
+func (s *findStream[T]) Next(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	if s.index >= len(s.docs) {
+		return false
+	}
+	entity, err := s.decode(s.docs[s.index])
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.index++
+	s.current = entity
+	return true
+}
+
+func (s *findStream[T]) Current() T {
+	return s.current
+}
+
+func (s *findStream[T]) Err() error {
+	return s.err
+}
+
+func (s *findStream[T]) Close(ctx context.Context) error {
+	s.docs = nil
+	return nil
+}
+
+//
+// FindStream
+// @Description: 流式查询，返回逐条解码的迭代器
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param opts 查询选项，可设置 Limit、Skip
+// @return ddd_repository.FindStream[T]
+// @return error
+//
+func (r *Repository[T]) FindStream(ctx context.Context, tenantId string, filter string, opts ...*ddd_repository.FindOptions) (ddd_repository.FindStream[T], error) {
+	docs, err := r.findByFilter(ctx, tenantId, filter)
+	if err != nil {
+		return nil, err
+	}
+	opt := ddd_repository.MergeFindOptions(opts...)
+	if opt.Skip != nil {
+		if *opt.Skip >= int64(len(docs)) {
+			docs = nil
+		} else if *opt.Skip > 0 {
+			docs = docs[*opt.Skip:]
+		}
+	}
+	if opt.Limit != nil && *opt.Limit > 0 && *opt.Limit < int64(len(docs)) {
+		docs = docs[:*opt.Limit]
+	}
+	return &findStream[T]{docs: docs, decode: r.decode}, nil
+}
+
+//
+// ForEach
+// @Description: 流式遍历查询结果
+// @param ctx 上下文，取消时停止遍历并返回ctx的错误
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param fun 回调，返回 ddd_repository.ErrStopIteration 时提前停止
+// @param opts 查询选项
+// @return error
+//
+func (r *Repository[T]) ForEach(ctx context.Context, tenantId string, filter string, fun func(entity T) error, opts ...*ddd_repository.FindOptions) error {
+	stream, err := r.FindStream(ctx, tenantId, filter, opts...)
+	if err != nil {
+		return err
+	}
+	return ddd_repository.ForEachStream[T](ctx, stream, fun)
+}
@@ -0,0 +1,196 @@
+package ddd_memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+const (
+	IdField       = "_id"
+	TenantIdField = "tenant_id"
+)
+
+//
+// MemoryDB
+// @Description: 内存数据库，保存各集合的实体数据，用于单元测试
+//
+type MemoryDB struct {
+	mu          sync.RWMutex
+	collections map[string]map[string]*document
+}
+
+// 实体的json数据及用于查询的字段
+type document struct {
+	tenantId string
+	id       string
+	data     []byte
+	fields   map[string]interface{}
+}
+
+// 事务中未提交的写入，值为nil表示删除
+type memoryTx struct {
+	mu     sync.Mutex
+	writes map[string]map[string]*document
+}
+
+type memoryTxKey struct {
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		collections: make(map[string]map[string]*document),
+	}
+}
+
+func getDocumentKey(tenantId, id string) string {
+	return tenantId + "/" + id
+}
+
+func getMemoryTx(ctx context.Context) *memoryTx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(memoryTxKey{}).(*memoryTx)
+	return tx
+}
+
+//
+//  get
+//  @Description: 获取文档，事务中优先读取未提交的写入
+//
+func (db *MemoryDB) get(ctx context.Context, collection string, tenantId, id string) (*document, bool) {
+	key := getDocumentKey(tenantId, id)
+	if tx := getMemoryTx(ctx); tx != nil {
+		tx.mu.Lock()
+		doc, ok := tx.writes[collection][key]
+		tx.mu.Unlock()
+		if ok {
+			return doc, doc != nil
+		}
+	}
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	doc, ok := db.collections[collection][key]
+	return doc, ok
+}
+
+//
+//  list
+//  @Description: 获取租户的所有文档，按写入键排序以保证结果稳定
+//
+func (db *MemoryDB) list(ctx context.Context, collection string, tenantId string) []*document {
+	docs := make(map[string]*document)
+	db.mu.RLock()
+	for key, doc := range db.collections[collection] {
+		if doc.tenantId == tenantId {
+			docs[key] = doc
+		}
+	}
+	db.mu.RUnlock()
+
+	if tx := getMemoryTx(ctx); tx != nil {
+		tx.mu.Lock()
+		for key, doc := range tx.writes[collection] {
+			if doc == nil {
+				delete(docs, key)
+			} else if doc.tenantId == tenantId {
+				docs[key] = doc
+			}
+		}
+		tx.mu.Unlock()
+	}
+
+	keys := make([]string, 0, len(docs))
+	for key := range docs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	res := make([]*document, len(keys))
+	for i, key := range keys {
+		res[i] = docs[key]
+	}
+	return res
+}
+
+func (db *MemoryDB) put(ctx context.Context, collection string, doc *document) {
+	db.write(ctx, collection, getDocumentKey(doc.tenantId, doc.id), doc)
+}
+
+func (db *MemoryDB) delete(ctx context.Context, collection string, tenantId, id string) {
+	db.write(ctx, collection, getDocumentKey(tenantId, id), nil)
+}
+
+//
+//  update
+//  @Description: 检查原文档并写入，检查与写入在同一个锁中执行
+//  @param doc 新文档
+//  @param check 检查原文档，原文档不存在时为nil，返回false时不写入
+//  @return bool 是否已写入
+//
+func (db *MemoryDB) update(ctx context.Context, collection string, doc *document, check func(old *document) bool) bool {
+	key := getDocumentKey(doc.tenantId, doc.id)
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	if tx := getMemoryTx(ctx); tx != nil {
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+		old, ok := tx.writes[collection][key]
+		if !ok {
+			old = db.collections[collection][key]
+		}
+		if !check(old) {
+			return false
+		}
+		if _, ok := tx.writes[collection]; !ok {
+			tx.writes[collection] = make(map[string]*document)
+		}
+		tx.writes[collection][key] = doc
+		return true
+	}
+	if !check(db.collections[collection][key]) {
+		return false
+	}
+	db.apply(collection, key, doc)
+	return true
+}
+
+func (db *MemoryDB) write(ctx context.Context, collection string, key string, doc *document) {
+	if tx := getMemoryTx(ctx); tx != nil {
+		tx.mu.Lock()
+		defer tx.mu.Unlock()
+		if _, ok := tx.writes[collection]; !ok {
+			tx.writes[collection] = make(map[string]*document)
+		}
+		tx.writes[collection][key] = doc
+		return
+	}
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.apply(collection, key, doc)
+}
+
+func (db *MemoryDB) commit(tx *memoryTx) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	for collection, writes := range tx.writes {
+		for key, doc := range writes {
+			db.apply(collection, key, doc)
+		}
+	}
+}
+
+func (db *MemoryDB) apply(collection string, key string, doc *document) {
+	docs, ok := db.collections[collection]
+	if !ok {
+		docs = make(map[string]*document)
+		db.collections[collection] = docs
+	}
+	if doc == nil {
+		delete(docs, key)
+	} else {
+		docs[key] = doc
+	}
+}
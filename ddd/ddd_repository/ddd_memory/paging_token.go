@@ -0,0 +1,88 @@
+package ddd_memory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// 游标分页的续查令牌，保存上一页最后一条数据的排序字段值与id
+type pagingToken struct {
+	Sort   string        `json:"s"`
+	Values []interface{} `json:"v"`
+	Id     string        `json:"id"`
+}
+
+var errInvalidContinuationToken = errors.New("continuation token is invalid")
+
+func getSortSignature(items []sortItem) string {
+	list := make([]string, len(items))
+	for i, item := range items {
+		order := "asc"
+		if item.desc {
+			order = "desc"
+		}
+		list[i] = item.name + ":" + order
+	}
+	return strings.Join(list, ",")
+}
+
+//
+//  newContinuationToken
+//  @Description: 按最后一条数据的排序字段值与id生成续查令牌
+//
+func newContinuationToken(items []sortItem, last *document) (string, error) {
+	token := &pagingToken{
+		Sort:   getSortSignature(items),
+		Values: make([]interface{}, len(items)),
+		Id:     last.id,
+	}
+	for i, item := range items {
+		token.Values[i], _ = getFieldValue(last.fields, item.name)
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+//
+//  getDocumentsAfter
+//  @Description: 获取排在续查令牌对应数据之后的文档，docs 须已按 items 与id排序
+//
+func getDocumentsAfter(docs []*document, items []sortItem, continuationToken string) ([]*document, error) {
+	data, err := base64.RawURLEncoding.DecodeString(continuationToken)
+	if err != nil {
+		return nil, errInvalidContinuationToken
+	}
+	token := &pagingToken{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, errInvalidContinuationToken
+	}
+	if token.Sort != getSortSignature(items) || len(token.Values) != len(items) {
+		return nil, errors.New("continuation token does not match the sort")
+	}
+	for i, doc := range docs {
+		if compareToken(doc, items, token) > 0 {
+			return docs[i:], nil
+		}
+	}
+	return docs[len(docs):], nil
+}
+
+func compareToken(doc *document, items []sortItem, token *pagingToken) int {
+	for i, item := range items {
+		value, _ := getFieldValue(doc.fields, item.name)
+		c, _ := compareValues(value, token.Values[i])
+		if c == 0 {
+			continue
+		}
+		if item.desc {
+			return -c
+		}
+		return c
+	}
+	return strings.Compare(doc.id, token.Id)
+}
@@ -0,0 +1,593 @@
+package ddd_memory
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/utils/stringutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"sort"
+	"strings"
+)
+
+// PredicateKey DoFilter 传入的过滤条件中保存 Predicate 的键
+const PredicateKey = "$predicate"
+
+//
+// Repository
+// @Description: 内存仓储，实现 ddd_repository.Repository[T]，用于查询端的单元测试。
+// 实体以json数据保存，读取时返回新的实体对象，字段名称按json标签匹配。
+//
+type Repository[T ddd.Entity] struct {
+	db          *MemoryDB
+	collection  string
+	emptyEntity T
+	newFun      func() T
+}
+
+func NewRepository[T ddd.Entity](newFun func() T, db *MemoryDB, collection string) *Repository[T] {
+	return &Repository[T]{
+		newFun:     newFun,
+		db:         db,
+		collection: collection,
+	}
+}
+
+func (r *Repository[T]) NewEntity() T {
+	return r.newFun()
+}
+
+func (r *Repository[T]) NewEntityList() *[]T {
+	return &[]T{}
+}
+
+func (r *Repository[T]) Insert(ctx context.Context, entity T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	ddd_repository.SetInsertAudit(ctx, entity)
+	return r.DoSet(func() (T, error) {
+		if _, ok := r.db.get(ctx, r.collection, entity.GetTenantId(), entity.GetId()); ok {
+			return entity, errors.New(fmt.Sprintf("duplicate key error, collection: %s, id: %s", r.collection, entity.GetId()))
+		}
+		doc, err := newDocument(entity)
+		if err != nil {
+			return entity, err
+		}
+		r.db.put(ctx, r.collection, doc)
+		return entity, nil
+	})
+}
+
+func (r *Repository[T]) Update(ctx context.Context, entity T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	ddd_repository.SetUpdateAudit(ctx, entity)
+	return r.DoSet(func() (T, error) {
+		existing, ok := r.db.get(ctx, r.collection, entity.GetTenantId(), entity.GetId())
+		if ok {
+			// 保留原创建人与创建时间
+			old, err := r.decode(existing)
+			if err != nil {
+				return entity, err
+			}
+			ddd_repository.CopyCreatedAudit(entity, old)
+		}
+		if version, hasVersion := ddd_repository.GetEntityVersion(entity); hasVersion {
+			return entity, r.updateVersion(ctx, entity, version)
+		}
+		if !ok {
+			return entity, nil
+		}
+		doc, err := newDocument(entity)
+		if err != nil {
+			return entity, err
+		}
+		r.db.put(ctx, r.collection, doc)
+		return entity, nil
+	})
+}
+
+//
+// InsertMany
+// @Description: 批量新建，逐项写入，ctx中的事务有效
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) InsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.setMany(ctx, entities, opts, func(entity T) error {
+		return r.Insert(ctx, entity, opts...).GetError()
+	})
+}
+
+//
+// UpdateMany
+// @Description: 批量更新，逐项写入，不存在的实体被忽略
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) UpdateMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.setMany(ctx, entities, opts, func(entity T) error {
+		return r.Update(ctx, entity, opts...).GetError()
+	})
+}
+
+//
+// UpsertMany
+// @Description: 批量新建或更新，逐项写入。更新已有数据时保留原创建人与创建时间；
+// 启用乐观锁的实体与 Update 一样检查并递增版本号，新建时版本号同样递增
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果，版本号不一致的项返回 *ddd_errors.VersionConflictError
+//
+func (r *Repository[T]) UpsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.setMany(ctx, entities, opts, func(entity T) error {
+		version, hasVersion := ddd_repository.GetEntityVersion(entity)
+		if existing, ok := r.db.get(ctx, r.collection, entity.GetTenantId(), entity.GetId()); ok {
+			old, err := r.decode(existing)
+			if err != nil {
+				return err
+			}
+			ddd_repository.CopyCreatedAudit(entity, old)
+			ddd_repository.SetUpdateAudit(ctx, entity)
+			if hasVersion {
+				return r.updateVersion(ctx, entity, version)
+			}
+		} else {
+			ddd_repository.SetInsertAudit(ctx, entity)
+			if hasVersion {
+				version.Set(version.Value + 1)
+			}
+		}
+		doc, err := newDocument(entity)
+		if err != nil {
+			if hasVersion {
+				version.Set(version.Value - 1)
+			}
+			return err
+		}
+		r.db.put(ctx, r.collection, doc)
+		return nil
+	})
+}
+
+func (r *Repository[T]) Delete(ctx context.Context, entity ddd.Entity, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	return r.DeleteById(ctx, entity.GetTenantId(), entity.GetId(), opts...)
+}
+
+func (r *Repository[T]) DeleteById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	data := map[string]interface{}{
+		IdField: id,
+	}
+	return r.DeleteByMap(ctx, tenantId, data, opts...)
+}
+
+//
+// DeleteByIds
+// @Description: 按id批量删除，不存在的id被忽略
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param ids id列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) DeleteByIds(ctx context.Context, tenantId string, ids []string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetManyResultError[T](err)
+	}
+	items := ddd_repository.SetManyEach(ids, ddd_repository.MergeSetOptions(opts...).GetOrdered(), func(i int) error {
+		r.db.delete(ctx, r.collection, tenantId, ids[i])
+		return nil
+	})
+	return ddd_repository.NewSetManyResult[T](nil, items, nil)
+}
+
+func (r *Repository[T]) DeleteAll(ctx context.Context, tenantId string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	data := map[string]interface{}{}
+	return r.DeleteByMap(ctx, tenantId, data, opts...)
+}
+
+//
+// DeleteByMap
+// @Description: 删除租户下所有与 filterMap 匹配的实体
+// @receiver r
+// @param ctx
+// @param tenantId 租户id
+// @param filterMap 过滤条件，键为字段名称
+// @param opts
+// @return *ddd_repository.SetResult[T]
+//
+func (r *Repository[T]) DeleteByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotNil(filterMap, assert.NewOptions("data is nil")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	return r.DoSet(func() (T, error) {
+		var result T
+		filter, err := newMapPredicate(filterMap)
+		if err != nil {
+			return result, err
+		}
+		for _, doc := range r.db.list(ctx, r.collection, tenantId) {
+			if filter(doc.fields) {
+				r.db.delete(ctx, r.collection, doc.tenantId, doc.id)
+			}
+		}
+		return result, nil
+	})
+}
+
+func (r *Repository[T]) NewFilter(tenantId string, filterMap map[string]interface{}) bson.D {
+	filter := bson.D{
+		{Key: TenantIdField, Value: tenantId},
+	}
+	if filterMap != nil {
+		for fieldName, fieldValue := range filterMap {
+			if fieldName != IdField {
+				fieldName = AsFieldName(fieldName)
+			}
+			e := bson.E{
+				Key:   fieldName,
+				Value: fieldValue,
+			}
+			filter = append(filter, e)
+		}
+	}
+	return filter
+}
+
+func (r *Repository[T]) FindById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
+	idMap := map[string]interface{}{
+		IdField: id,
+	}
+	return r.FindOneByMap(ctx, tenantId, idMap, opts...)
+}
+
+func (r *Repository[T]) FindOneByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
+	return r.DoFindOne(func() (T, bool, error) {
+		list, err := r.findByMap(ctx, tenantId, filterMap)
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
+		if len(list) == 0 {
+			return r.emptyEntity, false, nil
+		}
+		data, err := r.decode(list[0])
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
+		return data, true, nil
+	})
+}
+
+func (r *Repository[T]) FindListByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindListResult[T] {
+	return r.DoFindList(func() (*[]T, bool, error) {
+		docs, err := r.findByMap(ctx, tenantId, filterMap)
+		if err != nil {
+			return nil, false, err
+		}
+		data, err := r.decodeList(docs)
+		if err != nil {
+			return nil, false, err
+		}
+		return data, true, nil
+	})
+}
+
+func (r *Repository[T]) FindAll(ctx context.Context, tenantId string, opts ...*ddd_repository.FindOptions) *ddd_repository.FindListResult[T] {
+	return r.FindListByMap(ctx, tenantId, nil, opts...)
+}
+
+func (r *Repository[T]) FindPaging(ctx context.Context, query ddd_repository.FindPagingQuery, opts ...*ddd_repository.FindOptions) *ddd_repository.FindPagingResult[T] {
+	return r.DoFilter(query.GetTenantId(), query.GetFilter(), func(filter map[string]interface{}) (*ddd_repository.FindPagingResult[T], bool, error) {
+		predicate := GetPredicate(filter)
+		docs := make([]*document, 0)
+		for _, doc := range r.db.list(ctx, r.collection, query.GetTenantId()) {
+			if predicate(doc.fields) {
+				docs = append(docs, doc)
+			}
+		}
+		var sortItems []sortItem
+		if len(query.GetSort()) > 0 {
+			items, err := r.getSort(query.GetSort())
+			if err != nil {
+				return nil, false, err
+			}
+			sortItems = items
+			sortDocuments(docs, sortItems)
+		}
+
+		var totalRows int64
+		if query.GetIsTotalRows() {
+			totalRows = int64(len(docs))
+		}
+		var last *document
+		if pageSize := query.GetPageSize(); pageSize > 0 {
+			skip := pageSize * query.GetPageNum()
+			if token := query.GetContinuationToken(); len(token) > 0 {
+				after, err := getDocumentsAfter(docs, sortItems, token)
+				if err != nil {
+					return nil, false, err
+				}
+				docs, skip = after, 0
+			}
+			count := int64(len(docs))
+			end := skip + pageSize
+			if skip > count {
+				skip = count
+			}
+			if end > count {
+				end = count
+			}
+			docs = docs[skip:end]
+			if int64(len(docs)) == pageSize {
+				last = docs[len(docs)-1]
+			}
+		}
+
+		data, err := r.decodeList(docs)
+		if err != nil {
+			return nil, false, err
+		}
+		findData := ddd_repository.NewFindPagingResult[T](data, totalRows, query, nil)
+		if last != nil {
+			if findData.ContinuationToken, err = newContinuationToken(sortItems, last); err != nil {
+				return nil, false, err
+			}
+		}
+		return findData, true, nil
+	})
+}
+
+//
+// DoFilter
+// @Description: 解析 rsql 过滤条件，fun 的参数中以 PredicateKey 保存过滤条件，可使用 GetPredicate 获取
+// @receiver r
+// @param tenantId 租户id
+// @param filter rsql 表达式
+// @param fun
+// @return *ddd_repository.FindPagingResult[T]
+//
+func (r *Repository[T]) DoFilter(tenantId, filter string, fun func(filter map[string]interface{}) (*ddd_repository.FindPagingResult[T], bool, error)) *ddd_repository.FindPagingResult[T] {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	predicate, err := newRsqlPredicate(filter)
+	if err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	filterData := map[string]interface{}{
+		PredicateKey: predicate,
+	}
+	data, _, err := fun(filterData)
+	if err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	return data
+}
+
+func (r *Repository[T]) DoFindList(fun func() (*[]T, bool, error)) *ddd_repository.FindListResult[T] {
+	data, isFound, err := fun()
+	return ddd_repository.NewFindListResult[T](data, isFound, err)
+}
+
+func (r *Repository[T]) DoFindOne(fun func() (T, bool, error)) *ddd_repository.FindOneResult[T] {
+	data, isFound, err := fun()
+	return ddd_repository.NewFindOneResult[T](data, isFound, err)
+}
+
+func (r *Repository[T]) DoSet(fun func() (T, error)) *ddd_repository.SetResult[T] {
+	data, err := fun()
+	return ddd_repository.NewSetResult[T](data, err)
+}
+
+//
+//  updateVersion
+//  @Description: 按乐观锁版本号更新，版本号一致时递增版本号并写入，否则返回 *ddd_errors.VersionConflictError
+//
+func (r *Repository[T]) updateVersion(ctx context.Context, entity T, version *ddd_repository.EntityVersion) error {
+	current := version.Value
+	version.Set(current + 1)
+	doc, err := newDocument(entity)
+	if err != nil {
+		version.Set(current)
+		return err
+	}
+	ok := r.db.update(ctx, r.collection, doc, func(old *document) bool {
+		if old == nil {
+			return false
+		}
+		value, _ := getFieldValue(old.fields, version.Name)
+		stored, _ := toFloat(value)
+		return int64(stored) == current
+	})
+	if !ok {
+		version.Set(current)
+		return ddd_errors.NewVersionConflictError(entity.GetTenantId(), entity.GetId(), current)
+	}
+	return nil
+}
+
+func (r *Repository[T]) setMany(ctx context.Context, entities []T, opts []*ddd_repository.SetOptions, set func(entity T) error) *ddd_repository.SetManyResult[T] {
+	for i, entity := range entities {
+		if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions(fmt.Sprintf("entities[%d].tenantId is empty", i))); err != nil {
+			return ddd_repository.NewSetManyResultError[T](err)
+		}
+	}
+	ids := ddd_repository.GetEntityIds(entities)
+	items := ddd_repository.SetManyEach(ids, ddd_repository.MergeSetOptions(opts...).GetOrdered(), func(i int) error {
+		return set(entities[i])
+	})
+	return ddd_repository.NewSetManyResult[T](entities, items, nil)
+}
+
+func (r *Repository[T]) findByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}) ([]*document, error) {
+	filter, err := newMapPredicate(filterMap)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*document, 0)
+	for _, doc := range r.db.list(ctx, r.collection, tenantId) {
+		if filter(doc.fields) {
+			res = append(res, doc)
+		}
+	}
+	return res, nil
+}
+
+func (r *Repository[T]) decode(doc *document) (T, error) {
+	data := r.NewEntity()
+	if err := json.Unmarshal(doc.data, data); err != nil {
+		return r.emptyEntity, err
+	}
+	return data, nil
+}
+
+func (r *Repository[T]) decodeList(docs []*document) (*[]T, error) {
+	list := make([]T, 0, len(docs))
+	for _, doc := range docs {
+		data, err := r.decode(doc)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, data)
+	}
+	return &list, nil
+}
+
+type sortItem struct {
+	name string
+	desc bool
+}
+
+func (r *Repository[T]) getSort(sort string) ([]sortItem, error) {
+	//name:desc,id:asc
+	res := make([]sortItem, 0)
+	for _, s := range strings.Split(sort, ",") {
+		item := strings.Split(s, ":")
+		name := strings.Trim(item[0], " ")
+		order := "asc"
+		if len(item) > 1 {
+			order = strings.Trim(strings.ToLower(item[1]), " ")
+		}
+		switch order {
+		case "asc":
+			res = append(res, sortItem{name: name})
+		case "desc":
+			res = append(res, sortItem{name: name, desc: true})
+		default:
+			return nil, errors.New("order " + order + " is error")
+		}
+	}
+	return res, nil
+}
+
+func sortDocuments(docs []*document, items []sortItem) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, item := range items {
+			a, _ := getFieldValue(docs[i].fields, item.name)
+			b, _ := getFieldValue(docs[j].fields, item.name)
+			c, _ := compareValues(a, b)
+			if c == 0 {
+				continue
+			}
+			if item.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+//
+// GetPredicate
+// @Description: 获取 DoFilter 传入的过滤条件
+// @param filter
+// @return Predicate
+//
+func GetPredicate(filter map[string]interface{}) Predicate {
+	if p, ok := filter[PredicateKey].(Predicate); ok {
+		return p
+	}
+	return func(doc map[string]interface{}) bool { return false }
+}
+
+//
+//  newMapPredicate
+//  @Description: 按字段值相等新建过滤条件，值先经json转换，与保存的实体数据保持一致
+//
+func newMapPredicate(filterMap map[string]interface{}) (Predicate, error) {
+	values := make(map[string]interface{}, len(filterMap))
+	for name, value := range filterMap {
+		v, err := toJsonValue(value)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = v
+	}
+	return func(doc map[string]interface{}) bool {
+		for name, value := range values {
+			fieldValue, _ := getFieldValue(doc, name)
+			match := anyMatch(fieldValue, func(item interface{}) bool {
+				return equalsValue(item, value)
+			})
+			if !match {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func newDocument(entity ddd.Entity) (*document, error) {
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return &document{
+		tenantId: entity.GetTenantId(),
+		id:       entity.GetId(),
+		data:     data,
+		fields:   fields,
+	}, nil
+}
+
+func toJsonValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var res interface{}
+	if err := json.Unmarshal(data, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+//
+// AsFieldName
+// @Description: 转换为与 mongodb 一致的字段名称
+// @param name
+// @return string
+//
+func AsFieldName(name string) string {
+	return stringutils.SnakeString(name)
+}
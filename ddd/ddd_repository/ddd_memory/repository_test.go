@@ -0,0 +1,570 @@
+package ddd_memory
+
+import (
+	"context"
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type Movie struct {
+	Id       string    `json:"id"`
+	TenantId string    `json:"tenantId"`
+	Name     string    `json:"name"`
+	Year     int64     `json:"year"`
+	Genres   []string  `json:"genres"`
+	Director *Director `json:"director"`
+}
+
+type Director struct {
+	LastName string `json:"lastName"`
+}
+
+func (m *Movie) GetTenantId() string { return m.TenantId }
+func (m *Movie) GetId() string       { return m.Id }
+
+var _ ddd_repository.Repository[*Movie] = (*Repository[*Movie])(nil)
+
+func newMovieRepository() (*MemoryDB, *Repository[*Movie]) {
+	db := NewMemoryDB()
+	return db, NewRepository[*Movie](func() *Movie { return &Movie{} }, db, "movies")
+}
+
+func insertMovies(t *testing.T, repos *Repository[*Movie]) {
+	ctx := context.Background()
+	movies := []*Movie{
+		{Id: "1", TenantId: "t1", Name: "Inception", Year: 2010, Genres: []string{"sci-fi", "action"}, Director: &Director{LastName: "Nolan"}},
+		{Id: "2", TenantId: "t1", Name: "Interstellar", Year: 2014, Genres: []string{"sci-fi"}, Director: &Director{LastName: "Nolan"}},
+		{Id: "3", TenantId: "t1", Name: "Kill Bill", Year: 2003, Genres: []string{"action"}, Director: &Director{LastName: "Tarantino"}},
+		{Id: "4", TenantId: "t1", Name: "Memento", Year: 2000, Genres: []string{"thriller"}, Director: &Director{LastName: "Nolan"}},
+		{Id: "1", TenantId: "t2", Name: "Other Tenant", Year: 2010, Director: &Director{LastName: "Nolan"}},
+	}
+	for _, m := range movies {
+		assert.NoError(t, repos.Insert(ctx, m).GetError())
+	}
+}
+
+func TestRepository_InsertFind(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	err := repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1"}).GetError()
+	assert.Error(t, err)
+
+	movie, ok, err := repos.FindById(ctx, "t1", "1").Result()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Inception", movie.Name)
+
+	// 返回的是副本
+	movie.Name = "changed"
+	movie, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "Inception", movie.Name)
+
+	_, ok, err = repos.FindById(ctx, "t2", "2").Result()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	list, _, err := repos.FindListByMap(ctx, "t1", map[string]interface{}{"director.lastName": "Nolan"}).Result()
+	assert.NoError(t, err)
+	assert.Len(t, *list, 3)
+
+	list, _, err = repos.FindAll(ctx, "t2").Result()
+	assert.NoError(t, err)
+	assert.Len(t, *list, 1)
+
+	movie.Name = "Inception 2"
+	assert.NoError(t, repos.Update(ctx, movie).GetError())
+	movie, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "Inception 2", movie.Name)
+}
+
+func TestRepository_FindPaging(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	tests := []struct {
+		filter string
+		sort   string
+		ids    []string
+	}{
+		{filter: "", sort: "year:desc", ids: []string{"2", "1", "3", "4"}},
+		{filter: "director.lastName=='Nolan' and year>=2010", sort: "year:asc", ids: []string{"1", "2"}},
+		{filter: "genres=in=('action') or year<2001", sort: "name", ids: []string{"1", "3", "4"}},
+		{filter: "genres=out=('sci-fi')", sort: "id", ids: []string{"3", "4"}},
+		{filter: "name==~'^in'", sort: "id:desc", ids: []string{"2", "1"}},
+		{filter: "(year>2005 and year<2012) or name!='Memento'", sort: "id", ids: []string{"1", "2", "3"}},
+		{filter: "name=search='bill STELLAR'", sort: "id", ids: []string{"2", "3"}},
+		{filter: "year=='2010'", sort: "id", ids: []string{"1"}},
+	}
+	for _, test := range tests {
+		query := ddd_repository.NewFindPagingQuery()
+		query.SetTenantId("t1")
+		query.SetFilter(test.filter)
+		query.SetSort(test.sort)
+		res := repos.FindPaging(ctx, query)
+		if !assert.NoError(t, res.GetError(), test.filter) {
+			continue
+		}
+		ids := make([]string, 0)
+		for _, m := range *res.GetData() {
+			ids = append(ids, m.Id)
+		}
+		assert.Equal(t, test.ids, ids, test.filter)
+	}
+
+	query := ddd_repository.NewFindPagingQuery()
+	query.SetTenantId("t1")
+	query.SetSort("id")
+	query.SetPageSize(3)
+	query.SetPageNum(1)
+	res := repos.FindPaging(ctx, query)
+	assert.NoError(t, res.GetError())
+	assert.Equal(t, int64(4), res.TotalRows)
+	assert.Len(t, *res.GetData(), 1)
+	assert.Equal(t, "4", (*res.GetData())[0].Id)
+
+	query.SetFilter("name==")
+	assert.Error(t, repos.FindPaging(ctx, query).GetError())
+}
+
+func TestRepository_FindPagingContinuationToken(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	query := ddd_repository.NewFindPagingQuery()
+	query.SetTenantId("t1")
+	query.SetSort("director.lastName:asc")
+	query.SetPageSize(2)
+	query.SetIsTotalRows(false)
+
+	ids := make([]string, 0)
+	for i := 0; i < 5; i++ {
+		res := repos.FindPaging(ctx, query)
+		if !assert.NoError(t, res.GetError()) {
+			return
+		}
+		assert.Equal(t, int64(0), res.GetTotalRows())
+		for _, m := range *res.GetData() {
+			ids = append(ids, m.Id)
+		}
+		if res.GetContinuationToken() == "" {
+			break
+		}
+		query.SetContinuationToken(res.GetContinuationToken())
+	}
+	assert.Equal(t, []string{"1", "2", "4", "3"}, ids)
+
+	query.SetSort("year")
+	assert.Error(t, repos.FindPaging(ctx, query).GetError())
+}
+
+func TestRepository_Delete(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	assert.NoError(t, repos.DeleteByMap(ctx, "t1", map[string]interface{}{"director.lastName": "Nolan"}).GetError())
+	list, _, _ := repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 1)
+	list, _, _ = repos.FindAll(ctx, "t2").Result()
+	assert.Len(t, *list, 1)
+
+	assert.NoError(t, repos.DeleteById(ctx, "t1", "3").GetError())
+	assert.NoError(t, repos.DeleteAll(ctx, "t2").GetError())
+	list, _, _ = repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 0)
+	list, _, _ = repos.FindAll(ctx, "t2").Result()
+	assert.Len(t, *list, 0)
+}
+
+func TestSession_UseTransaction(t *testing.T) {
+	db, repos := newMovieRepository()
+	session := NewSession(db)
+	ctx := context.Background()
+
+	errRollback := errors.New("rollback")
+	err := ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+		assert.NoError(t, repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1", Name: "A"}).GetError())
+		_, ok, _ := repos.FindById(ctx, "t1", "1").Result()
+		assert.True(t, ok, "transaction should read its own writes")
+		_, ok, _ = repos.FindById(context.Background(), "t1", "1").Result()
+		assert.False(t, ok, "writes should be invisible before commit")
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	_, ok, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.False(t, ok)
+
+	err = ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+		assert.NoError(t, repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1", Name: "A"}).GetError())
+		assert.NoError(t, repos.Insert(ctx, &Movie{Id: "2", TenantId: "t1", Name: "B"}).GetError())
+		return repos.DeleteById(ctx, "t1", "2").GetError()
+	})
+	assert.NoError(t, err)
+	list, _, _ := repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 1)
+	assert.Equal(t, "A", (*list)[0].Name)
+}
+
+type transientSession struct {
+	ddd_repository.Session
+	failures int
+}
+
+var errTransient = errors.New("transient")
+
+func (s *transientSession) UseTransaction(ctx context.Context, dbFunc ddd_repository.SessionFunc) error {
+	return s.Session.UseTransaction(ctx, func(ctx context.Context) error {
+		if err := dbFunc(ctx); err != nil {
+			return err
+		}
+		if s.failures > 0 {
+			s.failures--
+			return errTransient
+		}
+		return nil
+	})
+}
+
+func (s *transientSession) IsTransientError(err error) bool {
+	return errors.Is(err, errTransient)
+}
+
+func TestStartSession_UnitOfWork(t *testing.T) {
+	db, repos := newMovieRepository()
+	session := NewSession(db)
+	ctx := context.Background()
+
+	var events []string
+	err := ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+		ddd_repository.OnCommit(ctx, func(ctx context.Context) {
+			_, ok, _ := repos.FindById(ctx, "t1", "1").Result()
+			assert.True(t, ok, "commit hooks run after commit")
+			events = append(events, "commit")
+		})
+		return ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+			ddd_repository.OnCommit(ctx, func(ctx context.Context) { events = append(events, "nested commit") })
+			assert.Empty(t, events, "nested session joins the outer unit of work")
+			return repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1", Name: "A"}).GetError()
+		})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"commit", "nested commit"}, events)
+
+	events = nil
+	errRollback := errors.New("rollback")
+	err = ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+		ddd_repository.OnCommit(ctx, func(ctx context.Context) { events = append(events, "commit") })
+		ddd_repository.OnRollback(ctx, func(ctx context.Context, err error) { events = append(events, "rollback: "+err.Error()) })
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	assert.Equal(t, []string{"rollback: rollback"}, events)
+
+	ddd_repository.OnCommit(ctx, func(ctx context.Context) { events = append(events, "no transaction") })
+	assert.Equal(t, "no transaction", events[len(events)-1])
+
+	attempts := 0
+	retrySession := &transientSession{Session: session, failures: 2}
+	opts := ddd_repository.NewSessionOptions().SetRetryBackoff(time.Millisecond)
+	err = ddd_repository.StartSession(ctx, retrySession, func(ctx context.Context) error {
+		attempts++
+		return repos.Insert(ctx, &Movie{Id: "2", TenantId: "t1", Name: "B"}).GetError()
+	}, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	retrySession.failures = 2
+	err = ddd_repository.StartSession(ctx, retrySession, func(ctx context.Context) error {
+		return nil
+	}, opts.SetMaxRetries(1))
+	assert.ErrorIs(t, err, errTransient)
+}
+
+func TestRepository_InsertMany(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	movies := []*Movie{
+		{Id: "5", TenantId: "t1", Name: "Dunkirk"},
+		{Id: "1", TenantId: "t1", Name: "Duplicate"},
+		{Id: "6", TenantId: "t1", Name: "Tenet"},
+	}
+	res := repos.InsertMany(ctx, movies)
+	assert.Error(t, res.GetError())
+	assert.Len(t, res.GetSuccessItems(), 1)
+	failed := res.GetFailedItems()
+	assert.Len(t, failed, 2)
+	assert.Equal(t, 1, failed[0].Index)
+	assert.Equal(t, ddd_repository.ErrNotExecuted, failed[1].Error)
+	_, ok, _ := repos.FindById(ctx, "t1", "6").Result()
+	assert.False(t, ok)
+
+	movies[0].Id, movies[2].Id = "7", "8"
+	res = repos.InsertMany(ctx, movies, ddd_repository.NewSetOptions().SetOrdered(false))
+	assert.Error(t, res.GetError())
+	assert.Len(t, res.GetSuccessItems(), 2)
+	assert.Equal(t, "1", res.GetFailedItems()[0].Id)
+	_, ok, _ = repos.FindById(ctx, "t1", "8").Result()
+	assert.True(t, ok)
+}
+
+func TestRepository_UpsertManyDeleteByIds(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	res := repos.UpsertMany(ctx, []*Movie{
+		{Id: "1", TenantId: "t1", Name: "Inception (2010)"},
+		{Id: "9", TenantId: "t1", Name: "Oppenheimer"},
+	})
+	assert.NoError(t, res.GetError())
+	movie, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "Inception (2010)", movie.Name)
+	_, ok, _ := repos.FindById(ctx, "t1", "9").Result()
+	assert.True(t, ok)
+
+	res = repos.UpdateMany(ctx, []*Movie{{Id: "2", TenantId: "t1", Name: "Interstellar (2014)"}})
+	assert.NoError(t, res.GetError())
+	movie, _, _ = repos.FindById(ctx, "t1", "2").Result()
+	assert.Equal(t, "Interstellar (2014)", movie.Name)
+
+	res = repos.DeleteByIds(ctx, "t1", []string{"1", "2", "9"})
+	assert.NoError(t, res.GetError())
+	assert.Len(t, res.GetItems(), 3)
+	list, _, _ := repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 2)
+	_, ok, _ = repos.FindById(ctx, "t2", "1").Result()
+	assert.True(t, ok)
+}
+
+func TestRepository_InsertManyTransaction(t *testing.T) {
+	db, repos := newMovieRepository()
+	session := NewSession(db)
+	ctx := context.Background()
+
+	err := session.UseTransaction(ctx, func(ctx context.Context) error {
+		res := repos.InsertMany(ctx, []*Movie{{Id: "1", TenantId: "t1"}, {Id: "2", TenantId: "t1"}})
+		if res.GetError() != nil {
+			return res.GetError()
+		}
+		return errors.New("rollback")
+	})
+	assert.Error(t, err)
+	list, _, _ := repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 0)
+}
+
+type Account struct {
+	Id       string `json:"id"`
+	TenantId string `json:"tenantId"`
+	Balance  int64  `json:"balance"`
+	Version  int64  `json:"version"`
+}
+
+func (a *Account) GetTenantId() string      { return a.TenantId }
+func (a *Account) GetId() string            { return a.Id }
+func (a *Account) GetVersion() int64        { return a.Version }
+func (a *Account) SetVersion(version int64) { a.Version = version }
+
+func TestRepository_UpdateVersion(t *testing.T) {
+	db := NewMemoryDB()
+	repos := NewRepository[*Account](func() *Account { return &Account{} }, db, "accounts")
+	ctx := context.Background()
+	assert.NoError(t, repos.Insert(ctx, &Account{Id: "1", TenantId: "t1"}).GetError())
+
+	first, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	second, _, _ := repos.FindById(ctx, "t1", "1").Result()
+
+	first.Balance = 10
+	assert.NoError(t, repos.Update(ctx, first).GetError())
+	assert.Equal(t, int64(1), first.Version)
+
+	second.Balance = 20
+	err := repos.Update(ctx, second).GetError()
+	assert.True(t, ddd_errors.IsErrorVersionConflict(err))
+	assert.Equal(t, int64(0), second.Version)
+
+	second, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, int64(10), second.Balance)
+	second.Balance = 20
+	assert.NoError(t, repos.Update(ctx, second).GetError())
+	assert.Equal(t, int64(2), second.Version)
+
+	err = repos.Update(ctx, &Account{Id: "2", TenantId: "t1"}).GetError()
+	assert.True(t, ddd_errors.IsErrorVersionConflict(err))
+}
+
+func TestRepository_UpsertManyVersion(t *testing.T) {
+	db := NewMemoryDB()
+	repos := NewRepository[*Account](func() *Account { return &Account{} }, db, "accounts")
+	ctx := context.Background()
+	assert.NoError(t, repos.Insert(ctx, &Account{Id: "1", TenantId: "t1", Version: 3}).GetError())
+
+	accounts := []*Account{
+		{Id: "1", TenantId: "t1", Balance: 10, Version: 3},
+		{Id: "2", TenantId: "t1", Balance: 20},
+	}
+	assert.NoError(t, repos.UpsertMany(ctx, accounts).GetError())
+	assert.Equal(t, int64(4), accounts[0].Version)
+	assert.Equal(t, int64(1), accounts[1].Version)
+
+	stale := []*Account{{Id: "1", TenantId: "t1", Balance: 30, Version: 3}}
+	res := repos.UpsertMany(ctx, stale)
+	assert.True(t, ddd_errors.IsErrorVersionConflict(res.GetItems()[0].Error))
+	assert.Equal(t, int64(3), stale[0].Version)
+
+	stored, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, int64(10), stored.Balance)
+	assert.Equal(t, int64(4), stored.Version)
+}
+
+type Note struct {
+	Id          string     `json:"id"`
+	TenantId    string     `json:"tenantId"`
+	Text        string     `json:"text"`
+	CreatedBy   string     `json:"createdBy" ddd:"createdBy"`
+	CreatedTime time.Time  `json:"createdTime" ddd:"createdTime"`
+	UpdatedBy   string     `json:"updatedBy" ddd:"updatedBy"`
+	UpdatedTime *time.Time `json:"updatedTime" ddd:"updatedTime"`
+}
+
+func (n *Note) GetTenantId() string { return n.TenantId }
+func (n *Note) GetId() string       { return n.Id }
+
+func TestRepository_Audit(t *testing.T) {
+	now := time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC)
+	ddd_repository.SetClock(ddd_repository.ClockFunc(func() time.Time { return now }))
+	defer ddd_repository.SetClock(nil)
+
+	repos := NewRepository[*Note](func() *Note { return &Note{} }, NewMemoryDB(), "notes")
+	ctx := ddd_context.NewContext(context.Background(), map[string]string{"X-User-Id": "u1"}, nil)
+	note := &Note{Id: "1", TenantId: "t1", Text: "a"}
+	assert.NoError(t, repos.Insert(ctx, note).GetError())
+	assert.Equal(t, "u1", note.CreatedBy)
+	assert.Equal(t, now, note.CreatedTime)
+	assert.Equal(t, "u1", note.UpdatedBy)
+	assert.Equal(t, now, *note.UpdatedTime)
+
+	created := now
+	now = now.Add(time.Hour)
+	ctx = ddd_context.NewContext(context.Background(), map[string]string{"x-user-id": "u2"}, nil)
+	note.Text = "b"
+	assert.NoError(t, repos.Update(ctx, note).GetError())
+	found, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "u1", found.CreatedBy)
+	assert.Equal(t, created, found.CreatedTime)
+	assert.Equal(t, "u2", found.UpdatedBy)
+	assert.Equal(t, now, found.UpdatedTime.UTC())
+
+	result := repos.UpsertMany(ctx, []*Note{{Id: "2", TenantId: "t1"}})
+	assert.NoError(t, result.GetError())
+	found, _, _ = repos.FindById(ctx, "t1", "2").Result()
+	assert.Equal(t, "u2", found.CreatedBy)
+	assert.Equal(t, now, found.CreatedTime.UTC())
+
+	now = now.Add(time.Hour)
+	upsert := &Note{Id: "1", TenantId: "t1", Text: "c"}
+	assert.NoError(t, repos.UpsertMany(ctx, []*Note{upsert}).GetError())
+	assert.Equal(t, "u1", upsert.CreatedBy)
+	found, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "c", found.Text)
+	assert.Equal(t, "u1", found.CreatedBy)
+	assert.Equal(t, created, found.CreatedTime.UTC())
+	assert.Equal(t, now, found.UpdatedTime.UTC())
+
+	update := &Note{Id: "1", TenantId: "t1", Text: "d"}
+	assert.NoError(t, repos.Update(ctx, update).GetError())
+	assert.Equal(t, "u1", update.CreatedBy)
+	found, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "d", found.Text)
+	assert.Equal(t, "u1", found.CreatedBy)
+	assert.Equal(t, created, found.CreatedTime.UTC())
+}
+
+func TestRepository_Aggregate(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	count, err := repos.Count(ctx, "t1", "year>2005")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	rows, err := repos.Aggregate(ctx, "t1", "", []string{"director.lastName"}, []ddd_repository.Metric{
+		ddd_repository.NewCountMetric("count"),
+		ddd_repository.NewSumMetric("sumYear", "year"),
+		ddd_repository.NewMaxMetric("maxYear", "year"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "Nolan", rows[0].GetGroupString("director.lastName"))
+	assert.Equal(t, int64(3), rows[0].GetInt("count"))
+	assert.Equal(t, int64(6024), rows[0].GetInt("sumYear"))
+	assert.Equal(t, int64(2014), rows[0].GetInt("maxYear"))
+	assert.Equal(t, "Tarantino", rows[1].GetGroupString("director.lastName"))
+	assert.Equal(t, int64(1), rows[1].GetInt("count"))
+
+	rows, err = repos.Aggregate(ctx, "t1", "", nil, []ddd_repository.Metric{ddd_repository.NewAvgMetric("avgYear", "year")})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, 2006.75, rows[0].GetFloat("avgYear"))
+
+	_, err = repos.Aggregate(ctx, "t1", "", nil, []ddd_repository.Metric{{Name: "sum", Type: ddd_repository.MetricSum}})
+	assert.Error(t, err)
+
+	values, err := repos.Distinct(ctx, "t1", "genres", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"action", "sci-fi", "thriller"}, values)
+}
+
+func TestRepository_ForEach(t *testing.T) {
+	_, repos := newMovieRepository()
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	names := make([]string, 0)
+	err := repos.ForEach(ctx, "t1", "director.lastName=='Nolan'", func(movie *Movie) error {
+		names = append(names, movie.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Inception", "Interstellar", "Memento"}, names)
+
+	count := 0
+	err = repos.ForEach(ctx, "t1", "", func(movie *Movie) error {
+		count++
+		if count == 2 {
+			return ddd_repository.ErrStopIteration
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	stream, err := repos.FindStream(ctx, "t1", "", ddd_repository.NewFindOptions().SetSkip(1).SetLimit(2))
+	assert.NoError(t, err)
+	ids := make([]string, 0)
+	for stream.Next(ctx) {
+		ids = append(ids, stream.Current().Id)
+	}
+	assert.NoError(t, stream.Err())
+	assert.NoError(t, stream.Close(ctx))
+	assert.Equal(t, []string{"2", "3"}, ids)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = repos.ForEach(cancelCtx, "t1", "", func(movie *Movie) error {
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,196 @@
+package ddd_memory
+
+import (
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"strings"
+	"time"
+)
+
+// Predicate 内存文档过滤条件
+type Predicate func(doc map[string]interface{}) bool
+
+//
+//  newRsqlPredicate
+//  @Description: 将 rsql 表达式编译为内存文档过滤条件，与 rsql.Compile 及 types.Items.Filter 的匹配规则一致。租户隔离由仓储读取数据时保证
+//  @param filter rsql 表达式，为空时所有文档都满足
+//  @return Predicate
+//  @return error 表达式错误
+//
+func newRsqlPredicate(filter string) (Predicate, error) {
+	p, err := rsql.Compile(filter)
+	if err != nil {
+		return nil, err
+	}
+	return func(doc map[string]interface{}) bool {
+		return p(doc)
+	}, nil
+}
+
+//
+//  anyMatch
+//  @Description: 字段值为数组时任一元素满足即可，与 mongodb 的数组查询语义一致
+//
+func anyMatch(fieldValue interface{}, match func(item interface{}) bool) bool {
+	if list, ok := fieldValue.([]interface{}); ok {
+		for _, item := range list {
+			if match(item) {
+				return true
+			}
+		}
+		return false
+	}
+	return match(fieldValue)
+}
+
+func equalsValue(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	c, ok := compareValues(a, b)
+	return ok && c == 0
+}
+
+//
+//  compareValues
+//  @Description: 比较两个值，数字按数值比较，可解析为时间的字符串按时间比较
+//  @return int 比较结果，-1 小于，0 等于，1 大于
+//  @return bool 是否可以比较
+//
+func compareValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		if a == nil && b == nil {
+			return 0, true
+		}
+		if a == nil {
+			return -1, true
+		}
+		return 1, true
+	}
+	if fa, ok := toFloat(a); ok {
+		if fb, ok := toFloat(b); ok {
+			return compareFloat(fa, fb), true
+		}
+		return 0, false
+	}
+	switch av := a.(type) {
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return 0, false
+		}
+		if ta, ok := parseTime(av); ok {
+			if tb, ok := parseTime(bv); ok {
+				return compareTime(ta, tb), true
+			}
+		}
+		return strings.Compare(av, bv), true
+	case bool:
+		bv, ok := b.(bool)
+		if !ok {
+			return 0, false
+		}
+		if av == bv {
+			return 0, true
+		}
+		if !av {
+			return -1, true
+		}
+		return 1, true
+	}
+	return strings.Compare(fmt.Sprintf("%v", a), fmt.Sprintf("%v", b)), true
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, bool) {
+	if len(s) < 10 || s[4] != '-' {
+		return time.Time{}, false
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func compareTime(a, b time.Time) int {
+	if a.Before(b) {
+		return -1
+	} else if a.After(b) {
+		return 1
+	}
+	return 0
+}
+
+//
+//  getFieldValue
+//  @Description: 按字段路径获取文档中的值，支持 a.b 形式的嵌套路径。
+//  字段名称忽略大小写与下划线，因此 tenantId 与 tenant_id 均可匹配；id 与 _id 等同。
+//
+func getFieldValue(doc map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = doc
+	for _, name := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = getMapValue(m, name)
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func getMapValue(m map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	key := normalizeFieldName(name)
+	for k, v := range m {
+		if normalizeFieldName(k) == key {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func normalizeFieldName(name string) string {
+	if name == IdField {
+		name = "id"
+	}
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
@@ -0,0 +1,35 @@
+package ddd_memory
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+)
+
+type MemorySession struct {
+	db *MemoryDB
+}
+
+func NewSession(db *MemoryDB) ddd_repository.Session {
+	return &MemorySession{db: db}
+}
+
+//
+// UseTransaction
+// @Description: 在事务中执行，写入先缓存在事务中，dbFunc 成功后一次提交，返回错误时全部丢弃。
+// 已在事务中时加入当前事务。
+// @receiver s
+// @param ctx
+// @param dbFunc
+// @return error
+//
+func (s *MemorySession) UseTransaction(ctx context.Context, dbFunc ddd_repository.SessionFunc) error {
+	if getMemoryTx(ctx) != nil {
+		return dbFunc(ctx)
+	}
+	tx := &memoryTx{writes: make(map[string]map[string]*document)}
+	if err := dbFunc(context.WithValue(ctx, memoryTxKey{}, tx)); err != nil {
+		return err
+	}
+	s.db.commit(tx)
+	return nil
+}
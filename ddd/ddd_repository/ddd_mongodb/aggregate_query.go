@@ -0,0 +1,182 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+)
+
+//
+// Count
+// @Description: 统计租户下与过滤条件匹配的数量
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件，为空时统计全部
+// @return int64 数量
+// @return error
+//
+func (r *Repository[T]) Count(ctx context.Context, tenantId string, filter string) (int64, error) {
+	match, err := r.getMatchFilter(tenantId, filter, false)
+	if err != nil {
+		return 0, err
+	}
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return 0, err
+	}
+	return collection.CountDocuments(ctx, match)
+}
+
+//
+// Aggregate
+// @Description: 分组统计，使用聚合管道执行，始终按租户过滤
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param groupBy 分组字段，驼峰格式，为空时统计全部数据
+// @param metrics 统计指标
+// @return []*ddd_repository.AggregateRow 按分组字段排序的结果行
+// @return error
+//
+func (r *Repository[T]) Aggregate(ctx context.Context, tenantId string, filter string, groupBy []string, metrics []ddd_repository.Metric) ([]*ddd_repository.AggregateRow, error) {
+	if err := ddd_repository.ValidateMetrics(metrics); err != nil {
+		return nil, err
+	}
+	match, err := r.getMatchFilter(tenantId, filter, false)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline, err := newAggregatePipeline(reflect.TypeOf(r.NewEntity()), match, groupBy, metrics)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	rows := make([]*ddd_repository.AggregateRow, len(docs))
+	for i, doc := range docs {
+		row := ddd_repository.NewAggregateRow()
+		id, _ := doc[IdField].(bson.M)
+		for j, field := range groupBy {
+			row.Group[field] = id[getGroupKey(j)]
+		}
+		for j, metric := range metrics {
+			row.Metrics[metric.Name] = doc[getMetricKey(j)]
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+//
+// Distinct
+// @Description: 获取字段的不重复值
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param field 字段，驼峰格式
+// @param filter rsql过滤条件
+// @return []interface{}
+// @return error
+//
+func (r *Repository[T]) Distinct(ctx context.Context, tenantId string, field string, filter string) ([]interface{}, error) {
+	fieldName, err := getFieldPath(reflect.TypeOf(r.NewEntity()), field)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("field %s: %s", field, err.Error()))
+	}
+	match, err := r.getMatchFilter(tenantId, filter, false)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Distinct(ctx, fieldName, match)
+}
+
+//
+//  getMatchFilter
+//  @Description: 按rsql生成过滤条件，包含租户条件，启用软删除时排除已删除的数据
+//
+func (r *Repository[T]) getMatchFilter(tenantId string, filter string, includeDeleted bool) (map[string]interface{}, error) {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return nil, err
+	}
+	p := NewMongoProcess()
+	if err := rsql.ParseProcess(filter, p); err != nil {
+		return nil, err
+	}
+	match := p.GetFilter(tenantId)
+	if err := r.checkTextSearch(match); err != nil {
+		return nil, err
+	}
+	return r.addNotDeletedFilter(match, includeDeleted), nil
+}
+
+//
+//  newAggregatePipeline
+//  @Description: 生成分组统计的聚合管道，分组字段与指标在结果中使用 g0、m0 等键，避免字段名中的.
+//
+func newAggregatePipeline(entityType reflect.Type, match interface{}, groupBy []string, metrics []ddd_repository.Metric) (bson.A, error) {
+	groupId := bson.D{}
+	for i, field := range groupBy {
+		fieldName, err := getFieldPath(entityType, field)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("group field %s: %s", field, err.Error()))
+		}
+		groupId = append(groupId, bson.E{Key: getGroupKey(i), Value: "$" + fieldName})
+	}
+	group := bson.D{{Key: IdField, Value: groupId}}
+	if len(groupId) == 0 {
+		group = bson.D{{Key: IdField, Value: nil}}
+	}
+	for i, metric := range metrics {
+		accumulator, err := getAccumulator(entityType, metric)
+		if err != nil {
+			return nil, err
+		}
+		group = append(group, bson.E{Key: getMetricKey(i), Value: accumulator})
+	}
+	pipeline := bson.A{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: group}},
+	}
+	if len(groupId) > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$sort", Value: bson.D{{Key: IdField, Value: 1}}}})
+	}
+	return pipeline, nil
+}
+
+func getAccumulator(entityType reflect.Type, metric ddd_repository.Metric) (bson.D, error) {
+	if metric.Type == ddd_repository.MetricCount {
+		return bson.D{{Key: "$sum", Value: 1}}, nil
+	}
+	fieldName, err := getFieldPath(entityType, metric.Field)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("metric %s field %s: %s", metric.Name, metric.Field, err.Error()))
+	}
+	return bson.D{{Key: "$" + string(metric.Type), Value: "$" + fieldName}}, nil
+}
+
+func getGroupKey(i int) string {
+	return fmt.Sprintf("g%d", i)
+}
+
+func getMetricKey(i int) string {
+	return fmt.Sprintf("m%d", i)
+}
@@ -0,0 +1,36 @@
+package ddd_mongodb
+
+import (
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"testing"
+)
+
+func Test_newAggregatePipeline(t *testing.T) {
+	entityType := reflect.TypeOf(&maskCustomer{})
+	match := bson.M{TenantIdField: "001"}
+	metrics := []ddd_repository.Metric{
+		ddd_repository.NewCountMetric("count"),
+		ddd_repository.NewMaxMetric("lastName", "userName"),
+	}
+	pipeline, err := newAggregatePipeline(entityType, match, []string{"address.city"}, metrics)
+	assert.NoError(t, err)
+	data, err := bson.MarshalExtJSON(bson.M{"p": pipeline}, false, false)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"p":[
+		{"$match":{"tenant_id":"001"}},
+		{"$group":{"_id":{"g0":"$address.city"},"m0":{"$sum":1},"m1":{"$max":"$user_name"}}},
+		{"$sort":{"_id":1}}
+	]}`, string(data))
+
+	pipeline, err = newAggregatePipeline(entityType, match, nil, metrics[:1])
+	assert.NoError(t, err)
+	assert.Len(t, pipeline, 2)
+
+	_, err = newAggregatePipeline(entityType, match, []string{"unknown"}, metrics)
+	assert.Error(t, err)
+	_, err = newAggregatePipeline(entityType, match, nil, []ddd_repository.Metric{ddd_repository.NewSumMetric("total", "unknown")})
+	assert.Error(t, err)
+}
@@ -0,0 +1,182 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+type versionUser struct {
+	Id       string `json:"id" bson:"_id"`
+	TenantId string `json:"tenantId" bson:"tenant_id"`
+	UserName string `json:"userName" bson:"user_name"`
+	Version  int64  `json:"version" bson:"version" ddd:"version"`
+}
+
+func (u *versionUser) GetTenantId() string {
+	return u.TenantId
+}
+
+func (u *versionUser) GetId() string {
+	return u.Id
+}
+
+func newMockVersionUserRepository(mt *mtest.T) *Repository[*versionUser] {
+	return NewRepository[*versionUser](func() *versionUser { return &versionUser{} }, nil, mt.Coll)
+}
+
+func TestRepository_UpdateManyVersion(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+
+	mt.Run("update", func(mt *mtest.T) {
+		repos := newMockVersionUserRepository(mt)
+		users := []*versionUser{
+			{Id: "1", TenantId: "001", UserName: "a", Version: 1},
+			{Id: "2", TenantId: "001", UserName: "b", Version: 1},
+		}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateCursorResponse(0, mockNamespace(mt), mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "tenant_id", Value: "001"}, {Key: "version", Value: int64(2)}},
+				bson.D{{Key: "_id", Value: "2"}, {Key: "tenant_id", Value: "001"}, {Key: "version", Value: int64(5)}}),
+		)
+		res := repos.UpdateMany(ctx, users)
+		assert.Error(t, res.GetError())
+		items := res.GetItems()
+		assert.True(t, items[0].IsSuccess())
+		_, ok := items[1].Error.(*ddd_errors.VersionConflictError)
+		assert.True(t, ok)
+		assert.Equal(t, int64(2), users[0].Version)
+		assert.Equal(t, int64(1), users[1].Version)
+
+		cmd := mt.GetStartedEvent().Command
+		update := cmd.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, int64(1), update.Lookup("q", "version").AsInt64())
+		assert.Equal(t, int64(1), update.Lookup("u", "$inc", "version").AsInt64())
+		_, err := update.LookupErr("u", "$set", "version")
+		assert.Error(t, err)
+		find := mt.GetStartedEvent().Command
+		assert.Equal(t, "001", find.Lookup("filter", "tenant_id", "$in").Array().Index(0).Value().StringValue())
+	})
+
+	mt.Run("bson name", func(mt *mtest.T) {
+		repos := NewRepository[*revisionUser](func() *revisionUser { return &revisionUser{} }, nil, mt.Coll)
+		user := &revisionUser{Id: "1", TenantId: "001", UserName: "a", Revision: 1}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		assert.NoError(t, repos.UpdateMany(ctx, []*revisionUser{user}).GetError())
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, int64(1), update.Lookup("q", "rev").AsInt64())
+		assert.Equal(t, int64(1), update.Lookup("u", "$inc", "rev").AsInt64())
+
+		assert.NoError(t, repos.Update(ctx, user).GetError())
+		update = mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, int64(2), update.Lookup("q", "rev").AsInt64())
+		assert.Equal(t, int64(3), user.Revision)
+	})
+
+	mt.Run("upsert", func(mt *mtest.T) {
+		repos := newMockVersionUserRepository(mt)
+		users := []*versionUser{
+			{Id: "1", TenantId: "001", UserName: "a", Version: 3},
+			{Id: "2", TenantId: "001", UserName: "b", Version: 3},
+		}
+		mt.AddMockResponses(
+			mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}),
+			mtest.CreateCursorResponse(0, mockNamespace(mt), mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: "1"}, {Key: "tenant_id", Value: "001"}, {Key: "version", Value: int64(4)}}),
+		)
+		res := repos.UpsertMany(ctx, users)
+		items := res.GetItems()
+		assert.True(t, items[0].IsSuccess())
+		_, ok := items[1].Error.(*ddd_errors.VersionConflictError)
+		assert.True(t, ok)
+		assert.Equal(t, int64(4), users[0].Version)
+		assert.Equal(t, int64(3), users[1].Version)
+	})
+}
+
+type revisionUser struct {
+	Id       string `json:"id" bson:"_id"`
+	TenantId string `json:"tenantId" bson:"tenant_id"`
+	UserName string `json:"userName" bson:"user_name"`
+	Revision int64  `json:"version" bson:"rev" ddd:"version"`
+}
+
+func (u *revisionUser) GetTenantId() string {
+	return u.TenantId
+}
+
+func (u *revisionUser) GetId() string {
+	return u.Id
+}
+
+type auditUser struct {
+	Id          string    `json:"id" bson:"_id"`
+	TenantId    string    `json:"tenantId" bson:"tenant_id"`
+	UserName    string    `json:"userName" bson:"user_name"`
+	CreatedBy   string    `json:"createdBy" bson:"created_by" ddd:"createdBy"`
+	CreatedTime time.Time `json:"createdTime" bson:"created_time" ddd:"createdTime"`
+	UpdatedBy   string    `json:"updatedBy" bson:"updated_by" ddd:"updatedBy"`
+}
+
+func (u *auditUser) GetTenantId() string {
+	return u.TenantId
+}
+
+func (u *auditUser) GetId() string {
+	return u.Id
+}
+
+func TestRepository_UpsertManyAudit(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+
+	mt.Run("upsert", func(mt *mtest.T) {
+		repos := NewRepository[*auditUser](func() *auditUser { return &auditUser{} }, nil, mt.Coll)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		assert.NoError(t, repos.UpsertMany(ctx, []*auditUser{{Id: "1", TenantId: "001", UserName: "a"}}).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		update := cmd.Lookup("updates").Array().Index(0).Value().Document()
+		assert.True(t, update.Lookup("upsert").Boolean())
+		assert.Equal(t, "a", update.Lookup("u", "$set", "user_name").StringValue())
+		_, err := update.LookupErr("u", "$set", "created_time")
+		assert.Error(t, err)
+		_, err = update.LookupErr("u", "$setOnInsert", "created_by")
+		assert.NoError(t, err)
+		_, err = update.LookupErr("u", "$setOnInsert", "created_time")
+		assert.NoError(t, err)
+	})
+
+	mt.Run("update", func(mt *mtest.T) {
+		repos := NewRepository[*auditUser](func() *auditUser { return &auditUser{} }, nil, mt.Coll)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		user := &auditUser{Id: "1", TenantId: "001", UserName: "a"}
+		assert.NoError(t, repos.Update(ctx, user).GetError())
+		assert.NoError(t, repos.UpdateMany(ctx, []*auditUser{user}).GetError())
+
+		for i := 0; i < 2; i++ {
+			update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+			assert.Equal(t, "a", update.Lookup("u", "$set", "user_name").StringValue())
+			_, err := update.LookupErr("u", "$set", "created_by")
+			assert.Error(t, err)
+			_, err = update.LookupErr("u", "$set", "created_time")
+			assert.Error(t, err)
+			_, err = update.LookupErr("u", "$setOnInsert")
+			assert.Error(t, err)
+		}
+	})
+}
@@ -0,0 +1,337 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"strings"
+	"sync"
+	"time"
+)
+
+//
+// ChangeType
+// @Description: 数据变更类型
+//
+type ChangeType string
+
+const (
+	ChangeInsert ChangeType = "insert"
+	ChangeUpdate ChangeType = "update"
+	ChangeDelete ChangeType = "delete"
+)
+
+//
+// ChangeEvent
+// @Description: 数据变更通知
+//
+type ChangeEvent[T ddd.Entity] struct {
+	Type        ChangeType `json:"type"`
+	Id          string     `json:"id"`
+	Data        T          `json:"data"` // 变更后的完整数据，删除时为删除前的数据
+	ResumeToken string     `json:"-"`    // 续接令牌，重新监听时从此通知之后开始
+}
+
+func (e *ChangeEvent[T]) GetSseId() string {
+	return e.ResumeToken
+}
+
+func (e *ChangeEvent[T]) GetSseEvent() string {
+	return string(e.Type)
+}
+
+//
+// WatchOptions
+// @Description: 监听设置
+//
+type WatchOptions struct {
+	ResumeToken  *string        // 从此令牌之后开始监听，用于断线重连
+	BufferSize   *int           // 通知通道的缓冲大小，默认为16
+	MaxAwaitTime *time.Duration // 等待新变更的最长时间
+}
+
+func NewWatchOptions() *WatchOptions {
+	return &WatchOptions{}
+}
+
+func (o *WatchOptions) SetResumeToken(resumeToken string) *WatchOptions {
+	o.ResumeToken = &resumeToken
+	return o
+}
+
+func (o *WatchOptions) SetBufferSize(bufferSize int) *WatchOptions {
+	o.BufferSize = &bufferSize
+	return o
+}
+
+func (o *WatchOptions) SetMaxAwaitTime(maxAwaitTime time.Duration) *WatchOptions {
+	o.MaxAwaitTime = &maxAwaitTime
+	return o
+}
+
+func MergeWatchOptions(opts ...*WatchOptions) *WatchOptions {
+	res := &WatchOptions{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if o.ResumeToken != nil {
+			res.ResumeToken = o.ResumeToken
+		}
+		if o.BufferSize != nil {
+			res.BufferSize = o.BufferSize
+		}
+		if o.MaxAwaitTime != nil {
+			res.MaxAwaitTime = o.MaxAwaitTime
+		}
+	}
+	return res
+}
+
+//
+// LiveQuery
+// @Description: 实时查询，通过 Events 接收变更通知，停止后通道关闭
+//
+type LiveQuery[T ddd.Entity] struct {
+	events chan *ChangeEvent[T]
+	cancel context.CancelFunc
+	done   chan struct{}
+	mu     sync.Mutex
+	err    error
+}
+
+//
+// Events
+// @Description: 变更通知通道，ctx取消、调用 Close 或出错时关闭
+// @return <-chan *ChangeEvent[T]
+//
+func (q *LiveQuery[T]) Events() <-chan *ChangeEvent[T] {
+	return q.events
+}
+
+//
+// Err
+// @Description: 通道关闭的原因，ctx取消时为ctx的错误，调用 Close 停止时为nil
+// @return error
+//
+func (q *LiveQuery[T]) Err() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.err
+}
+
+//
+// Close
+// @Description: 停止监听并等待通道关闭
+// @return error
+//
+func (q *LiveQuery[T]) Close() error {
+	q.cancel()
+	<-q.done
+	return nil
+}
+
+func (q *LiveQuery[T]) setErr(err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.err = err
+}
+
+//
+// Watch
+// @Description: 监听集合中租户下与过滤条件匹配的数据变更。需要Mongo副本集；删除通知需要集合启用
+// changeStreamPreAndPostImages 才能取得删除前的数据，否则无法按租户过滤，不会通知。启用软删除的仓储中软删除作为删除通知
+// @param ctx 上下文，取消时停止监听
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param opts 监听设置
+// @return *LiveQuery[T]
+// @return error
+//
+func (r *Repository[T]) Watch(ctx context.Context, tenantId string, filter string, opts ...*WatchOptions) (*LiveQuery[T], error) {
+	match, err := r.getMatchFilter(tenantId, filter, true)
+	if err != nil {
+		return nil, err
+	}
+	if hasTextSearch(match) {
+		return nil, errors.New("rsql =search= is not supported by change streams")
+	}
+	opt := MergeWatchOptions(opts...)
+	streamOptions := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetCustomPipeline(bson.M{"fullDocumentBeforeChange": "whenAvailable"})
+	if opt.ResumeToken != nil && len(*opt.ResumeToken) > 0 {
+		streamOptions.SetResumeAfter(bson.M{"_data": *opt.ResumeToken})
+	}
+	if opt.MaxAwaitTime != nil {
+		streamOptions.SetMaxAwaitTime(*opt.MaxAwaitTime)
+	}
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := collection.Watch(ctx, newChangeStreamPipeline(match), streamOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	bufferSize := 16
+	if opt.BufferSize != nil && *opt.BufferSize >= 0 {
+		bufferSize = *opt.BufferSize
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	q := &LiveQuery[T]{
+		events: make(chan *ChangeEvent[T], bufferSize),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(q.done)
+		defer close(q.events)
+		defer func() {
+			_ = stream.Close(context.Background())
+		}()
+		for stream.Next(watchCtx) {
+			event, err := r.newChangeEvent(stream.Current)
+			if err != nil {
+				q.setErr(err)
+				return
+			}
+			select {
+			case q.events <- event:
+			case <-watchCtx.Done():
+				q.setErr(ctx.Err())
+				return
+			}
+		}
+		if err := stream.Err(); err != nil && watchCtx.Err() == nil {
+			q.setErr(err)
+			return
+		}
+		q.setErr(ctx.Err())
+	}()
+	return q, nil
+}
+
+//
+//  newChangeStreamPipeline
+//  @Description: 按过滤条件生成变更流的管道，新建与更新按变更后的数据过滤，删除按删除前的数据过滤
+//  @param match 包含租户条件的过滤条件
+//  @return mongo.Pipeline
+//
+func newChangeStreamPipeline(match map[string]interface{}) mongo.Pipeline {
+	return mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"$and": bson.A{
+				bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace"}}},
+				prefixFilter(match, "fullDocument"),
+			}},
+			bson.M{"$and": bson.A{
+				bson.M{"operationType": "delete"},
+				prefixFilter(match, "fullDocumentBeforeChange"),
+			}},
+		}}}},
+	}
+}
+
+//
+//  prefixFilter
+//  @Description: 为过滤条件中的字段名加前缀，$and、$or 等逻辑操作符中的条件递归处理
+//
+func prefixFilter(filter interface{}, prefix string) interface{} {
+	switch f := filter.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(f))
+		for key, value := range f {
+			res[prefixKey(key, value, prefix)] = prefixValue(key, value, prefix)
+		}
+		return res
+	case bson.M:
+		return prefixFilter(map[string]interface{}(f), prefix)
+	case bson.D:
+		res := make(bson.D, len(f))
+		for i, e := range f {
+			res[i] = bson.E{Key: prefixKey(e.Key, e.Value, prefix), Value: prefixValue(e.Key, e.Value, prefix)}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(f))
+		for i, item := range f {
+			res[i] = prefixFilter(item, prefix)
+		}
+		return res
+	case bson.A:
+		return prefixFilter([]interface{}(f), prefix)
+	}
+	return filter
+}
+
+func prefixKey(key string, value interface{}, prefix string) string {
+	if strings.HasPrefix(key, "$") {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func prefixValue(key string, value interface{}, prefix string) interface{} {
+	if strings.HasPrefix(key, "$") {
+		return prefixFilter(value, prefix)
+	}
+	return value
+}
+
+// 变更流返回的通知
+type changeDocument struct {
+	Id                       bson.Raw `bson:"_id"`
+	OperationType            string   `bson:"operationType"`
+	DocumentKey              bson.Raw `bson:"documentKey"`
+	FullDocument             bson.Raw `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.Raw `bson:"fullDocumentBeforeChange"`
+}
+
+//
+//  newChangeEvent
+//  @Description: 将变更流的通知转换为变更通知，软删除的更新转换为删除
+//
+func (r *Repository[T]) newChangeEvent(raw bson.Raw) (*ChangeEvent[T], error) {
+	doc := &changeDocument{}
+	if err := bson.Unmarshal(raw, doc); err != nil {
+		return nil, err
+	}
+	event := &ChangeEvent[T]{Data: r.NewEntity()}
+	if token, ok := doc.Id.Lookup("_data").StringValueOK(); ok {
+		event.ResumeToken = token
+	}
+	if id, err := doc.DocumentKey.LookupErr(IdField); err == nil {
+		if s, ok := id.StringValueOK(); ok {
+			event.Id = s
+		} else {
+			event.Id = id.String()
+		}
+	}
+	data := doc.FullDocument
+	switch doc.OperationType {
+	case "insert":
+		event.Type = ChangeInsert
+	case "update", "replace":
+		event.Type = ChangeUpdate
+		if deleted, ok := data.Lookup(DeletedField).BooleanOK(); ok && deleted && r.IsSoftDelete() {
+			event.Type = ChangeDelete
+		}
+	case "delete":
+		event.Type = ChangeDelete
+		data = doc.FullDocumentBeforeChange
+	default:
+		return nil, errors.New(fmt.Sprintf("change operation %s is not supported", doc.OperationType))
+	}
+	if len(data) > 0 {
+		if err := bson.Unmarshal(data, event.Data); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
@@ -0,0 +1,113 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+)
+
+func Test_prefixFilter(t *testing.T) {
+	filter := map[string]interface{}{
+		"$and": []interface{}{
+			map[string]interface{}{"user_name": bson.D{{Key: "$ne", Value: "lxd"}}},
+			map[string]interface{}{"$or": []interface{}{
+				map[string]interface{}{"email": "a@b.c"},
+			}},
+			map[string]interface{}{TenantIdField: "001"},
+		},
+	}
+	assert.Equal(t, map[string]interface{}{
+		"$and": []interface{}{
+			map[string]interface{}{"fullDocument.user_name": bson.D{{Key: "$ne", Value: "lxd"}}},
+			map[string]interface{}{"$or": []interface{}{
+				map[string]interface{}{"fullDocument.email": "a@b.c"},
+			}},
+			map[string]interface{}{"fullDocument." + TenantIdField: "001"},
+		},
+	}, prefixFilter(filter, "fullDocument"))
+}
+
+func TestRepository_newChangeEvent(t *testing.T) {
+	repos := NewRepository[*User](func() *User { return &User{} }, nil, nil, NewRepositoryOptions().SetSoftDelete(true))
+	newRaw := func(doc bson.D) bson.Raw {
+		raw, err := bson.Marshal(doc)
+		assert.NoError(t, err)
+		return raw
+	}
+	user := bson.D{{Key: "_id", Value: "1"}, {Key: "tenantId", Value: "001"}, {Key: "userName", Value: "lxd"}}
+
+	event, err := repos.newChangeEvent(newRaw(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "token1"}}},
+		{Key: "operationType", Value: "insert"},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: "1"}}},
+		{Key: "fullDocument", Value: user},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeInsert, event.Type)
+	assert.Equal(t, "1", event.Id)
+	assert.Equal(t, "lxd", event.Data.UserName)
+	assert.Equal(t, "token1", event.GetSseId())
+	assert.Equal(t, "insert", event.GetSseEvent())
+
+	event, err = repos.newChangeEvent(newRaw(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "token2"}}},
+		{Key: "operationType", Value: "update"},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: "1"}}},
+		{Key: "fullDocument", Value: append(user, bson.E{Key: DeletedField, Value: true})},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeDelete, event.Type)
+
+	event, err = repos.newChangeEvent(newRaw(bson.D{
+		{Key: "_id", Value: bson.D{{Key: "_data", Value: "token3"}}},
+		{Key: "operationType", Value: "delete"},
+		{Key: "documentKey", Value: bson.D{{Key: "_id", Value: "1"}}},
+		{Key: "fullDocumentBeforeChange", Value: user},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, ChangeDelete, event.Type)
+	assert.Equal(t, "lxd", event.Data.UserName)
+
+	_, err = repos.newChangeEvent(newRaw(bson.D{{Key: "operationType", Value: "drop"}}))
+	assert.Error(t, err)
+}
+
+func TestRepository_Watch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("events", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		change := bson.D{
+			{Key: "_id", Value: bson.D{{Key: "_data", Value: "token1"}}},
+			{Key: "operationType", Value: "insert"},
+			{Key: "documentKey", Value: bson.D{{Key: "_id", Value: "1"}}},
+			{Key: "fullDocument", Value: bson.D{{Key: "_id", Value: "1"}, {Key: "tenantId", Value: "001"}}},
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(1, mockNamespace(mt), mtest.FirstBatch, change))
+		ctx := context.Background()
+		q, err := repos.Watch(ctx, "001", "userName=='lxd'", NewWatchOptions().SetResumeToken("token0"))
+		assert.NoError(t, err)
+
+		cmd := mt.GetStartedEvent().Command
+		stage := cmd.Lookup("pipeline", "0", "$changeStream")
+		assert.Equal(t, "updateLookup", stage.Document().Lookup("fullDocument").StringValue())
+		assert.Equal(t, "whenAvailable", stage.Document().Lookup("fullDocumentBeforeChange").StringValue())
+		assert.Equal(t, "token0", stage.Document().Lookup("resumeAfter", "_data").StringValue())
+		match := cmd.Lookup("pipeline", "1", "$match", "$or", "0", "$and", "1").Document().String()
+		assert.Contains(t, match, `"fullDocument.tenant_id": "001"`)
+		assert.Contains(t, match, `"fullDocument.userName": "lxd"`)
+
+		event := <-q.Events()
+		assert.Equal(t, ChangeInsert, event.Type)
+		assert.Equal(t, "1", event.Data.Id)
+
+		// 模拟服务没有更多响应，错误通过 Err 返回并关闭通道
+		for range q.Events() {
+		}
+		assert.Error(t, q.Err())
+		assert.NoError(t, q.Close())
+	})
+}
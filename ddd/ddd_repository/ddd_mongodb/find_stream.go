@@ -0,0 +1,105 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//
+// findStream
+// @Description: 基于Mongo游标的迭代器，按批从数据库读取数据
+//
+type findStream[T ddd.Entity] struct {
+	cursor  *mongo.Cursor
+	newFun  func() T
+	current T
+	err     error
+}
+
+func (s *findStream[T]) Next(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	if !s.cursor.Next(ctx) {
+		s.err = s.cursor.Err()
+		return false
+	}
+	entity := s.newFun()
+	if err := s.cursor.Decode(entity); err != nil {
+		s.err = err
+		return false
+	}
+	s.current = entity
+	return true
+}
+
+func (s *findStream[T]) Current() T {
+	return s.current
+}
+
+func (s *findStream[T]) Err() error {
+	return s.err
+}
+
+func (s *findStream[T]) Close(ctx context.Context) error {
+	return s.cursor.Close(ctx)
+}
+
+//
+// FindStream
+// @Description: 流式查询，返回逐条读取的迭代器，使用完后必须调用 Close
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param opts 查询选项，可设置 BatchSize、Fields、Limit 等
+// @return ddd_repository.FindStream[T]
+// @return error
+//
+func (r *Repository[T]) FindStream(ctx context.Context, tenantId string, filter string, opts ...*ddd_repository.FindOptions) (ddd_repository.FindStream[T], error) {
+	opt := ddd_repository.MergeFindOptions(opts...)
+	match, err := r.getMatchFilter(tenantId, filter, opt.GetIncludeDeleted())
+	if err != nil {
+		return nil, err
+	}
+	findOptions := getFindOptions(opts...)
+	projection, err := r.getProjection(opt.GetFields())
+	if err != nil {
+		return nil, err
+	}
+	if projection != nil {
+		findOptions.SetProjection(projection)
+	}
+	collection, err := r.getReadCollection(tenantId, opts...)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := collection.Find(ctx, match, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	return &findStream[T]{cursor: cursor, newFun: r.newFun}, nil
+}
+
+//
+// ForEach
+// @Description: 流式遍历查询结果
+// @param ctx 上下文，取消时停止遍历并返回ctx的错误
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param fun 回调，返回 ddd_repository.ErrStopIteration 时提前停止
+// @param opts 查询选项，可设置 BatchSize
+// @return error
+//
+func (r *Repository[T]) ForEach(ctx context.Context, tenantId string, filter string, fun func(entity T) error, opts ...*ddd_repository.FindOptions) error {
+	stream, err := r.FindStream(ctx, tenantId, filter, opts...)
+	if err != nil {
+		return err
+	}
+	return ddd_repository.ForEachStream[T](ctx, stream, fun)
+}
@@ -0,0 +1,78 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+)
+
+func TestRepository_FindStream(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+	user := func(id string) bson.D {
+		return bson.D{{Key: "_id", Value: id}, {Key: "tenantId", Value: "001"}}
+	}
+
+	mt.Run("batches", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		ns := mockNamespace(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch, user("1"), user("2")),
+			mtest.CreateCursorResponse(0, ns, mtest.NextBatch, user("3")),
+		)
+		ids := make([]string, 0)
+		err := repos.ForEach(ctx, "001", "userName=='lxd'", func(u *User) error {
+			ids = append(ids, u.Id)
+			return nil
+		}, ddd_repository.NewFindOptions().SetBatchSize(2))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"1", "2", "3"}, ids)
+
+		find := mt.GetStartedEvent().Command
+		assert.Equal(t, int32(2), find.Lookup("batchSize").Int32())
+		assert.Equal(t, "001", find.Lookup("filter", "$and", "1", TenantIdField).StringValue())
+		getMore := mt.GetStartedEvent().Command
+		assert.Equal(t, "getMore", getMore.Index(0).Key())
+	})
+
+	mt.Run("stop early", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, mockNamespace(mt), mtest.FirstBatch, user("1"), user("2")),
+			mtest.CreateSuccessResponse(),
+		)
+		count := 0
+		err := repos.ForEach(ctx, "001", "", func(u *User) error {
+			count++
+			return ddd_repository.ErrStopIteration
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, count)
+
+		mt.GetStartedEvent()
+		killCursors := mt.GetStartedEvent().Command
+		assert.Equal(t, "killCursors", killCursors.Index(0).Key())
+	})
+
+	mt.Run("cancel", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(1, mockNamespace(mt), mtest.FirstBatch, user("1")),
+			mtest.CreateSuccessResponse(),
+		)
+		cancelCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := repos.FindStream(cancelCtx, "001", "")
+		assert.NoError(t, err)
+		assert.True(t, stream.Next(cancelCtx))
+		assert.Equal(t, "1", stream.Current().Id)
+		cancel()
+		assert.False(t, stream.Next(cancelCtx))
+		assert.ErrorIs(t, stream.Err(), context.Canceled)
+		assert.NoError(t, stream.Close(ctx))
+	})
+}
@@ -0,0 +1,512 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexTag 实体字段上声明索引的标签，格式为 index:"[索引名称][,unique][,desc][,text][,sparse][,ttl=24h]"，
+// 名称相同的字段按字段顺序组成复合索引，名称为空时为单字段索引
+const IndexTag = "index"
+
+//
+// Index
+// @Description: 索引声明。除TTL索引外，索引的第一个字段总是 tenant_id
+//
+type Index struct {
+	Name        string         // 索引名称，为空时按字段生成
+	Fields      []string       // 驼峰格式的字段路径，以-开头表示降序
+	Text        bool           // 是否全文索引
+	Unique      bool           // 是否唯一索引，唯一性在租户内生效
+	Sparse      bool           // 是否稀疏索引
+	ExpireAfter *time.Duration // TTL索引的过期时间，Mongo要求TTL索引只有一个字段，因此不加 tenant_id
+}
+
+func NewIndex(fields ...string) *Index {
+	return &Index{Fields: fields}
+}
+
+func NewUniqueIndex(fields ...string) *Index {
+	return &Index{Fields: fields, Unique: true}
+}
+
+func NewTextIndex(fields ...string) *Index {
+	return &Index{Fields: fields, Text: true}
+}
+
+func NewTTLIndex(field string, expireAfter time.Duration) *Index {
+	return &Index{Fields: []string{field}, ExpireAfter: &expireAfter}
+}
+
+func (i *Index) SetName(name string) *Index {
+	i.Name = name
+	return i
+}
+
+func (i *Index) SetUnique(unique bool) *Index {
+	i.Unique = unique
+	return i
+}
+
+func (i *Index) SetSparse(sparse bool) *Index {
+	i.Sparse = sparse
+	return i
+}
+
+//
+// IndexDrift
+// @Description: 声明的索引与数据库中已有索引的差异
+//
+type IndexDrift struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+//
+// IndexReport
+// @Description: 确保索引的结果
+//
+type IndexReport struct {
+	Collection string        `json:"collection"`
+	Created    []string      `json:"created"`    // 新建的索引
+	Unchanged  []string      `json:"unchanged"`  // 已存在且与声明一致的索引
+	Drifts     []*IndexDrift `json:"drifts"`     // 已存在但与声明不一致的索引，不会自动修改
+	Undeclared []string      `json:"undeclared"` // 数据库中存在但没有声明的索引
+}
+
+func newIndexReport(collection string) *IndexReport {
+	return &IndexReport{
+		Collection: collection,
+		Created:    make([]string, 0),
+		Unchanged:  make([]string, 0),
+		Drifts:     make([]*IndexDrift, 0),
+		Undeclared: make([]string, 0),
+	}
+}
+
+//
+// HasDrift
+// @Description: 是否存在与声明不一致或没有声明的索引
+// @return bool
+//
+func (r *IndexReport) HasDrift() bool {
+	return len(r.Drifts) > 0 || len(r.Undeclared) > 0
+}
+
+func (r *IndexReport) String() string {
+	items := make([]string, 0)
+	for _, d := range r.Drifts {
+		items = append(items, fmt.Sprintf("index %s drift: %s", d.Name, d.Reason))
+	}
+	for _, name := range r.Undeclared {
+		items = append(items, fmt.Sprintf("index %s is not declared", name))
+	}
+	return fmt.Sprintf("collection %s: created %v; %s", r.Collection, r.Created, strings.Join(items, "; "))
+}
+
+// 转换为Mongo格式的索引
+type indexModel struct {
+	name               string
+	keys               bson.D
+	unique             bool
+	sparse             bool
+	expireAfterSeconds *int32
+}
+
+// 数据库中已有的索引
+type indexSpec struct {
+	Name               string                 `bson:"name"`
+	Key                bson.D                 `bson:"key"`
+	Unique             *bool                  `bson:"unique"`
+	Sparse             *bool                  `bson:"sparse"`
+	ExpireAfterSeconds *int32                 `bson:"expireAfterSeconds"`
+	Weights            map[string]interface{} `bson:"weights"`
+}
+
+//
+// GetIndexes
+// @Description: 获取实体标签与仓储设置中声明的索引
+// @return []*Index
+// @return error 标签格式错误
+//
+func (r *Repository[T]) GetIndexes() ([]*Index, error) {
+	indexes, err := getTagIndexes(reflect.TypeOf(r.NewEntity()))
+	if err != nil {
+		return nil, err
+	}
+	return append(indexes, r.options.GetIndexes()...), nil
+}
+
+//
+// GetCollectionName
+// @Description: 获取仓储的集合名称，MongoDB 按此名称注册需要确保索引的仓储
+// @return string
+//
+func (r *Repository[T]) GetCollectionName() string {
+	return r.collection.Name()
+}
+
+//
+// EnsureIndexes
+// @Description: 在仓储的集合上创建声明但不存在的索引，可重复执行。已存在但与声明不一致的索引不会修改，只在结果中报告；没有声明索引时不检查。
+// 按租户隔离集合或数据库时，租户的集合使用 EnsureTenantIndexes 创建索引
+// @param ctx 上下文
+// @return *IndexReport
+// @return error
+//
+func (r *Repository[T]) EnsureIndexes(ctx context.Context) (*IndexReport, error) {
+	return r.ensureIndexes(ctx, r.collection)
+}
+
+//
+// EnsureTenantIndexes
+// @Description: 在租户的集合上创建声明但不存在的索引，用于按租户隔离集合或数据库时开通新租户
+// @param ctx 上下文
+// @param tenantId 租户id
+// @return *IndexReport
+// @return error
+//
+func (r *Repository[T]) EnsureTenantIndexes(ctx context.Context, tenantId string) (*IndexReport, error) {
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	return r.ensureIndexes(ctx, collection)
+}
+
+func (r *Repository[T]) ensureIndexes(ctx context.Context, collection *mongo.Collection) (*IndexReport, error) {
+	indexes, err := r.GetIndexes()
+	if err != nil {
+		return nil, err
+	}
+	if len(indexes) == 0 {
+		// 没有声明索引的仓储不管理索引，不报告已有的索引
+		return newIndexReport(collection.Name()), nil
+	}
+	models, err := newIndexModels(reflect.TypeOf(r.NewEntity()), indexes)
+	if err != nil {
+		return nil, err
+	}
+	specs, err := listIndexSpecs(ctx, collection)
+	if err != nil {
+		return nil, err
+	}
+	report, creates := diffIndexes(collection.Name(), models, specs)
+	if len(creates) == 0 {
+		return report, nil
+	}
+	indexModels := make([]mongo.IndexModel, len(creates))
+	for i, m := range creates {
+		opts := options.Index().SetName(m.name)
+		if m.unique {
+			opts.SetUnique(true)
+		}
+		if m.sparse {
+			opts.SetSparse(true)
+		}
+		if m.expireAfterSeconds != nil {
+			opts.SetExpireAfterSeconds(*m.expireAfterSeconds)
+		}
+		indexModels[i] = mongo.IndexModel{Keys: m.keys, Options: opts}
+	}
+	if _, err := collection.Indexes().CreateMany(ctx, indexModels); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func listIndexSpecs(ctx context.Context, collection *mongo.Collection) ([]*indexSpec, error) {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	specs := make([]*indexSpec, 0)
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+//
+//  getTagIndexes
+//  @Description: 按实体字段的 index 标签生成索引声明，匿名结构的字段视为当前结构的字段
+//  @param t 实体类型
+//  @return []*Index
+//  @return error
+//
+func getTagIndexes(t reflect.Type) ([]*Index, error) {
+	indexes := make([]*Index, 0)
+	named := make(map[string]*Index)
+	var addFields func(t reflect.Type) error
+	addFields = func(t reflect.Type) error {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil
+		}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag, ok := field.Tag.Lookup(IndexTag)
+			if field.Anonymous && !ok {
+				if err := addFields(field.Type); err != nil {
+					return err
+				}
+				continue
+			}
+			if !ok || !field.IsExported() {
+				continue
+			}
+			items := strings.Split(tag, ",")
+			name := strings.TrimSpace(items[0])
+			index, ok := named[name]
+			if !ok || name == "" {
+				index = &Index{Name: name}
+				indexes = append(indexes, index)
+				if name != "" {
+					named[name] = index
+				}
+			}
+			path := field.Name
+			for _, item := range items[1:] {
+				item = strings.TrimSpace(item)
+				switch {
+				case item == "unique":
+					index.Unique = true
+				case item == "sparse":
+					index.Sparse = true
+				case item == "text":
+					index.Text = true
+				case item == "desc":
+					path = "-" + field.Name
+				case strings.HasPrefix(item, "ttl="):
+					expireAfter, err := time.ParseDuration(item[4:])
+					if err != nil {
+						return errors.New(fmt.Sprintf("field %s index tag ttl is error: %s", field.Name, err.Error()))
+					}
+					index.ExpireAfter = &expireAfter
+				case item == "":
+				default:
+					return errors.New(fmt.Sprintf("field %s index tag option %s is not supported", field.Name, item))
+				}
+			}
+			index.Fields = append(index.Fields, path)
+		}
+		return nil
+	}
+	if err := addFields(t); err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
+
+//
+//  newIndexModels
+//  @Description: 校验索引字段并转换为Mongo索引，索引名称不能重复
+//
+func newIndexModels(t reflect.Type, indexes []*Index) ([]*indexModel, error) {
+	models := make([]*indexModel, 0, len(indexes))
+	names := make(map[string]bool)
+	for _, index := range indexes {
+		m, err := newIndexModel(t, index)
+		if err != nil {
+			return nil, err
+		}
+		if names[m.name] {
+			return nil, errors.New(fmt.Sprintf("index %s is duplicate", m.name))
+		}
+		names[m.name] = true
+		models = append(models, m)
+	}
+	return models, nil
+}
+
+//
+//  newIndexModel
+//  @Description: 转换为Mongo索引，除TTL索引外在字段前加 tenant_id
+//  @param t 实体类型，用于校验字段
+//  @param index 索引声明
+//  @return *indexModel
+//  @return error
+//
+func newIndexModel(t reflect.Type, index *Index) (*indexModel, error) {
+	if len(index.Fields) == 0 {
+		return nil, errors.New(fmt.Sprintf("index %s fields is empty", index.Name))
+	}
+	m := &indexModel{unique: index.Unique, sparse: index.Sparse}
+	if index.ExpireAfter != nil {
+		if len(index.Fields) != 1 || index.Text {
+			return nil, errors.New(fmt.Sprintf("ttl index %s must have only one field", index.Name))
+		}
+		seconds := int32(index.ExpireAfter.Seconds())
+		m.expireAfterSeconds = &seconds
+	} else {
+		m.keys = bson.D{{Key: TenantIdField, Value: 1}}
+	}
+	for _, field := range index.Fields {
+		var value interface{} = 1
+		path := strings.TrimSpace(field)
+		if strings.HasPrefix(path, "-") {
+			value = -1
+			path = strings.TrimSpace(path[1:])
+		}
+		if index.Text {
+			value = "text"
+		}
+		fieldName, err := getFieldPath(t, path)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("index field %s error: %s", field, err.Error()))
+		}
+		if containsKey(m.keys, fieldName) {
+			continue
+		}
+		m.keys = append(m.keys, bson.E{Key: fieldName, Value: value})
+	}
+	m.name = index.Name
+	if m.name == "" {
+		items := make([]string, len(m.keys))
+		for i, e := range m.keys {
+			items[i] = fmt.Sprintf("%s_%v", e.Key, e.Value)
+		}
+		m.name = strings.Join(items, "_")
+	}
+	return m, nil
+}
+
+//
+//  diffIndexes
+//  @Description: 比较声明的索引与已有索引
+//  @param collection 集合名称
+//  @param models 声明的索引
+//  @param specs 已有的索引
+//  @return *IndexReport
+//  @return []*indexModel 需要新建的索引
+//
+func diffIndexes(collection string, models []*indexModel, specs []*indexSpec) (*IndexReport, []*indexModel) {
+	report := newIndexReport(collection)
+	creates := make([]*indexModel, 0)
+	matched := make(map[string]bool)
+	for _, m := range models {
+		var spec *indexSpec
+		for _, s := range specs {
+			if s.Name == m.name {
+				spec = s
+				break
+			}
+		}
+		if spec == nil {
+			// 字段相同但名称不同的索引无法再创建
+			for _, s := range specs {
+				if !matched[s.Name] && getSpecKeySignature(s) == getModelKeySignature(m) {
+					spec = s
+					break
+				}
+			}
+			if spec != nil {
+				matched[spec.Name] = true
+				report.Drifts = append(report.Drifts, &IndexDrift{Name: m.name, Reason: fmt.Sprintf("exists as index %s", spec.Name)})
+				continue
+			}
+			creates = append(creates, m)
+			report.Created = append(report.Created, m.name)
+			continue
+		}
+		matched[spec.Name] = true
+		if reasons := compareIndex(m, spec); len(reasons) > 0 {
+			report.Drifts = append(report.Drifts, &IndexDrift{Name: m.name, Reason: strings.Join(reasons, ", ")})
+			continue
+		}
+		report.Unchanged = append(report.Unchanged, m.name)
+	}
+	for _, s := range specs {
+		if !matched[s.Name] && s.Name != "_id_" {
+			report.Undeclared = append(report.Undeclared, s.Name)
+		}
+	}
+	return report, creates
+}
+
+func compareIndex(m *indexModel, spec *indexSpec) []string {
+	reasons := make([]string, 0)
+	if declared, existing := getModelKeySignature(m), getSpecKeySignature(spec); declared != existing {
+		reasons = append(reasons, fmt.Sprintf("keys %s != %s", existing, declared))
+	}
+	if existing := spec.Unique != nil && *spec.Unique; existing != m.unique {
+		reasons = append(reasons, fmt.Sprintf("unique %v != %v", existing, m.unique))
+	}
+	if existing := spec.Sparse != nil && *spec.Sparse; existing != m.sparse {
+		reasons = append(reasons, fmt.Sprintf("sparse %v != %v", existing, m.sparse))
+	}
+	existingTTL, declaredTTL := "none", "none"
+	if spec.ExpireAfterSeconds != nil {
+		existingTTL = fmt.Sprintf("%ds", *spec.ExpireAfterSeconds)
+	}
+	if m.expireAfterSeconds != nil {
+		declaredTTL = fmt.Sprintf("%ds", *m.expireAfterSeconds)
+	}
+	if existingTTL != declaredTTL {
+		reasons = append(reasons, fmt.Sprintf("ttl %s != %s", existingTTL, declaredTTL))
+	}
+	return reasons
+}
+
+//
+//  getModelKeySignature
+//  @Description: 索引字段的签名，全文索引的字段按名称排序后合并为一项，与数据库中 _fts 的保存方式一致
+//
+func getModelKeySignature(m *indexModel) string {
+	items := make([]string, 0)
+	texts := make([]string, 0)
+	for _, e := range m.keys {
+		if e.Value == "text" {
+			texts = append(texts, e.Key)
+			continue
+		}
+		items = append(items, fmt.Sprintf("%s:%v", e.Key, e.Value))
+	}
+	if len(texts) > 0 {
+		sort.Strings(texts)
+		items = append(items, "$text:"+strings.Join(texts, "|"))
+	}
+	return strings.Join(items, ",")
+}
+
+func getSpecKeySignature(spec *indexSpec) string {
+	items := make([]string, 0)
+	for _, e := range spec.Key {
+		switch e.Key {
+		case "_fts":
+			texts := make([]string, 0, len(spec.Weights))
+			for name := range spec.Weights {
+				texts = append(texts, name)
+			}
+			sort.Strings(texts)
+			items = append(items, "$text:"+strings.Join(texts, "|"))
+		case "_ftsx":
+		default:
+			items = append(items, fmt.Sprintf("%s:%v", e.Key, getIndexKeyValue(e.Value)))
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// 数据库返回的索引方向可能是 int32、int64 或 float64
+func getIndexKeyValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return v
+}
@@ -0,0 +1,113 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type indexBase struct {
+	CreatedTime time.Time `json:"createdTime" index:",ttl=24h"`
+}
+
+type indexOrder struct {
+	indexBase
+	Id         string `json:"id"`
+	TenantId   string `json:"tenantId"`
+	OrderNo    string `json:"orderNo" index:",unique"`
+	CustomerId string `json:"customerId" index:"idx_customer"`
+	OrderTime  string `json:"orderTime" index:"idx_customer,desc"`
+	Remarks    string `json:"remarks" index:",text"`
+}
+
+func Test_newIndexModels(t *testing.T) {
+	orderType := reflect.TypeOf(&indexOrder{})
+	indexes, err := getTagIndexes(orderType)
+	assert.NoError(t, err)
+	assert.Len(t, indexes, 4)
+	indexes = append(indexes, NewIndex("customerId", "orderNo").SetSparse(true))
+
+	models, err := newIndexModels(orderType, indexes)
+	assert.NoError(t, err)
+	assert.Len(t, models, 5)
+
+	ttl := int32(86400)
+	assert.Equal(t, &indexModel{name: "created_time_1", keys: bson.D{{Key: "created_time", Value: 1}}, expireAfterSeconds: &ttl}, models[0])
+	assert.Equal(t, &indexModel{name: "tenant_id_1_order_no_1", keys: bson.D{{Key: TenantIdField, Value: 1}, {Key: "order_no", Value: 1}}, unique: true}, models[1])
+	assert.Equal(t, bson.D{{Key: TenantIdField, Value: 1}, {Key: "customer_id", Value: 1}, {Key: "order_time", Value: -1}}, models[2].keys)
+	assert.Equal(t, "idx_customer", models[2].name)
+	assert.Equal(t, bson.D{{Key: TenantIdField, Value: 1}, {Key: "remarks", Value: "text"}}, models[3].keys)
+	assert.True(t, models[4].sparse)
+
+	_, err = newIndexModels(orderType, []*Index{NewIndex("unknown")})
+	assert.Error(t, err)
+	_, err = newIndexModels(orderType, []*Index{NewIndex("orderNo"), NewUniqueIndex("orderNo")})
+	assert.Error(t, err)
+	_, err = newIndexModels(orderType, []*Index{NewTTLIndex("createdTime", time.Hour), {Fields: []string{"orderNo", "remarks"}, ExpireAfter: &[]time.Duration{time.Hour}[0]}})
+	assert.Error(t, err)
+
+	type badTag struct {
+		Name string `index:",ttl=abc"`
+	}
+	_, err = getTagIndexes(reflect.TypeOf(badTag{}))
+	assert.Error(t, err)
+}
+
+func Test_diffIndexes(t *testing.T) {
+	orderType := reflect.TypeOf(&indexOrder{})
+	indexes, err := getTagIndexes(orderType)
+	assert.NoError(t, err)
+	models, err := newIndexModels(orderType, indexes)
+	assert.NoError(t, err)
+
+	unique := true
+	ttl := int32(3600)
+	specs := []*indexSpec{
+		{Name: "_id_", Key: bson.D{{Key: IdField, Value: int32(1)}}},
+		{Name: "created_time_1", Key: bson.D{{Key: "created_time", Value: int32(1)}}, ExpireAfterSeconds: &ttl},
+		{Name: "tenant_id_1_order_no_1", Key: bson.D{{Key: TenantIdField, Value: int32(1)}, {Key: "order_no", Value: int32(1)}}, Unique: &unique},
+		{Name: "customer", Key: bson.D{{Key: TenantIdField, Value: 1.0}, {Key: "customer_id", Value: 1.0}, {Key: "order_time", Value: -1.0}}},
+		{Name: "tenant_id_1_remarks_text", Key: bson.D{{Key: TenantIdField, Value: int32(1)}, {Key: "_fts", Value: "text"}, {Key: "_ftsx", Value: int32(1)}}, Weights: map[string]interface{}{"remarks": int32(1)}},
+		{Name: "old_index", Key: bson.D{{Key: "name", Value: int32(1)}}},
+	}
+	report, creates := diffIndexes("orders", models, specs)
+	assert.Empty(t, creates)
+	assert.Empty(t, report.Created)
+	assert.Equal(t, []string{"tenant_id_1_order_no_1", "tenant_id_1_remarks_text"}, report.Unchanged)
+	assert.Equal(t, []*IndexDrift{
+		{Name: "created_time_1", Reason: "ttl 3600s != 86400s"},
+		{Name: "idx_customer", Reason: "exists as index customer"},
+	}, report.Drifts)
+	assert.Equal(t, []string{"old_index"}, report.Undeclared)
+	assert.True(t, report.HasDrift())
+
+	report, creates = diffIndexes("orders", models, specs[:1])
+	assert.Len(t, creates, 4)
+	assert.Len(t, report.Created, 4)
+	assert.False(t, report.HasDrift())
+}
+
+func TestMongoDB_RegisterIndexManager(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("register", func(mt *mtest.T) {
+		mongodb := &MongoDB{}
+		for i := 0; i < 3; i++ {
+			NewRepository[*User](func() *User { return &User{} }, mongodb, mt.Coll)
+		}
+		assert.Len(t, mongodb.indexManagers, 1)
+		assert.Equal(t, []string{mt.Coll.Name()}, mongodb.indexCollections)
+
+		// User 没有声明索引，不查询已有索引也不报告
+		reports, err := mongodb.EnsureIndexes(context.Background())
+		assert.NoError(t, err)
+		assert.Len(t, reports, 1)
+		assert.False(t, reports[0].HasDrift())
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
@@ -0,0 +1,486 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMigrationCollection = "_migrations"
+	defaultMigrationLockTTL    = 10 * time.Minute
+	migrationLockWaitForever   = time.Duration(-1)
+	migrationLockId            = "_lock"
+	migrationLockRetry         = time.Second
+)
+
+//
+// MigrationFunc
+// @Description: 迁移方法
+// @param ctx 上下文
+// @param db 迁移的数据库
+// @return error
+//
+type MigrationFunc func(ctx context.Context, db *mongo.Database) error
+
+//
+// Migration
+// @Description: 数据迁移，按版本号从小到大执行
+//
+type Migration struct {
+	Version     int64         // 版本号，大于0且不能重复
+	Description string        // 说明
+	Up          MigrationFunc // 升级
+	Down        MigrationFunc // 回退，为nil时不能回退
+}
+
+//
+// MigrationRecord
+// @Description: 已执行的迁移记录，保存在迁移集合中
+//
+type MigrationRecord struct {
+	Version     int64     `bson:"_id" json:"version"`
+	Description string    `bson:"description" json:"description"`
+	AppliedTime time.Time `bson:"applied_time" json:"appliedTime"`
+}
+
+//
+// MigrateResult
+// @Description: 迁移结果
+//
+type MigrateResult struct {
+	DryRun     bool    `json:"dryRun"`     // 是否只列出将要执行的迁移
+	Applied    []int64 `json:"applied"`    // 升级的版本号，试运行时为将要升级的版本号
+	RolledBack []int64 `json:"rolledBack"` // 回退的版本号，试运行时为将要回退的版本号
+}
+
+func (r *MigrateResult) String() string {
+	prefix := ""
+	if r.DryRun {
+		prefix = "dry run: "
+	}
+	return fmt.Sprintf("%sapplied %v; rolled back %v", prefix, r.Applied, r.RolledBack)
+}
+
+//
+// MigrateOptions
+// @Description: 迁移设置
+//
+type MigrateOptions struct {
+	Collection *string        // 迁移记录与锁的集合名称，默认为 _migrations
+	DryRun     *bool          // 只列出将要执行的迁移，不执行也不加锁
+	LockTTL    *time.Duration // 锁的有效时长，执行迁移期间定时续期，持有锁的实例异常退出后超时释放，默认为10分钟
+	LockWait   *time.Duration // 等待其它实例释放锁的最长时间，默认一直等待到锁释放或过期
+}
+
+func NewMigrateOptions() *MigrateOptions {
+	return &MigrateOptions{}
+}
+
+func (o *MigrateOptions) SetCollection(collection string) *MigrateOptions {
+	o.Collection = &collection
+	return o
+}
+
+func (o *MigrateOptions) GetCollection() string {
+	if o.Collection == nil || len(*o.Collection) == 0 {
+		return defaultMigrationCollection
+	}
+	return *o.Collection
+}
+
+func (o *MigrateOptions) SetDryRun(dryRun bool) *MigrateOptions {
+	o.DryRun = &dryRun
+	return o
+}
+
+func (o *MigrateOptions) GetDryRun() bool {
+	if o.DryRun == nil {
+		return false
+	}
+	return *o.DryRun
+}
+
+func (o *MigrateOptions) SetLockTTL(lockTTL time.Duration) *MigrateOptions {
+	o.LockTTL = &lockTTL
+	return o
+}
+
+func (o *MigrateOptions) GetLockTTL() time.Duration {
+	if o.LockTTL == nil {
+		return defaultMigrationLockTTL
+	}
+	return *o.LockTTL
+}
+
+func (o *MigrateOptions) SetLockWait(lockWait time.Duration) *MigrateOptions {
+	o.LockWait = &lockWait
+	return o
+}
+
+func (o *MigrateOptions) GetLockWait() time.Duration {
+	if o.LockWait == nil {
+		return migrationLockWaitForever
+	}
+	return *o.LockWait
+}
+
+func MergeMigrateOptions(opts ...*MigrateOptions) *MigrateOptions {
+	res := &MigrateOptions{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if o.Collection != nil {
+			res.Collection = o.Collection
+		}
+		if o.DryRun != nil {
+			res.DryRun = o.DryRun
+		}
+		if o.LockTTL != nil {
+			res.LockTTL = o.LockTTL
+		}
+		if o.LockWait != nil {
+			res.LockWait = o.LockWait
+		}
+	}
+	return res
+}
+
+var (
+	migrationsLock sync.Mutex
+	migrations     = make(map[int64]*Migration)
+)
+
+//
+// RegisterMigration
+// @Description: 注册迁移，一般在 init 中调用
+// @param migration 迁移
+// @return error 版本号无效、重复或没有升级方法
+//
+func RegisterMigration(migration *Migration) error {
+	if migration == nil || migration.Version <= 0 {
+		return errors.New("migration version must be greater than 0")
+	}
+	if migration.Up == nil {
+		return errors.New(fmt.Sprintf("migration %d up is nil", migration.Version))
+	}
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	if _, ok := migrations[migration.Version]; ok {
+		return errors.New(fmt.Sprintf("migration %d is already registered", migration.Version))
+	}
+	migrations[migration.Version] = migration
+	return nil
+}
+
+//
+// GetMigrations
+// @Description: 获取已注册的迁移，按版本号排序
+// @return []*Migration
+//
+func GetMigrations() []*Migration {
+	migrationsLock.Lock()
+	defer migrationsLock.Unlock()
+	list := make([]*Migration, 0, len(migrations))
+	for _, m := range migrations {
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list
+}
+
+//
+// Migrator
+// @Description: 迁移执行器，执行时持有分布式锁，多个实例同时启动时只有一个实例执行
+//
+type Migrator struct {
+	database   *mongo.Database
+	collection *mongo.Collection
+	migrations []*Migration
+	options    *MigrateOptions
+	owner      string
+}
+
+//
+// NewMigrator
+// @Description: 新建迁移执行器，执行已注册的迁移
+// @param database 迁移的数据库
+// @param opts 迁移设置
+// @return *Migrator
+//
+func NewMigrator(database *mongo.Database, opts ...*MigrateOptions) *Migrator {
+	opt := MergeMigrateOptions(opts...)
+	return &Migrator{
+		database:   database,
+		collection: database.Collection(opt.GetCollection()),
+		migrations: GetMigrations(),
+		options:    opt,
+		owner:      uuid.NewString(),
+	}
+}
+
+//
+// Up
+// @Description: 执行所有未执行的迁移
+// @param ctx 上下文
+// @return *MigrateResult
+// @return error
+//
+func (m *Migrator) Up(ctx context.Context) (*MigrateResult, error) {
+	return m.migrate(ctx, func(applied []*MigrationRecord) ([]*Migration, []*Migration, error) {
+		return planMigrations(m.migrations, applied, nil)
+	})
+}
+
+//
+// Down
+// @Description: 回退最后执行的一个迁移
+// @param ctx 上下文
+// @return *MigrateResult
+// @return error 迁移没有回退方法
+//
+func (m *Migrator) Down(ctx context.Context) (*MigrateResult, error) {
+	return m.migrate(ctx, func(applied []*MigrationRecord) ([]*Migration, []*Migration, error) {
+		if len(applied) == 0 {
+			return nil, nil, nil
+		}
+		var target int64
+		if len(applied) > 1 {
+			target = applied[len(applied)-2].Version
+		}
+		_, downs, err := planMigrations(m.migrations, applied, &target)
+		return nil, downs, err
+	})
+}
+
+//
+// MigrateTo
+// @Description: 迁移到指定版本，执行不大于此版本的未执行迁移，回退大于此版本的已执行迁移
+// @param ctx 上下文
+// @param version 目标版本号，为0时回退所有迁移
+// @return *MigrateResult
+// @return error
+//
+func (m *Migrator) MigrateTo(ctx context.Context, version int64) (*MigrateResult, error) {
+	return m.migrate(ctx, func(applied []*MigrationRecord) ([]*Migration, []*Migration, error) {
+		return planMigrations(m.migrations, applied, &version)
+	})
+}
+
+//
+// GetApplied
+// @Description: 获取已执行的迁移记录，按版本号排序
+// @param ctx 上下文
+// @return []*MigrationRecord
+// @return error
+//
+func (m *Migrator) GetApplied(ctx context.Context) ([]*MigrationRecord, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{IdField: bson.M{"$type": "long"}}, options.Find().SetSort(bson.D{{Key: IdField, Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*MigrationRecord, 0)
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, plan func(applied []*MigrationRecord) ([]*Migration, []*Migration, error)) (result *MigrateResult, err error) {
+	dryRun := m.options.GetDryRun()
+	if !dryRun {
+		if err := m.lock(ctx); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if e := m.unlock(context.Background()); e != nil && err == nil {
+				err = e
+			}
+		}()
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		done := make(chan error, 1)
+		go func() {
+			done <- m.heartbeat(ctx, cancel)
+		}()
+		defer func() {
+			cancel()
+			if e := <-done; e != nil {
+				err = e
+			}
+		}()
+	}
+	applied, err := m.GetApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ups, downs, err := plan(applied)
+	if err != nil {
+		return nil, err
+	}
+	result = &MigrateResult{DryRun: dryRun, Applied: []int64{}, RolledBack: []int64{}}
+	for _, migration := range downs {
+		if !dryRun {
+			if err := migration.Down(ctx, m.database); err != nil {
+				return result, errors.New(fmt.Sprintf("migration %d down error: %s", migration.Version, err.Error()))
+			}
+			if _, err := m.collection.DeleteOne(ctx, bson.M{IdField: migration.Version}); err != nil {
+				return result, err
+			}
+		}
+		result.RolledBack = append(result.RolledBack, migration.Version)
+	}
+	for _, migration := range ups {
+		if !dryRun {
+			if err := migration.Up(ctx, m.database); err != nil {
+				return result, errors.New(fmt.Sprintf("migration %d up error: %s", migration.Version, err.Error()))
+			}
+			record := &MigrationRecord{
+				Version:     migration.Version,
+				Description: migration.Description,
+				AppliedTime: ddd_repository.GetClock().Now(),
+			}
+			if _, err := m.collection.InsertOne(ctx, record); err != nil {
+				return result, err
+			}
+		}
+		result.Applied = append(result.Applied, migration.Version)
+	}
+	return result, nil
+}
+
+//
+//  lock
+//  @Description: 获取分布式锁，锁被其它实例持有时等待，超过 LockWait 返回错误。锁超过 LockTTL 未续期时视为已释放
+//
+func (m *Migrator) lock(ctx context.Context) error {
+	wait := m.options.GetLockWait()
+	deadline := ddd_repository.GetClock().Now().Add(wait)
+	for {
+		now := ddd_repository.GetClock().Now()
+		filter := bson.M{
+			IdField: migrationLockId,
+			"$or": bson.A{
+				bson.M{"owner": m.owner},
+				bson.M{"expire_time": bson.M{"$lt": now}},
+			},
+		}
+		update := bson.M{"$set": bson.M{"owner": m.owner, "expire_time": now.Add(m.options.GetLockTTL())}}
+		_, err := m.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if wait != migrationLockWaitForever && now.After(deadline) {
+			return errors.New(fmt.Sprintf("migration lock is held by another instance, collection %s", m.collection.Name()))
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(migrationLockRetry):
+		}
+	}
+}
+
+//
+//  heartbeat
+//  @Description: 执行迁移期间定时延长锁的过期时间，锁被其它实例取得时取消迁移并返回错误
+//  @param ctx 迁移的上下文，结束时停止续期
+//  @param cancel 取消迁移
+//  @return error
+//
+func (m *Migrator) heartbeat(ctx context.Context, cancel context.CancelFunc) error {
+	ttl := m.options.GetLockTTL()
+	if ttl/3 <= 0 {
+		return nil
+	}
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		filter := bson.M{IdField: migrationLockId, "owner": m.owner}
+		update := bson.M{"$set": bson.M{"expire_time": ddd_repository.GetClock().Now().Add(ttl)}}
+		res, err := m.collection.UpdateOne(ctx, filter, update)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err == nil && res.MatchedCount == 0 {
+			err = errors.New(fmt.Sprintf("migration lock is lost, collection %s", m.collection.Name()))
+		}
+		if err != nil {
+			cancel()
+			return err
+		}
+	}
+}
+
+func (m *Migrator) unlock(ctx context.Context) error {
+	_, err := m.collection.DeleteOne(ctx, bson.M{IdField: migrationLockId, "owner": m.owner})
+	return err
+}
+
+//
+//  planMigrations
+//  @Description: 按已执行的迁移与目标版本确定需要升级与回退的迁移
+//  @param list 已注册的迁移，按版本号排序
+//  @param applied 已执行的迁移记录
+//  @param target 目标版本号，为nil时升级到最新
+//  @return []*Migration 需要升级的迁移，按版本号从小到大
+//  @return []*Migration 需要回退的迁移，按版本号从大到小
+//  @return error 已执行的迁移没有注册或没有回退方法
+//
+func planMigrations(list []*Migration, applied []*MigrationRecord, target *int64) ([]*Migration, []*Migration, error) {
+	appliedMap := make(map[int64]bool, len(applied))
+	for _, record := range applied {
+		appliedMap[record.Version] = true
+	}
+	registered := make(map[int64]*Migration, len(list))
+	ups := make([]*Migration, 0)
+	for _, migration := range list {
+		registered[migration.Version] = migration
+		if !appliedMap[migration.Version] && (target == nil || migration.Version <= *target) {
+			ups = append(ups, migration)
+		}
+	}
+	if target == nil {
+		return ups, nil, nil
+	}
+	downs := make([]*Migration, 0)
+	var errs []string
+	for i := len(applied) - 1; i >= 0; i-- {
+		version := applied[i].Version
+		if version <= *target {
+			continue
+		}
+		migration, ok := registered[version]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("migration %d is not registered", version))
+			continue
+		}
+		if migration.Down == nil {
+			errs = append(errs, fmt.Sprintf("migration %d down is nil", version))
+			continue
+		}
+		downs = append(downs, migration)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.New(strings.Join(errs, "; "))
+	}
+	return ups, downs, nil
+}
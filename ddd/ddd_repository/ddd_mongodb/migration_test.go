@@ -0,0 +1,136 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+func Test_planMigrations(t *testing.T) {
+	noop := func(ctx context.Context, db *mongo.Database) error { return nil }
+	list := []*Migration{
+		{Version: 1, Up: noop, Down: noop},
+		{Version: 2, Up: noop},
+		{Version: 3, Up: noop, Down: noop},
+		{Version: 4, Up: noop, Down: noop},
+	}
+	applied := []*MigrationRecord{{Version: 1}, {Version: 3}}
+	versions := func(list []*Migration) []int64 {
+		res := make([]int64, len(list))
+		for i, m := range list {
+			res[i] = m.Version
+		}
+		return res
+	}
+
+	ups, downs, err := planMigrations(list, applied, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2, 4}, versions(ups))
+	assert.Empty(t, downs)
+
+	target := int64(2)
+	ups, downs, err = planMigrations(list, applied, &target)
+	assert.NoError(t, err)
+	assert.Equal(t, []int64{2}, versions(ups))
+	assert.Equal(t, []int64{3}, versions(downs))
+
+	target = 0
+	_, _, err = planMigrations(list, append(applied, &MigrationRecord{Version: 2}), &target)
+	assert.Error(t, err, "migration 2 can not be rolled back")
+	_, _, err = planMigrations(list, []*MigrationRecord{{Version: 9}}, &target)
+	assert.Error(t, err, "migration 9 is not registered")
+}
+
+func TestRegisterMigration(t *testing.T) {
+	noop := func(ctx context.Context, db *mongo.Database) error { return nil }
+	assert.Error(t, RegisterMigration(&Migration{Version: 0, Up: noop}))
+	assert.Error(t, RegisterMigration(&Migration{Version: 1001}))
+	assert.NoError(t, RegisterMigration(&Migration{Version: 1001, Up: noop}))
+	assert.Error(t, RegisterMigration(&Migration{Version: 1001, Up: noop}))
+}
+
+func TestMigrator_Up(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+
+	var ran []int64
+	newMigration := func(version int64) *Migration {
+		return &Migration{Version: version, Description: "test", Up: func(ctx context.Context, db *mongo.Database) error {
+			ran = append(ran, version)
+			return nil
+		}}
+	}
+	newMigrator := func(mt *mtest.T, opts ...*MigrateOptions) *Migrator {
+		migrator := NewMigrator(mt.DB, opts...)
+		migrator.migrations = []*Migration{newMigration(1), newMigration(2)}
+		return migrator
+	}
+	appliedResponse := func(mt *mtest.T) bson.D {
+		ns := mt.DB.Name() + "." + defaultMigrationCollection
+		return mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: int64(1)}})
+	}
+
+	mt.Run("up", func(mt *mtest.T) {
+		ran = nil
+		migrator := newMigrator(mt)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			appliedResponse(mt),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		result, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []int64{2}, result.Applied)
+		assert.Equal(t, []int64{2}, ran)
+
+		lock := mt.GetStartedEvent().Command
+		assert.Equal(t, "update", lock.Index(0).Key())
+		assert.Equal(t, migrationLockId, lock.Lookup("updates", "0", "q", "_id").StringValue())
+		assert.True(t, lock.Lookup("updates", "0", "upsert").Boolean())
+		assert.Equal(t, "find", mt.GetStartedEvent().CommandName)
+		insert := mt.GetStartedEvent().Command
+		assert.Equal(t, int64(2), insert.Lookup("documents", "0", "_id").Int64())
+		unlock := mt.GetStartedEvent().Command
+		assert.Equal(t, "delete", unlock.Index(0).Key())
+		assert.Equal(t, migrator.owner, unlock.Lookup("deletes", "0", "q", "owner").StringValue())
+	})
+
+	mt.Run("dry run", func(mt *mtest.T) {
+		ran = nil
+		migrator := newMigrator(mt, NewMigrateOptions().SetDryRun(true))
+		mt.AddMockResponses(appliedResponse(mt))
+		result, err := migrator.Up(ctx)
+		assert.NoError(t, err)
+		assert.True(t, result.DryRun)
+		assert.Equal(t, []int64{2}, result.Applied)
+		assert.Empty(t, ran)
+		assert.Equal(t, "find", mt.GetStartedEvent().CommandName)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("locked", func(mt *mtest.T) {
+		migrator := newMigrator(mt, NewMigrateOptions().SetLockWait(0))
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+		_, err := migrator.Up(ctx)
+		assert.Error(t, err)
+	})
+
+	mt.Run("lock lost", func(mt *mtest.T) {
+		migrator := newMigrator(mt, NewMigrateOptions().SetLockTTL(30*time.Millisecond))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+		lockCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		err := migrator.heartbeat(lockCtx, cancel)
+		assert.Error(t, err)
+		assert.Error(t, lockCtx.Err())
+		update := mt.GetStartedEvent().Command
+		assert.Equal(t, migrator.owner, update.Lookup("updates", "0", "q", "owner").StringValue())
+		assert.NotNil(t, update.Lookup("updates", "0", "u", "$set", "expire_time"))
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -47,6 +48,15 @@ type MongoDB struct {
 	operationTimeout  time.Duration
 	database          *mongo.Database
 	collectionOptions *options.CollectionOptions
+	indexManagers     map[string]IndexManager
+	indexCollections  []string
+	indexLock         sync.Mutex
+}
+
+// IndexManager 声明了索引的仓储，由 MongoDB.EnsureIndexes 在启动时统一确保索引
+type IndexManager interface {
+	GetCollectionName() string
+	EnsureIndexes(ctx context.Context) (*IndexReport, error)
 }
 
 type Config struct {
@@ -126,6 +136,60 @@ func (m *MongoDB) GetCollection(collectionName string) *mongo.Collection {
 	return m.database.Collection(collectionName, m.collectionOptions)
 }
 
+//
+// RegisterIndexManager
+// @Description: 注册需要确保索引的仓储，NewRepository 会自动注册。按集合名称注册，同一集合只保留最后注册的仓储
+// @param manager
+//
+func (m *MongoDB) RegisterIndexManager(manager IndexManager) {
+	m.indexLock.Lock()
+	defer m.indexLock.Unlock()
+	if m.indexManagers == nil {
+		m.indexManagers = make(map[string]IndexManager)
+	}
+	name := manager.GetCollectionName()
+	if _, ok := m.indexManagers[name]; !ok {
+		m.indexCollections = append(m.indexCollections, name)
+	}
+	m.indexManagers[name] = manager
+}
+
+//
+// EnsureIndexes
+// @Description: 确保所有已注册仓储声明的索引，可重复执行
+// @param ctx 上下文
+// @return []*IndexReport 各仓储的结果
+// @return error
+//
+func (m *MongoDB) EnsureIndexes(ctx context.Context) ([]*IndexReport, error) {
+	m.indexLock.Lock()
+	managers := make([]IndexManager, 0, len(m.indexCollections))
+	for _, name := range m.indexCollections {
+		managers = append(managers, m.indexManagers[name])
+	}
+	m.indexLock.Unlock()
+
+	reports := make([]*IndexReport, 0, len(managers))
+	for _, manager := range managers {
+		report, err := manager.EnsureIndexes(ctx)
+		if err != nil {
+			return reports, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+//
+// NewMigrator
+// @Description: 新建当前数据库的迁移执行器
+// @param opts 迁移设置
+// @return *Migrator
+//
+func (m *MongoDB) NewMigrator(opts ...*MigrateOptions) *Migrator {
+	return NewMigrator(m.database, opts...)
+}
+
 func (m *MongoDB) CreateCollection(collectionName string) error {
 	ops := &options.CreateCollectionOptions{}
 	return m.database.CreateCollection(context.Background(), collectionName, ops)
@@ -1,34 +1,142 @@
 package ddd_mongodb
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func getDeleteOptions(opts ...*ddd_repository.SetOptions) *options.DeleteOptions {
-	deleteOptions := &options.DeleteOptions{}
-	return deleteOptions
-}
-
 func getFindOptions(opts ...*ddd_repository.FindOptions) *options.FindOptions {
 	opt := ddd_repository.MergeFindOptions(opts...)
-	findOneOptions := &options.FindOptions{}
-	findOneOptions.MaxTime = opt.MaxTime
-	return findOneOptions
+	findOptions := &options.FindOptions{}
+	findOptions.MaxTime = opt.MaxTime
+	findOptions.Limit = opt.Limit
+	findOptions.Skip = opt.Skip
+	findOptions.BatchSize = opt.BatchSize
+	findOptions.Collation = getCollation(opt.Collation)
+	if hint := opt.GetHint(); len(hint) > 0 {
+		findOptions.SetHint(hint)
+	}
+	return findOptions
 }
 
 func getFindOneOptions(opts ...*ddd_repository.FindOptions) *options.FindOneOptions {
 	opt := ddd_repository.MergeFindOptions(opts...)
 	findOneOptions := &options.FindOneOptions{}
 	findOneOptions.MaxTime = opt.MaxTime
+	findOneOptions.Skip = opt.Skip
+	findOneOptions.Collation = getCollation(opt.Collation)
+	if hint := opt.GetHint(); len(hint) > 0 {
+		findOneOptions.SetHint(hint)
+	}
 	return findOneOptions
 }
 
+func getCountOptions(opts ...*ddd_repository.FindOptions) *options.CountOptions {
+	opt := ddd_repository.MergeFindOptions(opts...)
+	countOptions := &options.CountOptions{}
+	countOptions.MaxTime = opt.MaxTime
+	countOptions.Collation = getCollation(opt.Collation)
+	if hint := opt.GetHint(); len(hint) > 0 {
+		countOptions.SetHint(hint)
+	}
+	return countOptions
+}
+
 func getUpdateOptions(opts ...*ddd_repository.SetOptions) *options.UpdateOptions {
+	opt := ddd_repository.MergeSetOptions(opts...)
 	updateOptions := &options.UpdateOptions{}
+	updateOptions.Upsert = opt.Upsert
+	updateOptions.BypassDocumentValidation = opt.BypassValidation
 	return updateOptions
 }
 
 func getInsertOneOptions(opts ...*ddd_repository.SetOptions) *options.InsertOneOptions {
-	return options.InsertOne()
+	opt := ddd_repository.MergeSetOptions(opts...)
+	insertOneOptions := options.InsertOne()
+	insertOneOptions.BypassDocumentValidation = opt.BypassValidation
+	return insertOneOptions
+}
+
+func getBulkWriteOptions(opts ...*ddd_repository.SetOptions) *options.BulkWriteOptions {
+	opt := ddd_repository.MergeSetOptions(opts...)
+	bulkWriteOptions := options.BulkWrite().SetOrdered(opt.GetOrdered())
+	bulkWriteOptions.BypassDocumentValidation = opt.BypassValidation
+	return bulkWriteOptions
+}
+
+//
+//  getWriteContext
+//  @Description: 按 MaxTime 限制写入的执行时间。驱动的写入命令没有 maxTimeMS 选项，通过上下文超时实现
+//  @param ctx 上下文
+//  @param opts 写入选项
+//  @return context.Context
+//  @return context.CancelFunc 写入完成后调用
+//
+func getWriteContext(ctx context.Context, opts ...*ddd_repository.SetOptions) (context.Context, context.CancelFunc) {
+	maxTime := ddd_repository.MergeSetOptions(opts...).MaxTime
+	if maxTime == nil || *maxTime <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, *maxTime)
+}
+
+func getCollation(collation *ddd_repository.Collation) *options.Collation {
+	if collation == nil {
+		return nil
+	}
+	return &options.Collation{
+		Locale:          collation.Locale,
+		CaseLevel:       collation.CaseLevel,
+		Strength:        collation.Strength,
+		NumericOrdering: collation.NumericOrdering,
+	}
+}
+
+//
+//  getReadCollection
+//  @Description: 按读取偏好获取集合，没有设置时使用仓储的集合
+//  @param collection 仓储的集合
+//  @param opts 查询选项
+//  @return *mongo.Collection
+//  @return error 读取偏好无效
+//
+func getReadCollection(collection *mongo.Collection, opts ...*ddd_repository.FindOptions) (*mongo.Collection, error) {
+	readPreference := ddd_repository.MergeFindOptions(opts...).GetReadPreference()
+	if len(readPreference) == 0 {
+		return collection, nil
+	}
+	mode, err := readpref.ModeFromString(string(readPreference))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("read preference %s is error", readPreference))
+	}
+	rp, err := readpref.New(mode)
+	if err != nil {
+		return nil, err
+	}
+	return collection.Clone(options.Collection().SetReadPreference(rp))
+}
+
+//
+//  getWriteCollection
+//  @Description: 按写关注获取集合，没有设置时使用仓储的集合。事务中的操作使用事务的写关注，不能单独设置
+//  @param collection 仓储的集合
+//  @param opts 写入选项
+//  @return *mongo.Collection
+//  @return error 写关注无效
+//
+func getWriteCollection(collection *mongo.Collection, opts ...*ddd_repository.SetOptions) (*mongo.Collection, error) {
+	writeConcern := ddd_repository.MergeSetOptions(opts...).GetWriteConcern()
+	if len(writeConcern) == 0 {
+		return collection, nil
+	}
+	wc, err := getWriteConcernObject(writeConcern)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("write concern %s is error", writeConcern))
+	}
+	return collection.Clone(options.Collection().SetWriteConcern(wc))
 }
@@ -0,0 +1,202 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+func newMockUserRepository(mt *mtest.T, opts ...*RepositoryOptions) *Repository[*User] {
+	return NewRepository[*User](func() *User { return &User{} }, nil, mt.Coll, opts...)
+}
+
+func mockNamespace(mt *mtest.T) string {
+	return mt.Coll.Database().Name() + "." + mt.Coll.Name()
+}
+
+func TestRepository_FindOptions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+	collation := &ddd_repository.Collation{Locale: "zh", Strength: 2, NumericOrdering: true}
+
+	mt.Run("find list", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mockNamespace(mt), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "1"}, {Key: "tenantId", Value: "001"}, {Key: "userName", Value: "lxd"}}))
+		opts := ddd_repository.NewFindOptions().
+			SetHint("user_name_1").
+			SetCollation(collation).
+			SetReadPreference(ddd_repository.ReadSecondaryPreferred).
+			SetLimit(10).
+			SetSkip(20).
+			SetMaxTime(3 * time.Second)
+		list, ok, err := repos.FindAll(ctx, "001", opts).Result()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Len(t, *list, 1)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "user_name_1", cmd.Lookup("hint").StringValue())
+		assert.Equal(t, int64(10), cmd.Lookup("limit").AsInt64())
+		assert.Equal(t, int64(20), cmd.Lookup("skip").AsInt64())
+		assert.Equal(t, int64(3000), cmd.Lookup("maxTimeMS").AsInt64())
+		assert.Equal(t, "zh", cmd.Lookup("collation", "locale").StringValue())
+		assert.Equal(t, int32(2), cmd.Lookup("collation", "strength").Int32())
+		assert.True(t, cmd.Lookup("collation", "numericOrdering").Boolean())
+		assert.Equal(t, "secondaryPreferred", cmd.Lookup("$readPreference", "mode").StringValue())
+	})
+
+	mt.Run("find one", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mockNamespace(mt), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "1"}, {Key: "tenantId", Value: "001"}}))
+		opts := ddd_repository.NewFindOptions().SetHint("_id_").SetCollation(collation).SetSkip(1).
+			SetReadPreference(ddd_repository.ReadNearest)
+		_, ok, err := repos.FindById(ctx, "001", "1", opts).Result()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "_id_", cmd.Lookup("hint").StringValue())
+		assert.Equal(t, int64(1), cmd.Lookup("skip").AsInt64())
+		assert.Equal(t, "zh", cmd.Lookup("collation", "locale").StringValue())
+		assert.Equal(t, "nearest", cmd.Lookup("$readPreference", "mode").StringValue())
+	})
+
+	mt.Run("find paging", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		ns := mockNamespace(mt)
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "_id", Value: "1"}, {Key: "tenantId", Value: "001"}}),
+			mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{{Key: "n", Value: int32(1)}}),
+		)
+		query := ddd_repository.NewFindPagingQuery()
+		query.SetTenantId("001")
+		query.SetPageSize(5)
+		query.SetPageNum(1)
+		opts := ddd_repository.NewFindOptions().SetHint("tenant_id_1").SetCollation(collation).SetLimit(100).SetSkip(100)
+		result := repos.FindPaging(ctx, query, opts)
+		assert.NoError(t, result.GetError())
+		assert.Equal(t, int64(1), result.TotalRows)
+
+		find := mt.GetStartedEvent().Command
+		assert.Equal(t, "tenant_id_1", find.Lookup("hint").StringValue())
+		assert.Equal(t, int64(5), find.Lookup("limit").AsInt64())
+		assert.Equal(t, int64(5), find.Lookup("skip").AsInt64())
+		count := mt.GetStartedEvent().Command
+		assert.Equal(t, "aggregate", count.Index(0).Key())
+		assert.Equal(t, "tenant_id_1", count.Lookup("hint").StringValue())
+		assert.Equal(t, "zh", count.Lookup("collation", "locale").StringValue())
+	})
+
+	mt.Run("invalid read preference", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		opts := ddd_repository.NewFindOptions().SetReadPreference("unknown")
+		assert.Error(t, repos.FindAll(ctx, "001", opts).GetError())
+		assert.Error(t, repos.FindById(ctx, "001", "1", opts).GetError())
+	})
+}
+
+func TestRepository_SetOptions(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+	user := &User{Id: "1", TenantId: "001", UserName: "lxd"}
+
+	mt.Run("insert", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		opts := ddd_repository.NewSetOptions().SetBypassValidation(true).SetWriteConcern("2")
+		assert.NoError(t, repos.Insert(ctx, user, opts).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		assert.True(t, cmd.Lookup("bypassDocumentValidation").Boolean())
+		assert.Equal(t, int32(2), cmd.Lookup("writeConcern", "w").Int32())
+	})
+
+	mt.Run("update", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		opts := ddd_repository.NewSetOptions().SetUpsert(true).SetBypassValidation(true).SetWriteConcern("majority")
+		assert.NoError(t, repos.Update(ctx, user, opts).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		assert.True(t, cmd.Lookup("updates", "0", "upsert").Boolean())
+		assert.True(t, cmd.Lookup("bypassDocumentValidation").Boolean())
+		assert.Equal(t, "majority", cmd.Lookup("writeConcern", "w").StringValue())
+	})
+
+	mt.Run("update default", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		assert.NoError(t, repos.Update(ctx, user).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		_, err := cmd.LookupErr("updates", "0", "upsert")
+		assert.Error(t, err)
+		_, err = cmd.LookupErr("bypassDocumentValidation")
+		assert.Error(t, err)
+	})
+
+	mt.Run("bulk write", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		opts := ddd_repository.NewSetOptions().SetOrdered(false).SetBypassValidation(true).SetWriteConcern("majority")
+		assert.NoError(t, repos.InsertMany(ctx, []*User{user}, opts).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		assert.False(t, cmd.Lookup("ordered").Boolean())
+		assert.True(t, cmd.Lookup("bypassDocumentValidation").Boolean())
+		assert.Equal(t, "majority", cmd.Lookup("writeConcern", "w").StringValue())
+	})
+
+	mt.Run("delete", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		opts := ddd_repository.NewSetOptions().SetWriteConcern("majority")
+		assert.NoError(t, repos.DeleteById(ctx, "001", "1", opts).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "delete", cmd.Index(0).Key())
+		assert.Equal(t, "majority", cmd.Lookup("writeConcern", "w").StringValue())
+	})
+
+	mt.Run("soft delete never upserts", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().SetSoftDelete(true))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		opts := ddd_repository.NewSetOptions().SetUpsert(true)
+		assert.NoError(t, repos.DeleteById(ctx, "001", "1", opts).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, "update", cmd.Index(0).Key())
+		assert.False(t, cmd.Lookup("updates", "0", "upsert").Boolean())
+	})
+
+	mt.Run("max time", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+		assert.NoError(t, repos.DeleteById(ctx, "001", "1", ddd_repository.NewSetOptions().SetMaxTime(time.Minute)).GetError())
+		assert.Equal(t, "delete", mt.GetStartedEvent().Command.Index(0).Key())
+
+		opts := ddd_repository.NewSetOptions().SetMaxTime(time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		assert.ErrorIs(t, repos.Insert(ctx, user, opts).GetError(), context.DeadlineExceeded)
+		assert.ErrorIs(t, repos.Update(ctx, user, opts).GetError(), context.DeadlineExceeded)
+		assert.ErrorIs(t, repos.DeleteById(ctx, "001", "1", opts).GetError(), context.DeadlineExceeded)
+		assert.ErrorIs(t, repos.InsertMany(ctx, []*User{user}, opts).GetError(), context.DeadlineExceeded)
+		assert.ErrorIs(t, repos.DeleteByIds(ctx, "001", []string{"1"}, opts).GetError(), context.DeadlineExceeded)
+	})
+
+	mt.Run("invalid write concern", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		opts := ddd_repository.NewSetOptions().SetWriteConcern("all")
+		assert.Error(t, repos.Insert(ctx, user, opts).GetError())
+		assert.Error(t, repos.Update(ctx, user, opts).GetError())
+		assert.Error(t, repos.InsertMany(ctx, []*User{user}, opts).GetError())
+	})
+}
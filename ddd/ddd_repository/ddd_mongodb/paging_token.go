@@ -0,0 +1,123 @@
+package ddd_mongodb
+
+import (
+	"encoding/base64"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"strings"
+)
+
+// 游标分页的续查令牌，保存上一页最后一条数据的排序字段值
+type pagingToken struct {
+	Sort   string          `bson:"s"`
+	Values []bson.RawValue `bson:"v"`
+}
+
+var errInvalidContinuationToken = errors.New("continuation token is invalid")
+
+//
+//  getKeysetSort
+//  @Description: 游标分页使用的排序，在排序字段后追加 _id 以保证顺序唯一
+//  @param sort 排序字段
+//  @return bson.D
+//
+func getKeysetSort(sort bson.D) bson.D {
+	for _, e := range sort {
+		if e.Key == IdField {
+			return sort
+		}
+	}
+	return append(append(bson.D{}, sort...), bson.E{Key: IdField, Value: 1})
+}
+
+func getSortSignature(sort bson.D) string {
+	items := make([]string, len(sort))
+	for i, e := range sort {
+		order := "asc"
+		if e.Value == -1 {
+			order = "desc"
+		}
+		items[i] = e.Key + ":" + order
+	}
+	return strings.Join(items, ",")
+}
+
+//
+//  newContinuationToken
+//  @Description: 按最后一条数据的排序字段值生成续查令牌
+//  @param sort 游标分页的排序
+//  @param last 最后一条数据
+//  @return string
+//  @return error
+//
+func newContinuationToken(sort bson.D, last bson.Raw) (string, error) {
+	token := &pagingToken{
+		Sort:   getSortSignature(sort),
+		Values: make([]bson.RawValue, len(sort)),
+	}
+	for i, e := range sort {
+		value, err := last.LookupErr(strings.Split(e.Key, ".")...)
+		if err != nil {
+			value = bson.RawValue{Type: bsontype.Null}
+		}
+		token.Values[i] = value
+	}
+	data, err := bson.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+//
+//  newKeysetFilter
+//  @Description: 按续查令牌生成从上一页最后一条数据之后开始的过滤条件
+//  @param sort 游标分页的排序
+//  @param continuationToken 续查令牌
+//  @return bson.M
+//  @return error 令牌无效或与排序不一致
+//
+func newKeysetFilter(sort bson.D, continuationToken string) (bson.M, error) {
+	data, err := base64.RawURLEncoding.DecodeString(continuationToken)
+	if err != nil {
+		return nil, errInvalidContinuationToken
+	}
+	token := &pagingToken{}
+	if err := bson.Unmarshal(data, token); err != nil {
+		return nil, errInvalidContinuationToken
+	}
+	if token.Sort != getSortSignature(sort) || len(token.Values) != len(sort) {
+		return nil, errors.New("continuation token does not match the sort")
+	}
+
+	// MongoDB 排序时 null 与缺失的字段最小：升序排在最前，降序排在最后
+	or := bson.A{}
+	for i, e := range sort {
+		item := bson.D{}
+		for j := 0; j < i; j++ {
+			item = append(item, bson.E{Key: sort[j].Key, Value: token.Values[j]})
+		}
+		value := token.Values[i]
+		isNull := value.Type == bsontype.Null || value.Type == bsontype.Undefined
+		if e.Value == -1 {
+			if isNull {
+				// 降序时 null 之后没有更小的值，只能由后续排序字段决定
+				continue
+			}
+			item = append(item, bson.E{Key: "$or", Value: bson.A{
+				bson.D{{Key: e.Key, Value: bson.M{"$lt": value}}},
+				bson.D{{Key: e.Key, Value: nil}},
+			}})
+		} else if isNull {
+			item = append(item, bson.E{Key: e.Key, Value: bson.M{"$ne": nil}})
+		} else {
+			item = append(item, bson.E{Key: e.Key, Value: bson.M{"$gt": value}})
+		}
+		or = append(or, item)
+	}
+	if len(or) == 0 {
+		return bson.M{IdField: bson.M{"$in": bson.A{}}}, nil
+	}
+	return bson.M{"$or": or}, nil
+}
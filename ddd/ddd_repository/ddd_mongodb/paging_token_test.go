@@ -0,0 +1,80 @@
+package ddd_mongodb
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func TestContinuationToken(t *testing.T) {
+	repos := newRepository(nil, nil)
+	sort, err := repos.getSort("name:desc, age")
+	assert.NoError(t, err)
+	sort = getKeysetSort(sort)
+	assert.Equal(t, bson.D{{Key: "name", Value: -1}, {Key: "age", Value: 1}, {Key: IdField, Value: 1}}, sort)
+
+	last, err := bson.Marshal(bson.D{{Key: IdField, Value: "id-9"}, {Key: "name", Value: "lxd"}, {Key: "age", Value: 30}})
+	assert.NoError(t, err)
+	token, err := newContinuationToken(sort, last)
+	assert.NoError(t, err)
+
+	filter, err := newKeysetFilter(sort, token)
+	assert.NoError(t, err)
+	data, err := bson.MarshalExtJSON(filter, false, false)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"$or":[
+		{"$or":[{"name":{"$lt":"lxd"}},{"name":null}]},
+		{"name":"lxd","age":{"$gt":30}},
+		{"name":"lxd","age":30,"_id":{"$gt":"id-9"}}
+	]}`, string(data))
+
+	sort, _ = repos.getSort("name:asc")
+	_, err = newKeysetFilter(getKeysetSort(sort), token)
+	assert.Error(t, err)
+	_, err = newKeysetFilter(sort, "not a token")
+	assert.Error(t, err)
+}
+
+func TestContinuationToken_Null(t *testing.T) {
+	repos := newRepository(nil, nil)
+	// name 为 null，age 字段缺失
+	last, err := bson.Marshal(bson.D{{Key: IdField, Value: "id-9"}, {Key: "name", Value: nil}})
+	assert.NoError(t, err)
+
+	sort, err := repos.getSort("name, age:desc")
+	assert.NoError(t, err)
+	sort = getKeysetSort(sort)
+	token, err := newContinuationToken(sort, last)
+	assert.NoError(t, err)
+	filter, err := newKeysetFilter(sort, token)
+	assert.NoError(t, err)
+	data, err := bson.MarshalExtJSON(filter, false, false)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"$or":[
+		{"name":{"$ne":null}},
+		{"name":null,"age":null,"_id":{"$gt":"id-9"}}
+	]}`, string(data))
+
+	sort, err = repos.getSort("age:desc, name")
+	assert.NoError(t, err)
+	sort = getKeysetSort(sort)
+	token, err = newContinuationToken(sort, last)
+	assert.NoError(t, err)
+	filter, err = newKeysetFilter(sort, token)
+	assert.NoError(t, err)
+	data, err = bson.MarshalExtJSON(filter, false, false)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"$or":[
+		{"age":null,"name":{"$ne":null}},
+		{"age":null,"name":null,"_id":{"$gt":"id-9"}}
+	]}`, string(data))
+
+	sort = bson.D{{Key: "age", Value: -1}, {Key: IdField, Value: -1}}
+	last, err = bson.Marshal(bson.D{{Key: "age", Value: nil}})
+	assert.NoError(t, err)
+	token, err = newContinuationToken(sort, last)
+	assert.NoError(t, err)
+	filter, err = newKeysetFilter(sort, token)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.M{IdField: bson.M{"$in": bson.A{}}}, filter)
+}
@@ -0,0 +1,177 @@
+package ddd_mongodb
+
+import (
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//
+//  newProjection
+//  @Description: 按返回字段生成Mongo投影。字段以逗号分隔，以-开头表示排除，如 name,address.city 或 -password，
+//  包含与排除不能混用（排除 id 除外）
+//  @param entity 实体，用于校验字段
+//  @param fields 返回字段
+//  @return bson.D 投影，字段为空时返回nil
+//  @return error 有未知字段或混用包含与排除时返回 *ddd_errors.VerifyError
+//
+func newProjection(entity interface{}, fields string) (bson.D, error) {
+	verifyError := ddd_errors.NewVerifyError()
+	projection := bson.D{}
+	exists := make(map[string]bool)
+	includeCount, excludeCount := 0, 0
+	for _, field := range strings.Split(fields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		value := 1
+		path := field
+		if strings.HasPrefix(field, "-") {
+			value = 0
+			path = strings.TrimSpace(field[1:])
+		}
+		fieldName, err := getFieldPath(reflect.TypeOf(entity), path)
+		if err != nil {
+			verifyError.AppendField(field, err.Error())
+			continue
+		}
+		if exists[fieldName] {
+			continue
+		}
+		exists[fieldName] = true
+		if fieldName != IdField {
+			if value == 1 {
+				includeCount++
+			} else {
+				excludeCount++
+			}
+		}
+		projection = append(projection, bson.E{Key: fieldName, Value: value})
+	}
+	if includeCount > 0 && excludeCount > 0 {
+		verifyError.AppendField("fields", "cannot mix inclusion and exclusion")
+	}
+	if err := verifyError.GetError(); err != nil {
+		return nil, err
+	}
+	if len(projection) == 0 {
+		return nil, nil
+	}
+	return projection, nil
+}
+
+//
+//  ensureProjectionFields
+//  @Description: 确保投影结果中包含指定的字段，用于游标分页时读取排序字段的值
+//  @param projection 投影
+//  @param sort 排序
+//  @return bson.D
+//
+func ensureProjectionFields(projection bson.D, sort bson.D) bson.D {
+	if len(projection) == 0 {
+		return projection
+	}
+	inclusion := false
+	for _, e := range projection {
+		if e.Key != IdField && e.Value == 1 {
+			inclusion = true
+		}
+	}
+	res := bson.D{}
+	for _, e := range projection {
+		if !inclusion && e.Value == 0 && containsKey(sort, e.Key) {
+			continue
+		}
+		if inclusion && e.Key == IdField && e.Value == 0 && containsKey(sort, e.Key) {
+			continue
+		}
+		res = append(res, e)
+	}
+	if inclusion {
+		for _, e := range sort {
+			if e.Key != IdField && !containsKey(res, e.Key) {
+				res = append(res, bson.E{Key: e.Key, Value: 1})
+			}
+		}
+	}
+	return res
+}
+
+func containsKey(d bson.D, key string) bool {
+	for _, e := range d {
+		if e.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+//
+//  getFieldPath
+//  @Description: 按实体类型校验字段路径，并转换为Mongo字段名
+//  @param t 实体类型
+//  @param path 驼峰格式的字段路径，以.分隔
+//  @return string Mongo字段名
+//  @return error 字段不存在
+//
+func getFieldPath(t reflect.Type, path string) (string, error) {
+	if path == "" {
+		return "", errors.New("field path is empty")
+	}
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if name == "" {
+			return "", errors.New("field path is invalid")
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := findMaskField(t, name)
+			if !ok {
+				return "", errors.New("field is not exist")
+			}
+			t = field.Type
+			names[i] = AsFieldName(name)
+		case reflect.Map:
+			if t.Key().Kind() != reflect.String {
+				return "", errors.New("field is not exist")
+			}
+			t = t.Elem()
+		case reflect.Slice, reflect.Array:
+			// 数组元素的字段，如 items.name，或数组下标，如 items.0
+			if _, err := strconv.Atoi(name); err == nil {
+				t = t.Elem()
+				continue
+			}
+			t = t.Elem()
+			for t.Kind() == reflect.Ptr {
+				t = t.Elem()
+			}
+			if t.Kind() != reflect.Struct {
+				return "", errors.New("field is not exist")
+			}
+			field, ok := findMaskField(t, name)
+			if !ok {
+				return "", errors.New("field is not exist")
+			}
+			t = field.Type
+			names[i] = AsFieldName(name)
+		case reflect.Interface:
+			// 动态类型的字段无法校验
+			return strings.Join(names, "."), nil
+		default:
+			return "", errors.New("field is not exist")
+		}
+	}
+	fieldName := strings.Join(names, ".")
+	if fieldName == "id" {
+		fieldName = IdField
+	}
+	return fieldName, nil
+}
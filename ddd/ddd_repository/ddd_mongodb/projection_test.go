@@ -0,0 +1,46 @@
+package ddd_mongodb
+
+import (
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+func Test_newProjection(t *testing.T) {
+	customer := &maskCustomer{}
+	projection, err := newProjection(customer, "userName, address.city,contacts.postCode,tags.level,-id")
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "user_name", Value: 1},
+		{Key: "address.city", Value: 1},
+		{Key: "contacts.post_code", Value: 1},
+		{Key: "tags.level", Value: 1},
+		{Key: IdField, Value: 0},
+	}, projection)
+
+	projection, err = newProjection(customer, "-secretCode,-address")
+	assert.Error(t, err)
+
+	projection, err = newProjection(customer, "-remarks,-address")
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "remarks", Value: 0}, {Key: "address", Value: 0}}, projection)
+
+	projection, err = newProjection(customer, " , ")
+	assert.NoError(t, err)
+	assert.Nil(t, projection)
+
+	_, err = newProjection(customer, "userName,-address,unknown")
+	verifyError, ok := err.(*ddd_errors.VerifyError)
+	assert.True(t, ok)
+	assert.Len(t, verifyError.Errors, 2)
+}
+
+func Test_ensureProjectionFields(t *testing.T) {
+	sort := bson.D{{Key: "year", Value: -1}, {Key: IdField, Value: 1}}
+	projection := ensureProjectionFields(bson.D{{Key: "user_name", Value: 1}, {Key: IdField, Value: 0}}, sort)
+	assert.Equal(t, bson.D{{Key: "user_name", Value: 1}, {Key: "year", Value: 1}}, projection)
+
+	projection = ensureProjectionFields(bson.D{{Key: "year", Value: 0}, {Key: "remarks", Value: 0}}, sort)
+	assert.Equal(t, bson.D{{Key: "remarks", Value: 0}}, projection)
+}
@@ -3,6 +3,7 @@ package ddd_mongodb
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
@@ -11,6 +12,7 @@ import (
 	"github.com/liuxd6825/dapr-go-ddd-sdk/utils/stringutils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"reflect"
 	"strings"
 )
 
@@ -25,14 +27,29 @@ type Repository[T ddd.Entity] struct {
 	mongodb       *MongoDB
 	emptyEntity   T
 	newFun        func() T
+	options       *RepositoryOptions
 }
 
-func NewRepository[T ddd.Entity](newFun func() T, mongodb *MongoDB, collection *mongo.Collection) *Repository[T] {
-	return &Repository[T]{
+//
+// NewRepository
+// @Description: 新建Mongo仓储
+// @param newFun 新建实体方法
+// @param mongodb MongoDB
+// @param collection 集合
+// @param opts 仓储设置，如启用软删除、声明索引
+// @return *Repository[T]
+//
+func NewRepository[T ddd.Entity](newFun func() T, mongodb *MongoDB, collection *mongo.Collection, opts ...*RepositoryOptions) *Repository[T] {
+	r := &Repository[T]{
 		newFun:     newFun,
 		collection: collection,
 		mongodb:    mongodb,
+		options:    MergeRepositoryOptions(opts...),
 	}
+	if mongodb != nil {
+		mongodb.RegisterIndexManager(r)
+	}
+	return r
 }
 
 func (r *Repository[T]) NewEntity() T {
@@ -47,8 +64,15 @@ func (r *Repository[T]) Insert(ctx context.Context, entity T, opts ...*ddd_repos
 	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
 		return ddd_repository.NewSetResultError[T](err)
 	}
+	ddd_repository.SetInsertAudit(ctx, entity)
 	return r.DoSet(func() (T, error) {
-		_, err := r.collection.InsertOne(ctx, entity, getInsertOneOptions(opts...))
+		collection, err := r.getWriteCollection(entity.GetTenantId(), opts...)
+		if err != nil {
+			return entity, err
+		}
+		ctx, cancel := getWriteContext(ctx, opts...)
+		defer cancel()
+		_, err = collection.InsertOne(ctx, entity, getInsertOneOptions(opts...))
 		return entity, err
 	})
 }
@@ -57,19 +81,94 @@ func (r *Repository[T]) Update(ctx context.Context, entity T, opts ...*ddd_repos
 	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
 		return ddd_repository.NewSetResultError[T](err)
 	}
+	ddd_repository.SetUpdateAudit(ctx, entity)
 	return r.DoSet(func() (T, error) {
 		objId, err := GetObjectID(entity.GetId())
 		if err != nil {
 			return entity, err
 		}
-		updateOptions := getUpdateOptions(opts...)
-		filter := bson.D{{IdField, objId}}
-		setData := bson.M{"$set": entity}
-		_, err = r.collection.UpdateOne(ctx, filter, setData, updateOptions)
+		filter := bson.D{{Key: IdField, Value: objId}}
+		err = r.updateOne(ctx, entity, filter, nil, opts...)
+		return entity, err
+	})
+}
+
+//
+// UpdateByMask
+// @Description: 按更新掩码更新实体，只写入掩码中的字段，掩码为空时更新整个实体。启用软删除时不更新已删除的数据
+// @param ctx 上下文
+// @param entity 实体
+// @param mask 更新掩码，驼峰格式的字段路径，如 userName、address.city
+// @param opts 更新选项
+// @return *ddd_repository.SetResult[T] 掩码中有未知字段时返回 *ddd_errors.VerifyError
+//
+func (r *Repository[T]) UpdateByMask(ctx context.Context, entity T, mask []string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if len(mask) == 0 {
+		return r.Update(ctx, entity, opts...)
+	}
+	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	return r.DoSet(func() (T, error) {
+		auditFields := ddd_repository.SetUpdateAudit(ctx, entity)
+		setData, err := newMaskSetData(entity, mask)
+		if err != nil {
+			return entity, err
+		}
+		setData = appendMaskSetData(setData, entity, auditFields)
+		objId, err := GetObjectID(entity.GetId())
+		if err != nil {
+			return entity, err
+		}
+		filter := bson.D{{Key: TenantIdField, Value: entity.GetTenantId()}, {Key: IdField, Value: objId}}
+		err = r.updateOne(ctx, entity, filter, setData, opts...)
 		return entity, err
 	})
 }
 
+//
+// InsertMany
+// @Description: 批量新建，使用BulkWrite执行，ctx中的会话有效
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) InsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.bulkWrite(ctx, entities, opts, func(i int, entity T) (mongo.WriteModel, error) {
+		ddd_repository.SetInsertAudit(ctx, entity)
+		return mongo.NewInsertOneModel().SetDocument(entity), nil
+	}, nil)
+}
+
+//
+// UpdateMany
+// @Description: 批量更新，按实体id更新，使用BulkWrite执行，启用软删除时不更新已删除的数据。实体启用乐观锁时版本号不匹配的项返回 *ddd_errors.VersionConflictError
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) UpdateMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.bulkUpdate(ctx, entities, opts, false, func(entity T) {
+		ddd_repository.SetUpdateAudit(ctx, entity)
+	})
+}
+
+//
+// UpsertMany
+// @Description: 批量新建或更新，实体id不存在时新建，使用BulkWrite执行。实体启用乐观锁时版本号不匹配的项返回 *ddd_errors.VersionConflictError
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) UpsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.bulkUpdate(ctx, entities, opts, true, func(entity T) {
+		ddd_repository.SetInsertAudit(ctx, entity)
+	})
+}
+
 /*func (r *Repository[T]) UpdateMap(ctx context.Context, tenantId string, data map[string]interface{}, filterMap map[string]interface{}, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[map[string]interface{}] {
 	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
 		return ddd_repository.NewSetResultError[map[string]interface{}](err)
@@ -93,12 +192,52 @@ func (r *Repository[T]) DeleteById(ctx context.Context, tenantId string, id stri
 	data := map[string]interface{}{
 		IdField: id,
 	}
-	return r.DeleteByMap(ctx, tenantId, data)
+	return r.DeleteByMap(ctx, tenantId, data, opts...)
+}
+
+//
+// DeleteByIds
+// @Description: 按id批量删除，使用BulkWrite执行
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param ids id列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) DeleteByIds(ctx context.Context, tenantId string, ids []string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetManyResultError[T](err)
+	}
+	if len(ids) == 0 {
+		return ddd_repository.NewSetManyResult[T](nil, ddd_repository.NewSetManyItems(ids), nil)
+	}
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		objId, err := GetObjectID(id)
+		if err != nil {
+			return ddd_repository.NewSetManyResultError[T](err)
+		}
+		filter := bson.D{{Key: TenantIdField, Value: tenantId}, {Key: IdField, Value: objId}}
+		if notDeleted, ok := r.getNotDeletedFilter(false); ok {
+			models[i] = mongo.NewUpdateOneModel().SetFilter(append(filter, notDeleted)).SetUpdate(newSoftDeleteUpdate())
+		} else {
+			models[i] = mongo.NewDeleteOneModel().SetFilter(filter)
+		}
+	}
+	collection, err := r.getWriteCollection(tenantId, opts...)
+	if err != nil {
+		return ddd_repository.NewSetManyResultError[T](err)
+	}
+	ctx, cancel := getWriteContext(ctx, opts...)
+	defer cancel()
+	_, err = collection.BulkWrite(ctx, models, getBulkWriteOptions(opts...))
+	items, err := newBulkWriteItems(ids, err, ddd_repository.MergeSetOptions(opts...).GetOrdered())
+	return ddd_repository.NewSetManyResult[T](nil, items, err)
 }
 
 func (r *Repository[T]) DeleteAll(ctx context.Context, tenantId string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
 	data := map[string]interface{}{}
-	return r.DeleteByMap(ctx, tenantId, data)
+	return r.DeleteByMap(ctx, tenantId, data, opts...)
 }
 
 func (r *Repository[T]) DeleteByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
@@ -109,17 +248,38 @@ func (r *Repository[T]) DeleteByMap(ctx context.Context, tenantId string, filter
 		return ddd_repository.NewSetResultError[T](err)
 	}
 	return r.DoSet(func() (T, error) {
-		filter := r.NewFilter(tenantId, filterMap)
-		deleteOptions := getDeleteOptions(opts...)
-		_, err := r.collection.DeleteOne(ctx, filter, deleteOptions)
 		var result T
+		collection, err := r.getWriteCollection(tenantId, opts...)
+		if err != nil {
+			return result, err
+		}
+		filter := r.NewFilter(tenantId, filterMap)
+		ctx, cancel := getWriteContext(ctx, opts...)
+		defer cancel()
+		if r.IsSoftDelete() {
+			// 删除不能新建数据
+			_, err := collection.UpdateOne(ctx, filter, newSoftDeleteUpdate(), getUpdateOptions(opts...).SetUpsert(false))
+			return result, err
+		}
+		_, err = collection.DeleteOne(ctx, filter)
 		return result, err
 	})
 }
 
+//
+// NewFilter
+// @Description: 新建过滤条件，启用软删除时排除已删除的数据
+// @param tenantId 租户id
+// @param filterMap 过滤字段
+// @return bson.D
+//
 func (r *Repository[T]) NewFilter(tenantId string, filterMap map[string]interface{}) bson.D {
+	return r.newFilter(tenantId, filterMap, false)
+}
+
+func (r *Repository[T]) newFilter(tenantId string, filterMap map[string]interface{}, includeDeleted bool) bson.D {
 	filter := bson.D{
-		{TenantIdField, tenantId},
+		{Key: TenantIdField, Value: tenantId},
 	}
 	if filterMap != nil {
 		for fieldName, fieldValue := range filterMap {
@@ -133,7 +293,7 @@ func (r *Repository[T]) NewFilter(tenantId string, filterMap map[string]interfac
 			filter = append(filter, e)
 		}
 	}
-	return filter
+	return r.addNotDeletedFilterD(filter, includeDeleted)
 }
 func (r *Repository[T]) FindById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
 	idMap := map[string]interface{}{
@@ -144,10 +304,21 @@ func (r *Repository[T]) FindById(ctx context.Context, tenantId string, id string
 
 func (r *Repository[T]) FindOneByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
 	return r.DoFindOne(func() (T, bool, error) {
-		filter := r.NewFilter(tenantId, filterMap)
+		filter := r.newFilter(tenantId, filterMap, ddd_repository.MergeFindOptions(opts...).GetIncludeDeleted())
 		findOneOptions := getFindOneOptions(opts...)
+		projection, err := r.getProjection(ddd_repository.MergeFindOptions(opts...).GetFields())
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
+		if projection != nil {
+			findOneOptions.SetProjection(projection)
+		}
+		collection, err := r.getReadCollection(tenantId, opts...)
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
 		data := r.NewEntity()
-		result := r.collection.FindOne(ctx, filter, findOneOptions)
+		result := collection.FindOne(ctx, filter, findOneOptions)
 		if result.Err() != nil {
 			return r.emptyEntity, false, result.Err()
 		}
@@ -160,10 +331,21 @@ func (r *Repository[T]) FindOneByMap(ctx context.Context, tenantId string, filte
 
 func (r *Repository[T]) FindListByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindListResult[T] {
 	return r.DoFindList(func() (*[]T, bool, error) {
-		filter := r.NewFilter(tenantId, filterMap)
+		filter := r.newFilter(tenantId, filterMap, ddd_repository.MergeFindOptions(opts...).GetIncludeDeleted())
 		data := r.NewEntityList()
 		findOptions := getFindOptions(opts...)
-		cursor, err := r.collection.Find(ctx, filter, findOptions)
+		projection, err := r.getProjection(ddd_repository.MergeFindOptions(opts...).GetFields())
+		if err != nil {
+			return nil, false, err
+		}
+		if projection != nil {
+			findOptions.SetProjection(projection)
+		}
+		collection, err := r.getReadCollection(tenantId, opts...)
+		if err != nil {
+			return nil, false, err
+		}
+		cursor, err := collection.Find(ctx, filter, findOptions)
 		if err != nil {
 			return nil, false, err
 		}
@@ -176,6 +358,15 @@ func (r *Repository[T]) FindAll(ctx context.Context, tenantId string, opts ...*d
 	return r.FindListByMap(ctx, tenantId, nil, opts...)
 }
 
+//
+// FindPaging
+// @Description: 分页查询。query 有续查令牌时按上一页最后一条数据之后查询，不使用 skip。
+// 使用 =search= 全文检索且未指定排序时按相关度排序，此时只按页码分页，不返回续查令牌
+// @param ctx 上下文
+// @param query 分页查询条件，GetIsTotalRows() 为false时不统计总行数
+// @param opts 查询选项
+// @return *ddd_repository.FindPagingResult[T] 有下一页时 ContinuationToken 不为空
+//
 func (r *Repository[T]) FindPaging(ctx context.Context, query ddd_repository.FindPagingQuery, opts ...*ddd_repository.FindOptions) *ddd_repository.FindPagingResult[T] {
 
 	return r.DoFilter(query.GetTenantId(), query.GetFilter(), func(filter map[string]interface{}) (*ddd_repository.FindPagingResult[T], bool, error) {
@@ -184,28 +375,95 @@ func (r *Repository[T]) FindPaging(ctx context.Context, query ddd_repository.Fin
 		}
 
 		data := r.NewEntityList()
+		filter = r.addNotDeletedFilter(filter, ddd_repository.MergeFindOptions(opts...).GetIncludeDeleted())
 
+		if err := r.checkTextSearch(filter); err != nil {
+			return nil, false, err
+		}
+		sort, err := r.getSort(query.GetSort())
+		if err != nil {
+			return nil, false, err
+		}
+		// 全文检索且未指定排序时按相关度排序，相关度不能用于续查条件，只按页码分页
+		textScore := len(sort) == 0 && hasTextSearch(filter)
+		if textScore {
+			sort = getTextScoreSort()
+		}
 		findOptions := getFindOptions(opts...)
-		if query.GetPageSize() > 0 {
+		findFilter := interface{}(filter)
+		if query.GetPageSize() > 0 && textScore {
+			if len(query.GetContinuationToken()) > 0 {
+				return nil, false, errInvalidContinuationToken
+			}
 			findOptions.SetLimit(query.GetPageSize())
 			findOptions.SetSkip(query.GetPageSize() * query.GetPageNum())
-		}
-		if len(query.GetSort()) > 0 {
-			sort, err := r.getSort(query.GetSort())
-			if err != nil {
-				return nil, false, err
+		} else if query.GetPageSize() > 0 {
+			sort = getKeysetSort(sort)
+			findOptions.SetLimit(query.GetPageSize())
+			if token := query.GetContinuationToken(); len(token) > 0 {
+				keysetFilter, err := newKeysetFilter(sort, token)
+				if err != nil {
+					return nil, false, err
+				}
+				findFilter = bson.M{"$and": bson.A{filter, keysetFilter}}
+			} else {
+				findOptions.SetSkip(query.GetPageSize() * query.GetPageNum())
 			}
+		}
+		if len(sort) > 0 {
 			findOptions.SetSort(sort)
 		}
+		fields := query.GetFields()
+		if len(fields) == 0 {
+			fields = ddd_repository.MergeFindOptions(opts...).GetFields()
+		}
+		projection, err := r.getProjection(fields)
+		if err != nil {
+			return nil, false, err
+		}
+		if projection != nil && textScore {
+			findOptions.SetProjection(projection)
+		} else if projection != nil {
+			findOptions.SetProjection(ensureProjectionFields(projection, sort))
+		}
 
-		cursor, err := r.collection.Find(ctx, filter, findOptions)
+		collection, err := r.getReadCollection(query.GetTenantId(), opts...)
 		if err != nil {
 			return nil, false, err
 		}
-		err = cursor.All(ctx, data)
-		totalRows, err := r.collection.CountDocuments(ctx, filter)
-		findData := ddd_repository.NewFindPagingResult[T](data, totalRows, query, err)
-		return findData, true, err
+		cursor, err := collection.Find(ctx, findFilter, findOptions)
+		if err != nil {
+			return nil, false, err
+		}
+		defer func() {
+			_ = cursor.Close(ctx)
+		}()
+		var last bson.Raw
+		for cursor.Next(ctx) {
+			entity := r.NewEntity()
+			if err := cursor.Decode(entity); err != nil {
+				return nil, false, err
+			}
+			*data = append(*data, entity)
+			last = append(last[:0], cursor.Current...)
+		}
+		if err := cursor.Err(); err != nil {
+			return nil, false, err
+		}
+
+		var totalRows int64
+		if query.GetIsTotalRows() {
+			if totalRows, err = collection.CountDocuments(ctx, filter, getCountOptions(opts...)); err != nil {
+				return nil, false, err
+			}
+		}
+		findData := ddd_repository.NewFindPagingResult[T](data, totalRows, query, nil)
+		if query.GetPageSize() > 0 && !textScore && int64(len(*data)) == query.GetPageSize() {
+			if findData.ContinuationToken, err = newContinuationToken(sort, last); err != nil {
+				return nil, false, err
+			}
+		}
+		return findData, true, nil
 	})
 }
 
@@ -219,10 +477,8 @@ func (r *Repository[T]) DoFilter(tenantId, filter string, fun func(filter map[st
 	}
 	filterData := p.GetFilter(tenantId)
 	data, _, err := fun(filterData)
-	if err != nil {
-		if ddd_errors.IsErrorMongoNoDocuments(err) {
-			err = nil
-		}
+	if err != nil && !ddd_errors.IsErrorMongoNoDocuments(err) {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
 	}
 	return data
 }
@@ -260,12 +516,409 @@ func (r *Repository[T]) DoSetMap(fun func() (map[string]interface{}, error)) *dd
 	return ddd_repository.NewSetResult[T](data, err)
 }
 */
-func (r *Repository[T]) getSort(sort string) (map[string]interface{}, error) {
+//
+//  updateOne
+//  @Description: 更新一条数据，启用软删除时不更新已删除的数据。实体启用乐观锁时按版本号过滤并递增版本号，未匹配到数据时返回 *ddd_errors.VersionConflictError
+//  @param filter 过滤条件
+//  @param setData $set 的内容，为nil时更新整个实体
+//
+func (r *Repository[T]) updateOne(ctx context.Context, entity T, filter bson.D, setData bson.D, opts ...*ddd_repository.SetOptions) error {
+	if setData == nil {
+		if createdFields := getCreatedFields(entity); len(createdFields) > 0 {
+			doc, err := newEntityDocument(entity)
+			if err != nil {
+				return err
+			}
+			setData = bson.D{}
+			for _, e := range doc {
+				if !createdFields[e.Key] {
+					setData = append(setData, e)
+				}
+			}
+		}
+	}
+	getSetData := func() interface{} {
+		if setData == nil {
+			return entity
+		}
+		return setData
+	}
+	collection, err := r.getWriteCollection(entity.GetTenantId(), opts...)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := getWriteContext(ctx, opts...)
+	defer cancel()
+	filter = r.addNotDeletedFilterD(filter, false)
+	version, ok := ddd_repository.GetEntityVersion(entity)
+	if !ok {
+		_, err := collection.UpdateOne(ctx, filter, bson.M{"$set": getSetData()}, getUpdateOptions(opts...))
+		return err
+	}
+
+	current := version.Value
+	versionField := getBsonFieldName(entity, version.Name)
+	filter = append(filter, bson.E{Key: versionField, Value: current})
+	version.Set(current + 1)
+	if setData != nil {
+		data := bson.D{}
+		for _, e := range setData {
+			if e.Key != versionField {
+				data = append(data, e)
+			}
+		}
+		setData = append(data, bson.E{Key: versionField, Value: version.Value})
+	}
+	result, err := collection.UpdateOne(ctx, filter, bson.M{"$set": getSetData()}, getUpdateOptions(opts...))
+	if err == nil && result.MatchedCount == 0 {
+		err = ddd_errors.NewVersionConflictError(entity.GetTenantId(), entity.GetId(), current)
+	}
+	if err != nil {
+		version.Set(current)
+	}
+	return err
+}
+
+//
+//  bulkWrite
+//  @Description: 使用BulkWrite批量写入
+//  @param newModel 生成第i项的写入模型
+//  @param check 检查写入结果，可修改每一项的执行结果，可为nil
+//
+func (r *Repository[T]) bulkWrite(ctx context.Context, entities []T, opts []*ddd_repository.SetOptions, newModel func(i int, entity T) (mongo.WriteModel, error), check bulkWriteCheck) *ddd_repository.SetManyResult[T] {
+	if len(entities) == 0 {
+		return ddd_repository.NewSetManyResult[T](entities, ddd_repository.NewSetManyItems(nil), nil)
+	}
+	models := make([]mongo.WriteModel, len(entities))
+	for i, entity := range entities {
+		if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions(fmt.Sprintf("entities[%d].tenantId is empty", i))); err != nil {
+			return ddd_repository.NewSetManyResultError[T](err)
+		}
+		model, err := newModel(i, entity)
+		if err != nil {
+			return ddd_repository.NewSetManyResultError[T](err)
+		}
+		models[i] = model
+	}
+	collection, err := r.getBulkWriteCollection(entities, opts...)
+	if err != nil {
+		return ddd_repository.NewSetManyResultError[T](err)
+	}
+	ctx, cancel := getWriteContext(ctx, opts...)
+	defer cancel()
+	result, writeErr := collection.BulkWrite(ctx, models, getBulkWriteOptions(opts...))
+	items, err := newBulkWriteItems(ddd_repository.GetEntityIds(entities), writeErr, ddd_repository.MergeSetOptions(opts...).GetOrdered())
+	if check != nil {
+		if e := check(ctx, collection, result, writeErr, items); e != nil && err == nil {
+			err = e
+		}
+	}
+	return ddd_repository.NewSetManyResult[T](entities, items, err)
+}
+
+//
+//  getBulkWriteCollection
+//  @Description: 获取批量写入的集合，按租户隔离时所有实体必须属于同一个集合
+//
+func (r *Repository[T]) getBulkWriteCollection(entities []T, opts ...*ddd_repository.SetOptions) (*mongo.Collection, error) {
+	collection, err := r.getWriteCollection(entities[0].GetTenantId(), opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, entity := range entities[1:] {
+		if entity.GetTenantId() == entities[0].GetTenantId() {
+			continue
+		}
+		c, err := r.GetCollection(entity.GetTenantId())
+		if err != nil {
+			return nil, err
+		}
+		if c.Database().Name() != collection.Database().Name() || c.Name() != collection.Name() {
+			return nil, errors.New(fmt.Sprintf("entities of tenant %s and %s are in different collections", entities[0].GetTenantId(), entity.GetTenantId()))
+		}
+	}
+	return collection, nil
+}
+
+// duplicateKeyCode 唯一索引冲突的错误码
+const duplicateKeyCode = 11000
+
+//
+//  bulkWriteCheck
+//  @Description: 检查BulkWrite的写入结果
+//  @param result BulkWrite的写入结果
+//  @param err BulkWrite返回的错误
+//  @param items 每一项的执行结果
+//  @return error 非单项错误
+//
+type bulkWriteCheck func(ctx context.Context, collection *mongo.Collection, result *mongo.BulkWriteResult, err error, items []*ddd_repository.SetManyItem) error
+
+//
+//  bulkUpdate
+//  @Description: 批量更新或新建。实体启用乐观锁时按版本号过滤并递增版本号，版本号不匹配的项返回 *ddd_errors.VersionConflictError，实体的版本号恢复为原值
+//  @param upsert 实体id不存在时是否新建
+//  @param audit 写入审计字段
+//
+func (r *Repository[T]) bulkUpdate(ctx context.Context, entities []T, opts []*ddd_repository.SetOptions, upsert bool, audit func(entity T)) *ddd_repository.SetManyResult[T] {
+	versions := make([]*ddd_repository.EntityVersion, len(entities))
+	newModel := func(i int, entity T) (mongo.WriteModel, error) {
+		audit(entity)
+		version, ok := ddd_repository.GetEntityVersion(entity)
+		if !ok {
+			return r.newUpdateModel(entity, nil, upsert)
+		}
+		model, err := r.newUpdateModel(entity, version, upsert)
+		if err == nil {
+			versions[i] = version
+		}
+		return model, err
+	}
+	check := func(ctx context.Context, collection *mongo.Collection, result *mongo.BulkWriteResult, err error, items []*ddd_repository.SetManyItem) error {
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) {
+			for _, writeErr := range bulkErr.WriteErrors {
+				index := writeErr.Index
+				if index >= 0 && index < len(versions) && versions[index] != nil && writeErr.Code == duplicateKeyCode {
+					items[index].Error = ddd_errors.NewVersionConflictError(entities[index].GetTenantId(), entities[index].GetId(), versions[index].Value-1)
+				}
+			}
+		}
+		return r.checkBulkVersions(ctx, collection, entities, versions, result, items)
+	}
+	res := r.bulkWrite(ctx, entities, opts, newModel, check)
+	items := res.GetItems()
+	for i, version := range versions {
+		if version != nil && (len(items) != len(versions) || !items[i].IsSuccess()) {
+			version.Set(version.Value - 1)
+		}
+	}
+	return res
+}
+
+//
+//  checkBulkVersions
+//  @Description: BulkWrite的匹配数少于执行成功的项数时，逐项检查启用乐观锁的实体是否已更新为新版本号，未更新的项返回 *ddd_errors.VersionConflictError
+//
+func (r *Repository[T]) checkBulkVersions(ctx context.Context, collection *mongo.Collection, entities []T, versions []*ddd_repository.EntityVersion, result *mongo.BulkWriteResult, items []*ddd_repository.SetManyItem) error {
+	if result == nil {
+		return nil
+	}
+	var success int64
+	ids := make([]interface{}, 0)
+	tenantIds := make([]interface{}, 0)
+	tenants := make(map[string]bool)
+	for i, item := range items {
+		if !item.IsSuccess() {
+			continue
+		}
+		success++
+		if versions[i] != nil {
+			objId, err := GetObjectID(entities[i].GetId())
+			if err != nil {
+				return err
+			}
+			ids = append(ids, objId)
+			if tenantId := entities[i].GetTenantId(); !tenants[tenantId] {
+				tenants[tenantId] = true
+				tenantIds = append(tenantIds, tenantId)
+			}
+		}
+	}
+	if len(ids) == 0 || result.MatchedCount+result.UpsertedCount >= success {
+		return nil
+	}
+	filter := bson.D{{Key: TenantIdField, Value: bson.M{"$in": tenantIds}}, {Key: IdField, Value: bson.M{"$in": ids}}}
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	stored := make(map[string]bson.Raw)
+	for cursor.Next(ctx) {
+		tenantId, _ := cursor.Current.Lookup(TenantIdField).StringValueOK()
+		id, _ := cursor.Current.Lookup(IdField).StringValueOK()
+		stored[tenantId+"/"+id] = cursor.Current
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	for i, version := range versions {
+		if version == nil || !items[i].IsSuccess() {
+			continue
+		}
+		entity := entities[i]
+		doc, ok := stored[entity.GetTenantId()+"/"+entity.GetId()]
+		if ok {
+			if value, isInt := doc.Lookup(getBsonFieldName(entity, version.Name)).AsInt64OK(); isInt && value == version.Value {
+				continue
+			}
+		}
+		items[i].Error = ddd_errors.NewVersionConflictError(entity.GetTenantId(), entity.GetId(), version.Value-1)
+	}
+	return nil
+}
+
+//
+//  newUpdateModel
+//  @Description: 生成按实体id更新的写入模型。version不为nil时按版本号过滤并递增版本号；
+//  upsert时创建人与创建时间只在新建时写入，否则启用软删除时不更新已删除的数据
+//
+func (r *Repository[T]) newUpdateModel(entity T, version *ddd_repository.EntityVersion, upsert bool) (mongo.WriteModel, error) {
+	objId, err := GetObjectID(entity.GetId())
+	if err != nil {
+		return nil, err
+	}
+	filter := bson.D{{Key: TenantIdField, Value: entity.GetTenantId()}, {Key: IdField, Value: objId}}
+	if !upsert {
+		filter = r.addNotDeletedFilterD(filter, false)
+	}
+	createdFields := getCreatedFields(entity)
+	if version == nil && len(createdFields) == 0 {
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.M{"$set": entity}).SetUpsert(upsert), nil
+	}
+	doc, err := newEntityDocument(entity)
+	if err != nil {
+		return nil, err
+	}
+	versionField := ""
+	if version != nil {
+		versionField = getBsonFieldName(entity, version.Name)
+		filter = append(filter, bson.E{Key: versionField, Value: version.Value})
+	}
+	setData, insertData := bson.D{}, bson.D{}
+	for _, e := range doc {
+		switch {
+		case e.Key == versionField:
+		case createdFields[e.Key]:
+			if upsert {
+				insertData = append(insertData, e)
+			}
+		default:
+			setData = append(setData, e)
+		}
+	}
+	update := bson.M{"$set": setData}
+	if len(insertData) > 0 {
+		update["$setOnInsert"] = insertData
+	}
+	if version != nil {
+		update["$inc"] = bson.M{versionField: 1}
+		version.Set(version.Value + 1)
+	}
+	return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetUpsert(upsert), nil
+}
+
+//
+//  getCreatedFields
+//  @Description: 实体创建人与创建时间的bson字段名，更新已有数据时不覆盖这些字段
+//
+func getCreatedFields(entity interface{}) map[string]bool {
+	fields := make(map[string]bool)
+	for _, name := range ddd_repository.GetCreatedAuditFields(entity) {
+		fields[getBsonFieldName(entity, name)] = true
+	}
+	return fields
+}
+
+//
+//  newEntityDocument
+//  @Description: 将实体转换为bson文档
+//
+func newEntityDocument(entity interface{}) (bson.D, error) {
+	data, err := bson.Marshal(entity)
+	if err != nil {
+		return nil, err
+	}
+	doc := bson.D{}
+	if err := bson.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+//
+//  getBsonFieldName
+//  @Description: 获取实体字段保存在Mongo中的名称，与驱动编码时一致：bson标签中的名称，没有时为小写的字段名
+//  @param entity 实体
+//  @param name 字段名、json名称或蛇形名称
+//  @return string 没有找到字段时返回 AsFieldName(name)
+//
+func getBsonFieldName(entity interface{}, name string) string {
+	t := reflect.TypeOf(entity)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return AsFieldName(name)
+	}
+	field, ok := findMaskField(t, name)
+	if !ok {
+		return AsFieldName(name)
+	}
+	if bsonName := strings.Split(field.Tag.Get("bson"), ",")[0]; len(bsonName) > 0 {
+		return bsonName
+	}
+	return strings.ToLower(field.Name)
+}
+
+//
+//  newBulkWriteItems
+//  @Description: 将BulkWrite的错误转换为每一项的执行结果
+//  @param ids 实体id列表，与写入模型一一对应
+//  @param err BulkWrite返回的错误
+//  @param ordered 是否有序执行，有序时第一个失败项之后的项未执行
+//  @return []*ddd_repository.SetManyItem
+//  @return error 非单项错误，如写关注错误或连接错误
+//
+func newBulkWriteItems(ids []string, err error, ordered bool) ([]*ddd_repository.SetManyItem, error) {
+	items := ddd_repository.NewSetManyItems(ids)
+	if err == nil {
+		return items, nil
+	}
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) {
+		return items, err
+	}
+	firstFailed := -1
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Index < 0 || writeErr.Index >= len(items) {
+			continue
+		}
+		items[writeErr.Index].Error = errors.New(fmt.Sprintf("code %d: %s", writeErr.Code, writeErr.Message))
+		if firstFailed < 0 || writeErr.Index < firstFailed {
+			firstFailed = writeErr.Index
+		}
+	}
+	if ordered && firstFailed >= 0 {
+		for _, item := range items[firstFailed+1:] {
+			if item.Error == nil {
+				item.Error = ddd_repository.ErrNotExecuted
+			}
+		}
+	}
+	if bulkErr.WriteConcernError != nil {
+		return items, bulkErr.WriteConcernError
+	}
+	return items, nil
+}
+
+//
+//  getProjection
+//  @Description: 按返回字段生成投影，字段按实体类型校验
+//
+func (r *Repository[T]) getProjection(fields string) (bson.D, error) {
+	if len(strings.TrimSpace(fields)) == 0 {
+		return nil, nil
+	}
+	return newProjection(r.NewEntity(), fields)
+}
+
+func (r *Repository[T]) getSort(sort string) (bson.D, error) {
 	if len(sort) == 0 {
 		return nil, nil
 	}
 	//name:desc,id:asc
-	res := map[string]interface{}{}
+	res := bson.D{}
 	list := strings.Split(sort, ",")
 	for _, s := range list {
 		sortItem := strings.Split(s, ":")
@@ -295,7 +948,7 @@ func (r *Repository[T]) getSort(sort string) (map[string]interface{}, error) {
 		if oerr != nil {
 			return nil, oerr
 		}
-		res[name] = orderVal
+		res = append(res, bson.E{Key: name, Value: orderVal})
 	}
 	return res, nil
 }
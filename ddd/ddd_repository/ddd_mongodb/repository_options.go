@@ -0,0 +1,65 @@
+package ddd_mongodb
+
+//
+// RepositoryOptions
+// @Description: Mongo仓储设置
+//
+type RepositoryOptions struct {
+	SoftDelete *bool           // 是否启用软删除，启用后删除操作只设置删除标记与删除时间
+	Indexes    []*Index        // 声明的索引，与实体字段 index 标签声明的索引一起由 EnsureIndexes 创建
+	Tenancy    TenancyStrategy // 租户隔离策略，默认所有租户共用一个集合
+}
+
+func NewRepositoryOptions() *RepositoryOptions {
+	return &RepositoryOptions{}
+}
+
+func (o *RepositoryOptions) SetSoftDelete(softDelete bool) *RepositoryOptions {
+	o.SoftDelete = &softDelete
+	return o
+}
+
+func (o *RepositoryOptions) GetSoftDelete() bool {
+	if o.SoftDelete == nil {
+		return false
+	}
+	return *o.SoftDelete
+}
+
+func (o *RepositoryOptions) AddIndexes(indexes ...*Index) *RepositoryOptions {
+	o.Indexes = append(o.Indexes, indexes...)
+	return o
+}
+
+func (o *RepositoryOptions) GetIndexes() []*Index {
+	return o.Indexes
+}
+
+func (o *RepositoryOptions) SetTenancy(tenancy TenancyStrategy) *RepositoryOptions {
+	o.Tenancy = tenancy
+	return o
+}
+
+func (o *RepositoryOptions) GetTenancy() TenancyStrategy {
+	if o.Tenancy == nil {
+		return NewSharedTenancy()
+	}
+	return o.Tenancy
+}
+
+func MergeRepositoryOptions(opts ...*RepositoryOptions) *RepositoryOptions {
+	res := &RepositoryOptions{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if o.SoftDelete != nil {
+			res.SoftDelete = o.SoftDelete
+		}
+		if o.Tenancy != nil {
+			res.Tenancy = o.Tenancy
+		}
+		res.Indexes = append(res.Indexes, o.Indexes...)
+	}
+	return res
+}
@@ -15,7 +15,7 @@ func Test_Search(t *testing.T) {
 	mongodb, coll := newCollection("test_users")
 	repository := newRepository(mongodb, coll)
 	objId := NewObjectID()
-	id := objId.String()
+	id := string(objId)
 	user := &User{
 		Id:        id,
 		TenantId:  "001",
@@ -34,10 +34,9 @@ func Test_Search(t *testing.T) {
 		return err
 	})
 
-	search := &ddd_repository.FindPagingQuery{
-		TenantId: "001",
-		Filter:   fmt.Sprintf("id=='%s'", id),
-	}
+	search := ddd_repository.NewFindPagingQuery()
+	search.SetTenantId("001")
+	search.SetFilter(fmt.Sprintf("id=='%s'", id))
 
 	_ = repository.FindPaging(ctx, search).OnSuccess(func(data *[]*User) error {
 		println(data)
@@ -58,7 +57,7 @@ func TestMongoSession_UseTransaction(t *testing.T) {
 	repository := newRepository(mongodb, coll)
 	err := ddd_repository.StartSession(context.Background(), NewSession(mongodb), func(ctx context.Context) error {
 		for i := 0; i < 5; i++ {
-			id := NewObjectID().String()
+			id := string(NewObjectID())
 			user := &User{
 				Id:        id,
 				TenantId:  "001",
@@ -134,7 +134,7 @@ func (m *MongoProcess) OnEquals(name string, value interface{}, rValue rsql.Valu
 }
 
 func (m *MongoProcess) OnNotEquals(name string, value interface{}, rValue rsql.Value) {
-	m.current.addChildItem(name, bson.D{{"$ne", rsql.GetValue(rValue)}})
+	m.current.addChildItem(name, bson.D{{Key: "$ne", Value: rsql.GetValue(rValue)}})
 }
 
 func (m *MongoProcess) OnLike(name string, value interface{}, rValue rsql.Value) {
@@ -143,23 +143,23 @@ func (m *MongoProcess) OnLike(name string, value interface{}, rValue rsql.Value)
 }
 
 func (m *MongoProcess) OnNotLike(name string, value interface{}, rValue rsql.Value) {
-	m.current.addChildItem(name, bson.D{{"$lt", rsql.GetValue(rValue)}})
+	m.current.addChildItem(name, bson.D{{Key: "$lt", Value: rsql.GetValue(rValue)}})
 }
 
 func (m *MongoProcess) OnGreaterThan(name string, value interface{}, rValue rsql.Value) {
-	m.current.addChildItem(name, bson.D{{"$gt", rsql.GetValue(rValue)}})
+	m.current.addChildItem(name, bson.D{{Key: "$gt", Value: rsql.GetValue(rValue)}})
 }
 
 func (m *MongoProcess) OnGreaterThanOrEquals(name string, value interface{}, rValue rsql.Value) {
-	m.current.addChildItem(name, bson.D{{"$gte", rsql.GetValue(rValue)}})
+	m.current.addChildItem(name, bson.D{{Key: "$gte", Value: rsql.GetValue(rValue)}})
 }
 
 func (m *MongoProcess) OnLessThan(name string, value interface{}, rValue rsql.Value) {
-	m.current.addChildItem(name, bson.D{{"$lt", rsql.GetValue(rValue)}})
+	m.current.addChildItem(name, bson.D{{Key: "$lt", Value: rsql.GetValue(rValue)}})
 }
 
 func (m *MongoProcess) OnLessThanOrEquals(name string, value interface{}, rValue rsql.Value) {
-	m.current.addChildItem(name, bson.D{{"$lte", rsql.GetValue(rValue)}})
+	m.current.addChildItem(name, bson.D{{Key: "$lte", Value: rsql.GetValue(rValue)}})
 }
 
 func (m *MongoProcess) OnIn(name string, value interface{}, rValue rsql.Value) {
@@ -173,3 +173,15 @@ func (m *MongoProcess) OnNotIn(name string, value interface{}, rValue rsql.Value
 	values := rsql.GetValueList(listValue)
 	m.current.addChildItem(name, bson.M{"$nin": values})
 }
+
+//
+// OnSearch
+// @Description: 全文检索，转换为 $text 条件。Mongo 在集合的全文索引包含的所有字段中检索，name 只用于表达式可读。
+// 过滤条件中最多一个 =search=，且只能以 and 与其它条件组合，否则查询时返回 *ddd_errors.VerifyError
+// @param name 字段名称
+// @param value 检索内容，多个词以空格分隔，任一词匹配即可，双引号内为短语
+// @param rValue
+//
+func (m *MongoProcess) OnSearch(name string, value interface{}, rValue rsql.Value) {
+	m.current.addChildItem(TextField, bson.M{"$search": fmt.Sprintf("%v", rsql.GetValue(rValue))})
+}
@@ -2,6 +2,7 @@ package ddd_mongodb
 
 import (
 	"context"
+	"errors"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
 	"go.mongodb.org/mongo-driver/mongo"
 )
@@ -14,7 +15,18 @@ func NewSession(db *MongoDB) ddd_repository.Session {
 	return &MongoSession{mongodb: db}
 }
 
+//
+// UseTransaction
+// @Description: 在Mongo事务中执行，dbFunc 返回错误时回滚。ctx中已有会话时加入当前事务
+// @receiver r
+// @param ctx
+// @param dbFunc
+// @return error
+//
 func (r *MongoSession) UseTransaction(ctx context.Context, dbFunc ddd_repository.SessionFunc) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return dbFunc(ctx)
+	}
 	return r.mongodb.client.UseSession(ctx, func(sCtx mongo.SessionContext) error {
 		if err := sCtx.StartTransaction(); err != nil {
 			return err
@@ -30,3 +42,18 @@ func (r *MongoSession) UseTransaction(ctx context.Context, dbFunc ddd_repository
 		return err
 	})
 }
+
+//
+// IsTransientError
+// @Description: 带有 TransientTransactionError 标签的错误可以重试整个事务
+// @receiver r
+// @param err
+// @return bool
+//
+func (r *MongoSession) IsTransientError(err error) bool {
+	var serverError mongo.ServerError
+	if errors.As(err, &serverError) {
+		return serverError.HasErrorLabel("TransientTransactionError")
+	}
+	return false
+}
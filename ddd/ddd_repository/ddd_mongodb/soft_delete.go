@@ -0,0 +1,154 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"time"
+)
+
+const (
+	DeletedField     = "is_deleted"   // 软删除标记字段
+	DeletedTimeField = "deleted_time" // 软删除时间字段
+)
+
+var errSoftDeleteDisabled = errors.New("soft delete is not enabled for this repository")
+
+//
+// IsSoftDelete
+// @Description: 仓储是否启用软删除
+// @return bool
+//
+func (r *Repository[T]) IsSoftDelete() bool {
+	return r.options.GetSoftDelete()
+}
+
+//
+// Restore
+// @Description: 恢复已软删除的实体
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param id 实体id
+// @param opts 设置选项
+// @return *ddd_repository.SetResult[T]
+//
+func (r *Repository[T]) Restore(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	if !r.IsSoftDelete() {
+		return ddd_repository.NewSetResultError[T](errSoftDeleteDisabled)
+	}
+	return r.DoSet(func() (T, error) {
+		var result T
+		objId, err := GetObjectID(id)
+		if err != nil {
+			return result, err
+		}
+		filter := bson.D{
+			{Key: TenantIdField, Value: tenantId},
+			{Key: IdField, Value: objId},
+			{Key: DeletedField, Value: true},
+		}
+		update := bson.M{
+			"$set":   bson.M{DeletedField: false},
+			"$unset": bson.M{DeletedTimeField: ""},
+		}
+		collection, err := r.getWriteCollection(tenantId, opts...)
+		if err != nil {
+			return result, err
+		}
+		ctx, cancel := getWriteContext(ctx, opts...)
+		defer cancel()
+		// 恢复不能新建数据
+		_, err = collection.UpdateOne(ctx, filter, update, getUpdateOptions(opts...).SetUpsert(false))
+		return result, err
+	})
+}
+
+//
+// PurgeDeleted
+// @Description: 物理删除已软删除的数据
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param deletedBefore 只删除在此时间之前软删除的数据，为零值时删除所有已软删除的数据
+// @return int64 删除的数量
+// @return error
+//
+func (r *Repository[T]) PurgeDeleted(ctx context.Context, tenantId string, deletedBefore time.Time) (int64, error) {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return 0, err
+	}
+	if !r.IsSoftDelete() {
+		return 0, errSoftDeleteDisabled
+	}
+	filter := bson.D{
+		{Key: TenantIdField, Value: tenantId},
+		{Key: DeletedField, Value: true},
+	}
+	if !deletedBefore.IsZero() {
+		filter = append(filter, bson.E{Key: DeletedTimeField, Value: bson.M{"$lt": deletedBefore}})
+	}
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return 0, err
+	}
+	result, err := collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+//
+//  getNotDeletedFilter
+//  @Description: 排除已软删除数据的过滤条件，未启用软删除或需要包含已删除数据时返回false
+//
+func (r *Repository[T]) getNotDeletedFilter(includeDeleted bool) (bson.E, bool) {
+	if !r.IsSoftDelete() || includeDeleted {
+		return bson.E{}, false
+	}
+	return bson.E{Key: DeletedField, Value: bson.M{"$ne": true}}, true
+}
+
+//
+//  addNotDeletedFilter
+//  @Description: 启用软删除时加入排除已删除数据的条件。过滤条件中已有软删除字段的条件时用 $and 合并，不覆盖原条件
+//
+func (r *Repository[T]) addNotDeletedFilter(filter map[string]interface{}, includeDeleted bool) map[string]interface{} {
+	notDeleted, ok := r.getNotDeletedFilter(includeDeleted)
+	if !ok {
+		return filter
+	}
+	if _, exists := filter[notDeleted.Key]; !exists {
+		filter[notDeleted.Key] = notDeleted.Value
+		return filter
+	}
+	return map[string]interface{}{"$and": []interface{}{filter, map[string]interface{}{notDeleted.Key: notDeleted.Value}}}
+}
+
+//
+//  addNotDeletedFilterD
+//  @Description: 同 addNotDeletedFilter，用于 bson.D 格式的过滤条件
+//
+func (r *Repository[T]) addNotDeletedFilterD(filter bson.D, includeDeleted bool) bson.D {
+	notDeleted, ok := r.getNotDeletedFilter(includeDeleted)
+	if !ok {
+		return filter
+	}
+	for _, e := range filter {
+		if e.Key == notDeleted.Key {
+			return bson.D{{Key: "$and", Value: bson.A{filter, bson.D{notDeleted}}}}
+		}
+	}
+	return append(filter, notDeleted)
+}
+
+func newSoftDeleteUpdate() bson.M {
+	return bson.M{"$set": bson.M{
+		DeletedField:     true,
+		DeletedTimeField: time.Now(),
+	}}
+}
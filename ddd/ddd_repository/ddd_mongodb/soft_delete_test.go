@@ -0,0 +1,68 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+	"time"
+)
+
+func TestRepository_NewFilter_SoftDelete(t *testing.T) {
+	repos := NewRepository[*User](func() *User { return &User{} }, nil, nil, NewRepositoryOptions().SetSoftDelete(true))
+	assert.True(t, repos.IsSoftDelete())
+	filter := repos.NewFilter("001", map[string]interface{}{"userName": "lxd"})
+	assert.Equal(t, bson.D{
+		{Key: TenantIdField, Value: "001"},
+		{Key: "user_name", Value: "lxd"},
+		{Key: DeletedField, Value: bson.M{"$ne": true}},
+	}, filter)
+
+	includeDeleted := ddd_repository.MergeFindOptions(ddd_repository.NewFindOptions().SetIncludeDeleted(true)).GetIncludeDeleted()
+	filter = repos.newFilter("001", nil, includeDeleted)
+	assert.Equal(t, bson.D{{Key: TenantIdField, Value: "001"}}, filter)
+
+	repos = NewRepository[*User](func() *User { return &User{} }, nil, nil)
+	assert.False(t, repos.IsSoftDelete())
+	assert.Equal(t, bson.D{{Key: TenantIdField, Value: "001"}}, repos.NewFilter("001", nil))
+	_, err := repos.PurgeDeleted(context.Background(), "001", time.Time{})
+	assert.Error(t, err)
+	assert.Error(t, repos.Restore(context.Background(), "001", "1").GetError())
+}
+
+func TestRepository_addNotDeletedFilter(t *testing.T) {
+	repos := NewRepository[*User](func() *User { return &User{} }, nil, nil, NewRepositoryOptions().SetSoftDelete(true))
+	filter := repos.addNotDeletedFilter(map[string]interface{}{TenantIdField: "001"}, false)
+	assert.Equal(t, map[string]interface{}{TenantIdField: "001", DeletedField: bson.M{"$ne": true}}, filter)
+
+	// 已有软删除字段的条件时用 $and 合并
+	filter = repos.addNotDeletedFilter(map[string]interface{}{TenantIdField: "001", DeletedField: true}, false)
+	assert.Equal(t, map[string]interface{}{"$and": []interface{}{
+		map[string]interface{}{TenantIdField: "001", DeletedField: true},
+		map[string]interface{}{DeletedField: bson.M{"$ne": true}},
+	}}, filter)
+	filter = repos.addNotDeletedFilter(map[string]interface{}{TenantIdField: "001", DeletedField: true}, true)
+	assert.Equal(t, map[string]interface{}{TenantIdField: "001", DeletedField: true}, filter)
+
+	filterD := repos.NewFilter("001", map[string]interface{}{DeletedField: true})
+	assert.Equal(t, bson.D{{Key: "$and", Value: bson.A{
+		bson.D{{Key: TenantIdField, Value: "001"}, {Key: DeletedField, Value: true}},
+		bson.D{{Key: DeletedField, Value: bson.M{"$ne": true}}},
+	}}}, filterD)
+}
+
+func TestRepository_Update_SoftDelete(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("update", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().SetSoftDelete(true))
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+		assert.NoError(t, repos.Update(context.Background(), &User{Id: "1", TenantId: "001"}).GetError())
+		cmd := mt.GetStartedEvent().Command
+		update := cmd.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, true, update.Lookup("q", DeletedField, "$ne").Boolean())
+	})
+}
@@ -0,0 +1,137 @@
+package ddd_mongodb
+
+import (
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+)
+
+//
+// TenancyStrategy
+// @Description: 租户隔离策略，按租户id确定仓储每次操作使用的集合
+//
+type TenancyStrategy interface {
+	//
+	// GetCollection
+	// @Description: 获取租户的集合
+	// @param collection 仓储的集合，隔离的集合按它的名称与所在数据库生成
+	// @param tenantId 租户id
+	// @param opts 集合设置
+	// @return *mongo.Collection
+	// @return error 租户id不能用于集合或数据库名称
+	//
+	GetCollection(collection *mongo.Collection, tenantId string, opts ...*options.CollectionOptions) (*mongo.Collection, error)
+}
+
+// 租户id用于集合或数据库名称时允许的字符
+var tenantNameRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Mongo数据库名称的最大长度
+const maxDatabaseNameLength = 63
+
+type sharedTenancy struct {
+}
+
+type collectionTenancy struct {
+	separator string
+}
+
+type databaseTenancy struct {
+	separator string
+}
+
+//
+// NewSharedTenancy
+// @Description: 所有租户共用仓储的集合，按 tenant_id 字段区分，为默认策略
+// @return TenancyStrategy
+//
+func NewSharedTenancy() TenancyStrategy {
+	return &sharedTenancy{}
+}
+
+//
+// NewCollectionTenancy
+// @Description: 每个租户使用独立的集合，集合名称为 <集合名称>_<租户id>，与仓储的集合在同一数据库
+// @return TenancyStrategy
+//
+func NewCollectionTenancy() TenancyStrategy {
+	return &collectionTenancy{separator: "_"}
+}
+
+//
+// NewDatabaseTenancy
+// @Description: 每个租户使用独立的数据库，数据库名称为 <数据库名称>_<租户id>，集合名称与仓储的集合相同
+// @return TenancyStrategy
+//
+func NewDatabaseTenancy() TenancyStrategy {
+	return &databaseTenancy{separator: "_"}
+}
+
+func (s *sharedTenancy) GetCollection(collection *mongo.Collection, tenantId string, opts ...*options.CollectionOptions) (*mongo.Collection, error) {
+	return collection, nil
+}
+
+func (s *collectionTenancy) GetCollection(collection *mongo.Collection, tenantId string, opts ...*options.CollectionOptions) (*mongo.Collection, error) {
+	if err := checkTenantName(tenantId); err != nil {
+		return nil, err
+	}
+	name := collection.Name() + s.separator + tenantId
+	return collection.Database().Collection(name, opts...), nil
+}
+
+func (s *databaseTenancy) GetCollection(collection *mongo.Collection, tenantId string, opts ...*options.CollectionOptions) (*mongo.Collection, error) {
+	if err := checkTenantName(tenantId); err != nil {
+		return nil, err
+	}
+	database := collection.Database()
+	name := database.Name() + s.separator + tenantId
+	if len(name) > maxDatabaseNameLength {
+		return nil, errors.New(fmt.Sprintf("database name %s is too long", name))
+	}
+	return database.Client().Database(name).Collection(collection.Name(), opts...), nil
+}
+
+//
+//  checkTenantName
+//  @Description: 检查租户id是否可以用于集合或数据库名称
+//
+func checkTenantName(tenantId string) error {
+	if !tenantNameRegexp.MatchString(tenantId) {
+		return errors.New(fmt.Sprintf("tenantId %s can not be used as a collection or database name", tenantId))
+	}
+	return nil
+}
+
+//
+// GetCollection
+// @Description: 按租户隔离策略获取租户的集合
+// @param tenantId 租户id
+// @return *mongo.Collection
+// @return error
+//
+func (r *Repository[T]) GetCollection(tenantId string) (*mongo.Collection, error) {
+	var opts []*options.CollectionOptions
+	if r.mongodb != nil && r.mongodb.collectionOptions != nil {
+		opts = append(opts, r.mongodb.collectionOptions)
+	}
+	return r.options.GetTenancy().GetCollection(r.collection, tenantId, opts...)
+}
+
+func (r *Repository[T]) getReadCollection(tenantId string, opts ...*ddd_repository.FindOptions) (*mongo.Collection, error) {
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	return getReadCollection(collection, opts...)
+}
+
+func (r *Repository[T]) getWriteCollection(tenantId string, opts ...*ddd_repository.SetOptions) (*mongo.Collection, error) {
+	collection, err := r.GetCollection(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	return getWriteCollection(collection, opts...)
+}
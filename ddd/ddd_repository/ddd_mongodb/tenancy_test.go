@@ -0,0 +1,64 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+)
+
+func TestRepository_Tenancy(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+	user := &User{Id: "1", TenantId: "001", UserName: "lxd"}
+
+	mt.Run("shared", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		collection, err := repos.GetCollection("001")
+		assert.NoError(t, err)
+		assert.Same(t, mt.Coll, collection)
+	})
+
+	mt.Run("collection per tenant", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().SetTenancy(NewCollectionTenancy()))
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		assert.NoError(t, repos.Insert(ctx, user).GetError())
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, mt.Coll.Name()+"_001", cmd.Lookup("insert").StringValue())
+		assert.Equal(t, mt.Coll.Database().Name(), cmd.Lookup("$db").StringValue())
+	})
+
+	mt.Run("database per tenant", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().SetTenancy(NewDatabaseTenancy()))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.Coll.Database().Name()+"_001."+mt.Coll.Name(), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "1"}, {Key: "tenantId", Value: "001"}}))
+		_, ok, err := repos.FindById(ctx, "001", "1").Result()
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, mt.Coll.Name(), cmd.Lookup("find").StringValue())
+		assert.Equal(t, mt.Coll.Database().Name()+"_001", cmd.Lookup("$db").StringValue())
+		assert.Equal(t, "001", cmd.Lookup("filter", TenantIdField).StringValue())
+	})
+
+	mt.Run("invalid tenant", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().SetTenancy(NewCollectionTenancy()))
+		assert.Error(t, repos.FindAll(ctx, "a.b").GetError())
+		_, err := repos.Count(ctx, "a/b", "")
+		assert.Error(t, err)
+	})
+
+	mt.Run("bulk write across tenants", func(mt *mtest.T) {
+		other := &User{Id: "2", TenantId: "002", UserName: "abc"}
+		repos := newMockUserRepository(mt, NewRepositoryOptions().SetTenancy(NewCollectionTenancy()))
+		assert.Error(t, repos.InsertMany(ctx, []*User{user, other}).GetError())
+
+		repos = newMockUserRepository(mt)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}))
+		assert.NoError(t, repos.InsertMany(ctx, []*User{user, other}).GetError())
+	})
+}
@@ -0,0 +1,108 @@
+package ddd_mongodb
+
+import (
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	TextField      = "$text"
+	TextScoreField = "score" // 按相关度排序时的排序键，不保存在数据中
+)
+
+//
+//  hasTextSearch
+//  @Description: 过滤条件中是否包含全文检索
+//
+func hasTextSearch(filter interface{}) bool {
+	switch v := filter.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if key == TextField || hasTextSearch(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if hasTextSearch(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//
+//  getTextScoreSort
+//  @Description: 按相关度降序排序，相关度相同时按 _id 排序
+//
+func getTextScoreSort() bson.D {
+	return bson.D{
+		{Key: TextScoreField, Value: bson.M{"$meta": "textScore"}},
+		{Key: IdField, Value: 1},
+	}
+}
+
+//
+//  checkTextSearch
+//  @Description: 使用全文检索时，仓储必须声明全文索引。Mongo 每个集合只能有一个全文索引，
+//  过滤条件中最多一个 =search=，且只能以 and 与其它条件组合
+//  @param filter 过滤条件
+//  @return error =search= 多于一个或在 or 中时返回 *ddd_errors.VerifyError
+//
+func (r *Repository[T]) checkTextSearch(filter map[string]interface{}) error {
+	if !hasTextSearch(filter) {
+		return nil
+	}
+	count, nested := countTextSearch(filter, true)
+	verifyError := ddd_errors.NewVerifyError()
+	if count > 1 {
+		verifyError.AppendField("filter", "rsql =search= can be used only once")
+	}
+	if nested {
+		verifyError.AppendField("filter", "rsql =search= can only be combined with other conditions by and")
+	}
+	if err := verifyError.GetError(); err != nil {
+		return err
+	}
+	indexes, err := r.GetIndexes()
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index.Text {
+			return nil
+		}
+	}
+	return errors.New("rsql =search= requires a text index declared on the repository")
+}
+
+//
+//  countTextSearch
+//  @Description: 统计过滤条件中的 $text 条件
+//  @param filter 过滤条件
+//  @param topLevel 是否只经过顶层的 $and
+//  @return count $text 条件的数量
+//  @return nested 是否有 $text 不在顶层的 $and 中
+//
+func countTextSearch(filter interface{}, topLevel bool) (count int, nested bool) {
+	switch v := filter.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if key == TextField {
+				count++
+				nested = nested || !topLevel
+				continue
+			}
+			c, n := countTextSearch(item, topLevel && key == "$and")
+			count, nested = count+c, nested || n
+		}
+	case []interface{}:
+		for _, item := range v {
+			c, n := countTextSearch(item, topLevel)
+			count, nested = count+c, nested || n
+		}
+	}
+	return count, nested
+}
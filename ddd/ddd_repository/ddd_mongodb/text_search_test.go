@@ -0,0 +1,78 @@
+package ddd_mongodb
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"testing"
+)
+
+func TestRepository_TextSearch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+	ctx := context.Background()
+	newQuery := func(filter string, sort string) ddd_repository.FindPagingQuery {
+		query := ddd_repository.NewFindPagingQuery()
+		query.SetTenantId("t1")
+		query.SetFilter(filter)
+		query.SetSort(sort)
+		query.SetPageSize(10)
+		query.SetPageNum(1)
+		query.SetIsTotalRows(false)
+		return query
+	}
+
+	mt.Run("sort by score", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().AddIndexes(NewTextIndex("userName", "address")))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mockNamespace(mt), mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: "1"}, {Key: "userName", Value: "lee"}}))
+		res := repos.FindPaging(ctx, newQuery("userName=search='lee \"beijing road\"' and email=='a@b.c'", ""))
+		if !assert.NoError(t, res.GetError()) {
+			return
+		}
+		assert.Len(t, *res.GetData(), 1)
+		assert.Empty(t, res.ContinuationToken)
+
+		cmd := mt.GetStartedEvent().Command
+		assert.Equal(t, `lee "beijing road"`, cmd.Lookup("filter", "$and", "0", "$and", "0", "$text", "$search").StringValue())
+		sort := cmd.Lookup("sort").Document()
+		assert.Equal(t, "textScore", sort.Lookup(TextScoreField, "$meta").StringValue())
+		assert.Equal(t, int32(1), sort.Lookup(IdField).Int32())
+		assert.Equal(t, int64(10), cmd.Lookup("skip").Int64())
+	})
+
+	mt.Run("explicit sort", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().AddIndexes(NewTextIndex("userName")))
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mockNamespace(mt), mtest.FirstBatch))
+		assert.NoError(t, repos.FindPaging(ctx, newQuery("userName=search='lee'", "userName:desc")).GetError())
+		sort := mt.GetStartedEvent().Command.Lookup("sort").Document()
+		assert.Equal(t, int32(-1), sort.Lookup("userName").Int32())
+		_, err := sort.LookupErr(TextScoreField)
+		assert.Error(t, err)
+	})
+
+	mt.Run("no text index", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt)
+		assert.Error(t, repos.FindPaging(ctx, newQuery("userName=search='lee'", "")).GetError())
+		_, err := repos.FindStream(ctx, "t1", "userName=search='lee'")
+		assert.Error(t, err)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("invalid search", func(mt *mtest.T) {
+		repos := newMockUserRepository(mt, NewRepositoryOptions().AddIndexes(NewTextIndex("userName", "address")))
+		for _, filter := range []string{
+			"userName=search='lee' and address=search='road'",
+			"userName=search='lee' or email=='a@b.c'",
+			"email=='a@b.c' and (userName=search='lee' or address=='road')",
+		} {
+			err := repos.FindPaging(ctx, newQuery(filter, "")).GetError()
+			_, ok := err.(*ddd_errors.VerifyError)
+			assert.True(t, ok, filter)
+		}
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
@@ -0,0 +1,205 @@
+package ddd_mongodb
+
+import (
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//
+//  newMaskSetData
+//  @Description: 按更新掩码生成$set内容，掩码为驼峰格式的字段路径，如 userName、address.city，按 AsFieldName 转换为Mongo字段名
+//  @param entity 实体
+//  @param mask 更新掩码
+//  @return bson.D $set 的内容
+//  @return error 掩码中有未知字段或不可更新字段时返回 *ddd_errors.VerifyError
+//
+func newMaskSetData(entity interface{}, mask []string) (bson.D, error) {
+	verifyError := ddd_errors.NewVerifyError()
+	setData := bson.D{}
+	exists := make(map[string]bool)
+	for _, path := range mask {
+		path = strings.TrimSpace(path)
+		fieldName, value, err := getMaskValue(reflect.ValueOf(entity), path)
+		if err != nil {
+			verifyError.AppendField(path, err.Error())
+			continue
+		}
+		if fieldName == IdField || fieldName == TenantIdField {
+			verifyError.AppendField(path, "field cannot be updated")
+			continue
+		}
+		if exists[fieldName] {
+			continue
+		}
+		exists[fieldName] = true
+		setData = append(setData, bson.E{Key: fieldName, Value: value})
+	}
+	if err := verifyError.GetError(); err != nil {
+		return nil, err
+	}
+	return setData, nil
+}
+
+//
+//  appendMaskSetData
+//  @Description: 将字段追加到$set内容中，已有或不存在的字段被忽略，用于追加自动设置的审计字段
+//  @param setData $set 的内容
+//  @param entity 实体
+//  @param paths 字段路径
+//  @return bson.D
+//
+func appendMaskSetData(setData bson.D, entity interface{}, paths []string) bson.D {
+	exists := make(map[string]bool)
+	for _, e := range setData {
+		exists[e.Key] = true
+	}
+	for _, path := range paths {
+		fieldName, value, err := getMaskValue(reflect.ValueOf(entity), path)
+		if err != nil || exists[fieldName] {
+			continue
+		}
+		exists[fieldName] = true
+		setData = append(setData, bson.E{Key: fieldName, Value: value})
+	}
+	return setData
+}
+
+//
+//  getMaskValue
+//  @Description: 按字段路径获取字段值与Mongo字段名，路径中的空指针对应的值为nil
+//  @param v 实体值
+//  @param path 字段路径，以.分隔
+//  @return string Mongo字段名
+//  @return interface{} 字段值
+//  @return error 字段不存在
+//
+func getMaskValue(v reflect.Value, path string) (string, interface{}, error) {
+	if path == "" {
+		return "", nil, errors.New("field path is empty")
+	}
+	t := v.Type()
+	valid := true
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if name == "" {
+			return "", nil, errors.New("field path is invalid")
+		}
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+			if valid {
+				if v.IsNil() {
+					valid = false
+				} else {
+					v = v.Elem()
+				}
+			}
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := findMaskField(t, name)
+			if !ok {
+				return "", nil, errors.New("field is not exist")
+			}
+			if valid {
+				v, valid = fieldByIndex(v, field.Index)
+			}
+			t = field.Type
+			names[i] = AsFieldName(name)
+		case reflect.Map:
+			if t.Key().Kind() != reflect.String {
+				return "", nil, errors.New("field is not exist")
+			}
+			if valid {
+				v = v.MapIndex(reflect.ValueOf(name).Convert(t.Key()))
+				valid = v.IsValid()
+			}
+			t = t.Elem()
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 {
+				return "", nil, errors.New("field is not exist")
+			}
+			if valid {
+				if index >= v.Len() {
+					valid = false
+				} else {
+					v = v.Index(index)
+				}
+			}
+			t = t.Elem()
+		default:
+			return "", nil, errors.New("field is not exist")
+		}
+		if valid && v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				valid = false
+			} else {
+				v = v.Elem()
+				t = v.Type()
+			}
+		}
+	}
+	fieldName := strings.Join(names, ".")
+	if fieldName == "id" {
+		fieldName = IdField
+	}
+	if !valid {
+		return fieldName, nil, nil
+	}
+	return fieldName, v.Interface(), nil
+}
+
+//
+//  findMaskField
+//  @Description: 按名称查找结构字段，名称可以是字段名、json名称或蛇形名称，匿名结构的字段视为当前结构的字段
+//
+func findMaskField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		bsonName := strings.Split(field.Tag.Get("bson"), ",")[0]
+		if jsonName == "-" || bsonName == "-" {
+			continue
+		}
+		if field.Anonymous && jsonName == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if f, ok := findMaskField(ft, name); ok {
+					f.Index = append(append([]int{}, field.Index...), f.Index...)
+					return f, true
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == jsonName || strings.EqualFold(name, field.Name) || AsFieldName(name) == AsFieldName(field.Name) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
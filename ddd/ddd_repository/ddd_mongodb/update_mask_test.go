@@ -0,0 +1,73 @@
+package ddd_mongodb
+
+import (
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"testing"
+)
+
+type maskAddress struct {
+	City     string `json:"city"`
+	PostCode string `json:"postCode"`
+}
+
+type maskBase struct {
+	Remarks string `json:"remarks"`
+}
+
+type maskCustomer struct {
+	maskBase
+	Id       string            `json:"id"`
+	TenantId string            `json:"tenantId"`
+	UserName string            `json:"userName"`
+	Address  *maskAddress      `json:"address"`
+	Contacts []*maskAddress    `json:"contacts"`
+	Tags     map[string]string `json:"tags"`
+	Secret   string            `json:"-"`
+}
+
+func Test_newMaskSetData(t *testing.T) {
+	customer := &maskCustomer{
+		maskBase: maskBase{Remarks: "vip"},
+		Id:       "1",
+		TenantId: "t1",
+		UserName: "lxd",
+		Address:  &maskAddress{City: "Beijing", PostCode: "100000"},
+		Contacts: []*maskAddress{{City: "Shanghai"}},
+		Tags:     map[string]string{"level": "gold"},
+	}
+	setData, err := newMaskSetData(customer, []string{"userName", "address.city", "address.postCode", "contacts.0.city", "tags.level", "remarks", "userName"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{
+		{Key: "user_name", Value: "lxd"},
+		{Key: "address.city", Value: "Beijing"},
+		{Key: "address.post_code", Value: "100000"},
+		{Key: "contacts.0.city", Value: "Shanghai"},
+		{Key: "tags.level", Value: "gold"},
+		{Key: "remarks", Value: "vip"},
+	}, setData)
+
+	customer.Address = nil
+	setData, err = newMaskSetData(customer, []string{"address.city"})
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "address.city", Value: nil}}, setData)
+}
+
+func Test_newMaskSetData_VerifyError(t *testing.T) {
+	customer := &maskCustomer{Id: "1", TenantId: "t1"}
+	_, err := newMaskSetData(customer, []string{"userName", "unknown", "address.street", "secret", "id", "tenantId", "userName.first"})
+	verifyError, ok := err.(*ddd_errors.VerifyError)
+	assert.True(t, ok)
+	fields := make([]string, 0)
+	for _, e := range verifyError.Errors {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"unknown", "address.street", "secret", "id", "tenantId", "userName.first"}, fields)
+}
+
+func Test_appendMaskSetData(t *testing.T) {
+	customer := &maskCustomer{UserName: "lxd", maskBase: maskBase{Remarks: "vip"}}
+	setData := appendMaskSetData(bson.D{{Key: "user_name", Value: "lxd"}}, customer, []string{"userName", "remarks", "updatedBy"})
+	assert.Equal(t, bson.D{{Key: "user_name", Value: "lxd"}, {Key: "remarks", Value: "vip"}}, setData)
+}
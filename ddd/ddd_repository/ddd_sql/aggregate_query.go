@@ -0,0 +1,174 @@
+package ddd_sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"strings"
+)
+
+//
+// Count
+// @Description: 统计租户下与过滤条件匹配的数量
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件，为空时统计全部
+// @return int64 数量
+// @return error
+//
+func (r *Repository[T]) Count(ctx context.Context, tenantId string, filter string) (int64, error) {
+	where, args, err := r.getFilterWhere(tenantId, filter)
+	if err != nil {
+		return 0, err
+	}
+	return r.count(ctx, where, args...)
+}
+
+//
+// Aggregate
+// @Description: 分组统计，生成 SELECT ... GROUP BY 语句
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param groupBy 分组字段，为空时统计全部数据
+// @param metrics 统计指标
+// @return []*ddd_repository.AggregateRow 按分组字段排序的结果行
+// @return error
+//
+func (r *Repository[T]) Aggregate(ctx context.Context, tenantId string, filter string, groupBy []string, metrics []ddd_repository.Metric) ([]*ddd_repository.AggregateRow, error) {
+	if err := ddd_repository.ValidateMetrics(metrics); err != nil {
+		return nil, err
+	}
+	where, args, err := r.getFilterWhere(tenantId, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	groupColumns := make([]string, len(groupBy))
+	for i, field := range groupBy {
+		col, err := r.getAggregateColumn(field)
+		if err != nil {
+			return nil, err
+		}
+		groupColumns[i] = col
+	}
+	selects := append([]string{}, groupColumns...)
+	for _, metric := range metrics {
+		expr := "COUNT(*)"
+		if metric.Type != ddd_repository.MetricCount {
+			col, err := r.getAggregateColumn(metric.Field)
+			if err != nil {
+				return nil, err
+			}
+			expr = fmt.Sprintf("%s(%s)", strings.ToUpper(string(metric.Type)), col)
+		}
+		selects = append(selects, expr)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(selects, ", "), r.quoteTable(), where)
+	if len(groupColumns) > 0 {
+		columns := strings.Join(groupColumns, ", ")
+		query = query + " GROUP BY " + columns + " ORDER BY " + columns
+	}
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]*ddd_repository.AggregateRow, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(selects))
+		ptrs := make([]interface{}, len(selects))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := ddd_repository.NewAggregateRow()
+		for i, field := range groupBy {
+			row.Group[field] = asScanValue(values[i])
+		}
+		for i, metric := range metrics {
+			row.Metrics[metric.Name] = asScanValue(values[len(groupBy)+i])
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
+
+//
+// Distinct
+// @Description: 获取字段的不重复值
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param field 字段
+// @param filter rsql过滤条件
+// @return []interface{} 排序后的值
+// @return error
+//
+func (r *Repository[T]) Distinct(ctx context.Context, tenantId string, field string, filter string) ([]interface{}, error) {
+	col, err := r.getAggregateColumn(field)
+	if err != nil {
+		return nil, err
+	}
+	where, args, err := r.getFilterWhere(tenantId, filter)
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s ORDER BY %s", col, r.quoteTable(), where, col)
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	res := make([]interface{}, 0)
+	for rows.Next() {
+		var value interface{}
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		res = append(res, asScanValue(value))
+	}
+	return res, rows.Err()
+}
+
+//
+//  getFilterWhere
+//  @Description: 解析 rsql 过滤条件，生成包含租户条件的 WHERE 条件与参数
+//
+func (r *Repository[T]) getFilterWhere(tenantId string, filter string) (string, []interface{}, error) {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return "", nil, err
+	}
+	p := newSqlProcess(r.mapper, r.dialect)
+	if err := rsql.ParseProcess(filter, p); err != nil {
+		return "", nil, err
+	}
+	return p.GetFilter(tenantId)
+}
+
+func (r *Repository[T]) getAggregateColumn(field string) (string, error) {
+	col, ok := r.mapper.getColumn(field)
+	if !ok {
+		return "", errors.New(fmt.Sprintf("field %s does not exist in table %s", field, r.mapper.table))
+	}
+	return r.dialect.Quote(col.name), nil
+}
+
+// 部分驱动以 []byte 返回文本
+func asScanValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
@@ -0,0 +1,80 @@
+package ddd_sql
+
+import (
+	"fmt"
+	"strings"
+)
+
+//
+// Dialect
+// @Description: 数据库方言，处理参数占位符与标识符引用
+//
+type Dialect interface {
+	// Rebind 将语句中的 ? 占位符转换为数据库的占位符
+	Rebind(query string) string
+	// Quote 引用表名或列名
+	Quote(name string) string
+	// LikeEscape LIKE 语句 ESCAPE 子句中反斜杠转义符的字面量
+	LikeEscape() string
+}
+
+var (
+	MySQL    Dialect = &mysqlDialect{}
+	Postgres Dialect = &postgresDialect{}
+	SQLite   Dialect = &sqliteDialect{}
+)
+
+type mysqlDialect struct {
+}
+
+func (d *mysqlDialect) Rebind(query string) string {
+	return query
+}
+
+func (d *mysqlDialect) Quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func (d *mysqlDialect) LikeEscape() string {
+	return `'\\'`
+}
+
+type postgresDialect struct {
+}
+
+func (d *postgresDialect) Rebind(query string) string {
+	sb := strings.Builder{}
+	index := 0
+	for _, c := range query {
+		if c == '?' {
+			index++
+			sb.WriteString(fmt.Sprintf("$%d", index))
+			continue
+		}
+		sb.WriteRune(c)
+	}
+	return sb.String()
+}
+
+func (d *postgresDialect) Quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d *postgresDialect) LikeEscape() string {
+	return `'\'`
+}
+
+type sqliteDialect struct {
+}
+
+func (d *sqliteDialect) Rebind(query string) string {
+	return query
+}
+
+func (d *sqliteDialect) Quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (d *sqliteDialect) LikeEscape() string {
+	return `'\'`
+}
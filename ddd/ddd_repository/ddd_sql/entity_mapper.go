@@ -0,0 +1,369 @@
+package ddd_sql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/utils/stringutils"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	IdColumn       = "id"
+	TenantIdColumn = "tenant_id"
+	tagName        = "db"
+)
+
+// TableNamer 实体实现此接口时，使用其返回值作为表名
+type TableNamer interface {
+	TableName() string
+}
+
+//
+// columnInfo
+// @Description: 实体字段与数据库列的映射
+//
+type columnInfo struct {
+	name     string // 列名
+	field    string // 结构体字段名
+	jsonName string // json名称
+	index    []int
+	isJson   bool // 非基本类型的字段以json文本保存
+}
+
+//
+// entityMapper
+// @Description: 实体结构体与数据表的映射。列名取自 db 标签，没有时使用字段名称的 snake_case 形式
+//
+type entityMapper struct {
+	table   string
+	columns []*columnInfo
+	byName  map[string]*columnInfo
+}
+
+var mappers = sync.Map{}
+
+var timeType = reflect.TypeOf(time.Time{})
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+func getEntityMapper(entity interface{}, table string) (*entityMapper, error) {
+	t := reflect.TypeOf(entity)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, errors.New(fmt.Sprintf("entity %v is not struct", t))
+	}
+	key := t.PkgPath() + "." + t.Name() + "/" + table
+	if m, ok := mappers.Load(key); ok {
+		return m.(*entityMapper), nil
+	}
+	if len(table) == 0 {
+		if namer, ok := entity.(TableNamer); ok {
+			table = namer.TableName()
+		} else {
+			table = stringutils.SnakeString(t.Name())
+		}
+	}
+	m := &entityMapper{
+		table:   table,
+		columns: make([]*columnInfo, 0),
+		byName:  make(map[string]*columnInfo),
+	}
+	m.addFields(t, nil)
+	if _, ok := m.byName[IdColumn]; !ok {
+		return nil, errors.New(fmt.Sprintf("entity %s has no %s column", t.Name(), IdColumn))
+	}
+	mappers.Store(key, m)
+	return m, nil
+}
+
+func (m *entityMapper) addFields(t reflect.Type, parentIndex []int) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
+		tag := f.Tag.Get(tagName)
+		if tag == "-" || !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && len(tag) == 0 && f.Type.Kind() == reflect.Struct {
+			m.addFields(f.Type, index)
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if len(name) == 0 {
+			name = stringutils.SnakeString(f.Name)
+		}
+		jsonName := strings.Split(f.Tag.Get("json"), ",")[0]
+		if len(jsonName) == 0 || jsonName == "-" {
+			jsonName = f.Name
+		}
+		col := &columnInfo{
+			name:     name,
+			field:    f.Name,
+			jsonName: jsonName,
+			index:    index,
+			isJson:   isJsonType(f.Type),
+		}
+		m.columns = append(m.columns, col)
+		m.byName[name] = col
+	}
+}
+
+//
+//  getColumn
+//  @Description: 按列名、字段名称或json名称获取列，忽略大小写与下划线，_id 等同于 id
+//
+func (m *entityMapper) getColumn(name string) (*columnInfo, bool) {
+	if name == "_id" {
+		name = IdColumn
+	}
+	if col, ok := m.byName[name]; ok {
+		return col, true
+	}
+	key := normalizeName(name)
+	for _, col := range m.columns {
+		if normalizeName(col.name) == key || normalizeName(col.field) == key || normalizeName(col.jsonName) == key {
+			return col, true
+		}
+	}
+	return nil, false
+}
+
+func (m *entityMapper) columnNames() []string {
+	names := make([]string, len(m.columns))
+	for i, col := range m.columns {
+		names[i] = col.name
+	}
+	return names
+}
+
+//
+//  values
+//  @Description: 获取实体各列的值
+//
+func (m *entityMapper) values(entity interface{}) ([]interface{}, error) {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	res := make([]interface{}, len(m.columns))
+	for i, col := range m.columns {
+		fv, ok := fieldByIndex(v, col.index)
+		if !ok {
+			res[i] = nil
+			continue
+		}
+		value, err := toDbValue(fv, col.isJson)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = value
+	}
+	return res, nil
+}
+
+//
+//  scan
+//  @Description: 将查询结果写入实体
+//
+func (m *entityMapper) scan(entity interface{}, columns []string, values []interface{}) error {
+	v := reflect.Indirect(reflect.ValueOf(entity))
+	for i, name := range columns {
+		col, ok := m.byName[name]
+		if !ok {
+			continue
+		}
+		fv := fieldByIndexAlloc(v, col.index)
+		if err := setFieldValue(fv, values[i], col.isJson); err != nil {
+			return errors.New(fmt.Sprintf("column %s: %s", name, err.Error()))
+		}
+	}
+	return nil
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", ""))
+}
+
+func isJsonType(t reflect.Type) bool {
+	if t.Implements(valuerType) || reflect.PtrTo(t).Implements(valuerType) {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return !(t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+	}
+	return false
+}
+
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for _, i := range index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v
+}
+
+func toDbValue(v reflect.Value, isJson bool) (interface{}, error) {
+	if v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface || v.Kind() == reflect.Map || v.Kind() == reflect.Slice {
+		if v.IsNil() {
+			return nil, nil
+		}
+	}
+	if isJson {
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return nil, err
+		}
+		return string(data), nil
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	}
+	return v.Interface(), nil
+}
+
+func setFieldValue(field reflect.Value, value interface{}, isJson bool) error {
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if isJson {
+		var data []byte
+		switch s := value.(type) {
+		case string:
+			data = []byte(s)
+		case []byte:
+			data = s
+		default:
+			return errors.New(fmt.Sprintf("can not convert %T to json", value))
+		}
+		return json.Unmarshal(data, field.Addr().Interface())
+	}
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+	if b, ok := value.([]byte); ok && field.Kind() != reflect.Slice {
+		value = string(b)
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(fmt.Sprintf("%v", value))
+		return nil
+	case reflect.Bool:
+		switch b := value.(type) {
+		case bool:
+			field.SetBool(b)
+		case int64:
+			field.SetBool(b != 0)
+		case string:
+			r, err := strconv.ParseBool(b)
+			if err != nil {
+				return err
+			}
+			field.SetBool(r)
+		default:
+			return errors.New(fmt.Sprintf("can not convert %T to bool", value))
+		}
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(fmt.Sprintf("%v", value), 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(fmt.Sprintf("%v", value), 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+		return nil
+	}
+	if field.Type() == timeType {
+		switch t := value.(type) {
+		case time.Time:
+			field.Set(reflect.ValueOf(t))
+			return nil
+		case string:
+			r, err := parseTime(t)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(r))
+			return nil
+		}
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Type().ConvertibleTo(field.Type()) {
+		field.Set(rv.Convert(field.Type()))
+		return nil
+	}
+	return errors.New(fmt.Sprintf("can not convert %T to %s", value, field.Type()))
+}
+
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(fmt.Sprintf("can not parse time %s", s))
+}
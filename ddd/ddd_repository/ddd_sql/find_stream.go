@@ -0,0 +1,111 @@
+package ddd_sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"strings"
+)
+
+//
+// findStream
+// @Description: 基于 sql.Rows 的迭代器，逐行读取数据
+//
+type findStream[T ddd.Entity] struct {
+	rows    *sql.Rows
+	names   []string
+	scan    func(rows *sql.Rows, names []string) (T, error)
+	current T
+	err     error
+}
+
+func (s *findStream[T]) Next(ctx context.Context) bool {
+	if s.err != nil {
+		return false
+	}
+	if err := ctx.Err(); err != nil {
+		s.err = err
+		return false
+	}
+	if !s.rows.Next() {
+		s.err = s.rows.Err()
+		return false
+	}
+	entity, err := s.scan(s.rows, s.names)
+	if err != nil {
+		s.err = err
+		return false
+	}
+	s.current = entity
+	return true
+}
+
+func (s *findStream[T]) Current() T {
+	return s.current
+}
+
+func (s *findStream[T]) Err() error {
+	return s.err
+}
+
+func (s *findStream[T]) Close(ctx context.Context) error {
+	return s.rows.Close()
+}
+
+//
+// FindStream
+// @Description: 流式查询，返回逐行读取的迭代器，使用完后必须调用 Close
+// @param ctx 上下文，取消时中止查询
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param opts 查询选项，可设置 Fields、Limit、Skip
+// @return ddd_repository.FindStream[T]
+// @return error
+//
+func (r *Repository[T]) FindStream(ctx context.Context, tenantId string, filter string, opts ...*ddd_repository.FindOptions) (ddd_repository.FindStream[T], error) {
+	where, args, err := r.getFilterWhere(tenantId, filter)
+	if err != nil {
+		return nil, err
+	}
+	// 按id排序，使 Skip 的结果稳定
+	where = where + " ORDER BY " + r.dialect.Quote(IdColumn)
+	opt := ddd_repository.MergeFindOptions(opts...)
+	limit, limitArgs := getLimitClause(opt.Limit, opt.Skip)
+	where = where + limit
+	args = append(args, limitArgs...)
+	columns, err := r.getSelectColumns(opt.GetFields())
+	if err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(r.quoteColumns(columns), ", "), r.quoteTable(), where)
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	names, err := rows.Columns()
+	if err != nil {
+		_ = rows.Close()
+		return nil, err
+	}
+	return &findStream[T]{rows: rows, names: names, scan: r.scanEntity}, nil
+}
+
+//
+// ForEach
+// @Description: 流式遍历查询结果
+// @param ctx 上下文，取消时停止遍历并返回ctx的错误
+// @param tenantId 租户id
+// @param filter rsql过滤条件
+// @param fun 回调，返回 ddd_repository.ErrStopIteration 时提前停止
+// @param opts 查询选项
+// @return error
+//
+func (r *Repository[T]) ForEach(ctx context.Context, tenantId string, filter string, fun func(entity T) error, opts ...*ddd_repository.FindOptions) error {
+	stream, err := r.FindStream(ctx, tenantId, filter, opts...)
+	if err != nil {
+		return err
+	}
+	return ddd_repository.ForEachStream[T](ctx, stream, fun)
+}
@@ -0,0 +1,159 @@
+package ddd_sql
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 游标分页的续查令牌，保存上一页最后一条数据的排序列值
+type pagingToken struct {
+	Sort   string       `json:"s"`
+	Values []tokenValue `json:"v"`
+}
+
+// 令牌中的列值，保留写入数据库时的类型，使续查条件与排序的比较方式一致
+type tokenValue struct {
+	Type  string `json:"t,omitempty"`
+	Value string `json:"v,omitempty"`
+}
+
+//
+//  sortItem
+//  @Description: 排序列
+//
+type sortItem struct {
+	column string
+	desc   bool
+}
+
+var errInvalidContinuationToken = errors.New("continuation token is invalid")
+
+//
+//  getKeysetSort
+//  @Description: 游标分页使用的排序，在排序列后追加 id 以保证顺序唯一
+//  @param items 排序列
+//  @return []sortItem
+//
+func getKeysetSort(items []sortItem) []sortItem {
+	for _, item := range items {
+		if item.column == IdColumn {
+			return items
+		}
+	}
+	return append(append([]sortItem{}, items...), sortItem{column: IdColumn})
+}
+
+func getSortSignature(items []sortItem) string {
+	list := make([]string, len(items))
+	for i, item := range items {
+		order := "asc"
+		if item.desc {
+			order = "desc"
+		}
+		list[i] = item.column + ":" + order
+	}
+	return strings.Join(list, ",")
+}
+
+//
+//  newContinuationToken
+//  @Description: 按最后一条数据的排序列值生成续查令牌
+//  @param items 游标分页的排序
+//  @param values 排序列的值，与 items 一一对应
+//  @return string
+//  @return error
+//
+func newContinuationToken(items []sortItem, values []interface{}) (string, error) {
+	token := &pagingToken{
+		Sort:   getSortSignature(items),
+		Values: make([]tokenValue, len(values)),
+	}
+	for i, value := range values {
+		v, err := newTokenValue(value)
+		if err != nil {
+			return "", err
+		}
+		token.Values[i] = v
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+//
+//  parseContinuationToken
+//  @Description: 解析续查令牌，返回排序列的值
+//  @param items 游标分页的排序
+//  @param continuationToken 续查令牌
+//  @return []interface{}
+//  @return error 令牌无效或与排序不一致
+//
+func parseContinuationToken(items []sortItem, continuationToken string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(continuationToken)
+	if err != nil {
+		return nil, errInvalidContinuationToken
+	}
+	token := &pagingToken{}
+	if err := json.Unmarshal(data, token); err != nil {
+		return nil, errInvalidContinuationToken
+	}
+	if token.Sort != getSortSignature(items) || len(token.Values) != len(items) {
+		return nil, errors.New("continuation token does not match the sort")
+	}
+	values := make([]interface{}, len(token.Values))
+	for i, v := range token.Values {
+		value, err := v.get()
+		if err != nil {
+			return nil, errInvalidContinuationToken
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+func newTokenValue(value interface{}) (tokenValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return tokenValue{}, nil
+	case int64:
+		return tokenValue{Type: "i", Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return tokenValue{Type: "f", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case bool:
+		return tokenValue{Type: "b", Value: strconv.FormatBool(v)}, nil
+	case string:
+		return tokenValue{Type: "s", Value: v}, nil
+	case []byte:
+		return tokenValue{Type: "y", Value: base64.RawURLEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return tokenValue{Type: "t", Value: v.Format(time.RFC3339Nano)}, nil
+	}
+	return tokenValue{}, errors.New(fmt.Sprintf("can not use %T in continuation token", value))
+}
+
+func (v tokenValue) get() (interface{}, error) {
+	switch v.Type {
+	case "":
+		return nil, nil
+	case "i":
+		return strconv.ParseInt(v.Value, 10, 64)
+	case "f":
+		return strconv.ParseFloat(v.Value, 64)
+	case "b":
+		return strconv.ParseBool(v.Value)
+	case "s":
+		return v.Value, nil
+	case "y":
+		return base64.RawURLEncoding.DecodeString(v.Value)
+	case "t":
+		return time.Parse(time.RFC3339Nano, v.Value)
+	}
+	return nil, errInvalidContinuationToken
+}
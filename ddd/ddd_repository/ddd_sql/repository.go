@@ -0,0 +1,859 @@
+package ddd_sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/utils/stringutils"
+	"go.mongodb.org/mongo-driver/bson"
+	"math"
+	"sort"
+	"strings"
+)
+
+// DoFilter 传入的过滤条件中保存 WHERE 条件与参数的键
+const (
+	WhereKey = "$where"
+	ArgsKey  = "$args"
+)
+
+//
+// Repository
+// @Description: 关系数据库仓储，基于 database/sql 实现 ddd_repository.Repository[T]。
+// 表名与列名取自 db 标签，没有时使用 snake_case 形式；非基本类型的字段以json文本保存。
+//
+type Repository[T ddd.Entity] struct {
+	db          *sql.DB
+	dialect     Dialect
+	mapper      *entityMapper
+	emptyEntity T
+	newFun      func() T
+}
+
+//
+// NewRepository
+// @Description: 新建关系数据库仓储
+// @param newFun 新建实体方法
+// @param db 数据库
+// @param dialect 数据库方言
+// @param table 表名，为空时使用实体的 TableName() 或类型名称的 snake_case 形式
+// @return *Repository[T]
+// @return error
+//
+func NewRepository[T ddd.Entity](newFun func() T, db *sql.DB, dialect Dialect, table string) (*Repository[T], error) {
+	mapper, err := getEntityMapper(newFun(), table)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := mapper.byName[TenantIdColumn]; !ok {
+		return nil, errors.New(fmt.Sprintf("table %s has no %s column", mapper.table, TenantIdColumn))
+	}
+	return &Repository[T]{
+		newFun:  newFun,
+		db:      db,
+		dialect: dialect,
+		mapper:  mapper,
+	}, nil
+}
+
+func (r *Repository[T]) NewEntity() T {
+	return r.newFun()
+}
+
+func (r *Repository[T]) NewEntityList() *[]T {
+	return &[]T{}
+}
+
+func (r *Repository[T]) Insert(ctx context.Context, entity T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	ddd_repository.SetInsertAudit(ctx, entity)
+	return r.DoSet(func() (T, error) {
+		values, err := r.mapper.values(entity)
+		if err != nil {
+			return entity, err
+		}
+		columns := r.quoteColumns(r.mapper.columnNames())
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", r.quoteTable(), strings.Join(columns, ", "), placeholders)
+		_, err = r.exec(ctx, query, values...)
+		return entity, err
+	})
+}
+
+func (r *Repository[T]) Update(ctx context.Context, entity T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	ddd_repository.SetUpdateAudit(ctx, entity)
+	return r.DoSet(func() (T, error) {
+		version, versioned := ddd_repository.GetEntityVersion(entity)
+		if !versioned {
+			_, err := r.update(ctx, entity, "", 0)
+			return entity, err
+		}
+		column, ok := r.mapper.getColumn(version.Name)
+		if !ok {
+			return entity, errors.New(fmt.Sprintf("version field %s is not a column of table %s", version.Name, r.mapper.table))
+		}
+		current := version.Value
+		version.Set(current + 1)
+		rows, err := r.update(ctx, entity, column.name, current)
+		if err == nil && rows == 0 {
+			err = ddd_errors.NewVersionConflictError(entity.GetTenantId(), entity.GetId(), current)
+		}
+		if err != nil {
+			version.Set(current)
+		}
+		return entity, err
+	})
+}
+
+//
+// InsertMany
+// @Description: 批量新建，逐条执行INSERT，ctx中的事务有效
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止。注意Postgres事务中出错后事务不可继续使用
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) InsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.setMany(ctx, entities, opts, func(entity T) error {
+		return r.Insert(ctx, entity, opts...).GetError()
+	})
+}
+
+//
+// UpdateMany
+// @Description: 批量更新，逐条执行UPDATE
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) UpdateMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.setMany(ctx, entities, opts, func(entity T) error {
+		return r.Update(ctx, entity, opts...).GetError()
+	})
+}
+
+//
+// UpsertMany
+// @Description: 批量新建或更新，记录存在时执行UPDATE并保留原创建人与创建时间，否则执行INSERT
+// @param ctx 上下文
+// @param entities 实体列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) UpsertMany(ctx context.Context, entities []T, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	return r.setMany(ctx, entities, opts, func(entity T) error {
+		existing, ok, err := r.FindById(ctx, entity.GetTenantId(), entity.GetId()).Result()
+		if err != nil {
+			return err
+		}
+		if ok {
+			ddd_repository.CopyCreatedAudit(entity, existing)
+			return r.Update(ctx, entity, opts...).GetError()
+		}
+		return r.Insert(ctx, entity, opts...).GetError()
+	})
+}
+
+func (r *Repository[T]) Delete(ctx context.Context, entity ddd.Entity, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	return r.DeleteById(ctx, entity.GetTenantId(), entity.GetId(), opts...)
+}
+
+func (r *Repository[T]) DeleteById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	data := map[string]interface{}{
+		IdColumn: id,
+	}
+	return r.DeleteByMap(ctx, tenantId, data, opts...)
+}
+
+//
+// DeleteByIds
+// @Description: 按id批量删除，不存在的id被忽略
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param ids id列表
+// @param opts 可设置Ordered，有序执行时遇到错误停止
+// @return *ddd_repository.SetManyResult[T] 每一项的执行结果
+//
+func (r *Repository[T]) DeleteByIds(ctx context.Context, tenantId string, ids []string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetManyResult[T] {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetManyResultError[T](err)
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s = ?", r.quoteTable(), r.dialect.Quote(TenantIdColumn), r.dialect.Quote(IdColumn))
+	items := ddd_repository.SetManyEach(ids, ddd_repository.MergeSetOptions(opts...).GetOrdered(), func(i int) error {
+		_, err := r.exec(ctx, query, tenantId, ids[i])
+		return err
+	})
+	return ddd_repository.NewSetManyResult[T](nil, items, nil)
+}
+
+func (r *Repository[T]) DeleteAll(ctx context.Context, tenantId string, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	data := map[string]interface{}{}
+	return r.DeleteByMap(ctx, tenantId, data, opts...)
+}
+
+//
+// DeleteByMap
+// @Description: 删除租户下所有与 filterMap 匹配的记录
+// @receiver r
+// @param ctx
+// @param tenantId 租户id
+// @param filterMap 过滤条件，键为列名、字段名称或json名称
+// @param opts
+// @return *ddd_repository.SetResult[T]
+//
+func (r *Repository[T]) DeleteByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.SetOptions) *ddd_repository.SetResult[T] {
+	if err := assert.NotNil(filterMap, assert.NewOptions("data is nil")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewSetResultError[T](err)
+	}
+	return r.DoSet(func() (T, error) {
+		var result T
+		where, args, err := r.getMapWhere(tenantId, filterMap)
+		if err != nil {
+			return result, err
+		}
+		query := fmt.Sprintf("DELETE FROM %s WHERE %s", r.quoteTable(), where)
+		_, err = r.exec(ctx, query, args...)
+		return result, err
+	})
+}
+
+func (r *Repository[T]) NewFilter(tenantId string, filterMap map[string]interface{}) bson.D {
+	filter := bson.D{
+		{Key: TenantIdColumn, Value: tenantId},
+	}
+	if filterMap != nil {
+		for fieldName, fieldValue := range filterMap {
+			if col, ok := r.mapper.getColumn(fieldName); ok {
+				fieldName = col.name
+			} else {
+				fieldName = AsFieldName(fieldName)
+			}
+			filter = append(filter, bson.E{Key: fieldName, Value: fieldValue})
+		}
+	}
+	return filter
+}
+
+func (r *Repository[T]) FindById(ctx context.Context, tenantId string, id string, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
+	idMap := map[string]interface{}{
+		IdColumn: id,
+	}
+	return r.FindOneByMap(ctx, tenantId, idMap, opts...)
+}
+
+func (r *Repository[T]) FindOneByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindOneResult[T] {
+	return r.DoFindOne(func() (T, bool, error) {
+		where, args, err := r.getMapWhere(tenantId, filterMap)
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
+		columns, err := r.getSelectColumns(ddd_repository.MergeFindOptions(opts...).GetFields())
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
+		list, err := r.queryColumns(ctx, columns, where+" LIMIT 1", args...)
+		if err != nil {
+			return r.emptyEntity, false, err
+		}
+		if len(*list) == 0 {
+			return r.emptyEntity, false, nil
+		}
+		return (*list)[0], true, nil
+	})
+}
+
+//
+// FindListByMap
+// @Description: 查询租户下与 filterMap 匹配的实体
+// @param ctx 上下文
+// @param tenantId 租户id
+// @param filterMap 过滤条件，键为字段名称
+// @param opts 查询选项，可设置 Fields、Limit、Skip，设置 Limit 或 Skip 时按id排序
+// @return *ddd_repository.FindListResult[T]
+//
+func (r *Repository[T]) FindListByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*ddd_repository.FindOptions) *ddd_repository.FindListResult[T] {
+	return r.DoFindList(func() (*[]T, bool, error) {
+		where, args, err := r.getMapWhere(tenantId, filterMap)
+		if err != nil {
+			return nil, false, err
+		}
+		findOptions := ddd_repository.MergeFindOptions(opts...)
+		columns, err := r.getSelectColumns(findOptions.GetFields())
+		if err != nil {
+			return nil, false, err
+		}
+		if limit, limitArgs := getLimitClause(findOptions.Limit, findOptions.Skip); len(limit) > 0 {
+			where = where + " ORDER BY " + r.dialect.Quote(IdColumn) + limit
+			args = append(args, limitArgs...)
+		}
+		data, err := r.queryColumns(ctx, columns, where, args...)
+		if err != nil {
+			return nil, false, err
+		}
+		return data, true, nil
+	})
+}
+
+func (r *Repository[T]) FindAll(ctx context.Context, tenantId string, opts ...*ddd_repository.FindOptions) *ddd_repository.FindListResult[T] {
+	return r.FindListByMap(ctx, tenantId, nil, opts...)
+}
+
+//
+// FindPaging
+// @Description: 分页查询。设置分页大小时按排序列与id分页，返回下一页的续查令牌；
+// 排序时空值排在最小值之前（降序时排在最后），与 Mongo 仓储一致
+// @param ctx 上下文
+// @param query 分页查询，设置续查令牌时从上一页最后一条数据之后开始查询，忽略页码
+// @param opts 查询选项，可设置 Fields，未设置分页大小时可设置 Limit、Skip
+// @return *ddd_repository.FindPagingResult[T]
+//
+func (r *Repository[T]) FindPaging(ctx context.Context, query ddd_repository.FindPagingQuery, opts ...*ddd_repository.FindOptions) *ddd_repository.FindPagingResult[T] {
+	return r.DoFilter(query.GetTenantId(), query.GetFilter(), func(filter map[string]interface{}) (*ddd_repository.FindPagingResult[T], bool, error) {
+		where, _ := filter[WhereKey].(string)
+		args, _ := filter[ArgsKey].([]interface{})
+		findOptions := ddd_repository.MergeFindOptions(opts...)
+
+		var totalRows int64
+		if query.GetIsTotalRows() {
+			count, err := r.count(ctx, where, args...)
+			if err != nil {
+				return nil, false, err
+			}
+			totalRows = count
+		}
+
+		sortItems, err := r.getSort(query.GetSort())
+		if err != nil {
+			return nil, false, err
+		}
+		fields := query.GetFields()
+		if len(fields) == 0 {
+			fields = findOptions.GetFields()
+		}
+		columns, err := r.getSelectColumns(fields)
+		if err != nil {
+			return nil, false, err
+		}
+
+		sqlText := where
+		queryArgs := append([]interface{}{}, args...)
+		pageSize := query.GetPageSize()
+		token := query.GetContinuationToken()
+		if pageSize > 0 {
+			sortItems = getKeysetSort(sortItems)
+			columns = ensureColumns(columns, sortItems)
+			if len(token) > 0 {
+				keyset, keysetArgs, err := r.getKeysetWhere(sortItems, token)
+				if err != nil {
+					return nil, false, err
+				}
+				sqlText = "(" + sqlText + ") AND " + keyset
+				queryArgs = append(queryArgs, keysetArgs...)
+			}
+		}
+		if len(sortItems) > 0 {
+			sqlText = sqlText + " ORDER BY " + r.getOrderBy(sortItems)
+		}
+		limit, limitArgs := getLimitClause(findOptions.Limit, findOptions.Skip)
+		if pageSize > 0 {
+			skip := pageSize * query.GetPageNum()
+			if len(token) > 0 {
+				skip = 0
+			}
+			limit, limitArgs = getLimitClause(&pageSize, &skip)
+		}
+		sqlText = sqlText + limit
+		queryArgs = append(queryArgs, limitArgs...)
+
+		data, last, err := r.queryLast(ctx, columns, sqlText, queryArgs...)
+		if err != nil {
+			return nil, false, err
+		}
+		findData := ddd_repository.NewFindPagingResult[T](data, totalRows, query, nil)
+		if pageSize > 0 && int64(len(*data)) == pageSize {
+			values := make([]interface{}, len(sortItems))
+			for i, item := range sortItems {
+				values[i] = last[item.column]
+			}
+			if findData.ContinuationToken, err = newContinuationToken(sortItems, values); err != nil {
+				return nil, false, err
+			}
+		}
+		return findData, true, nil
+	})
+}
+
+//
+// DoFilter
+// @Description: 解析 rsql 过滤条件，fun 的参数中以 WhereKey 与 ArgsKey 保存 WHERE 条件与参数
+// @receiver r
+// @param tenantId 租户id
+// @param filter rsql 表达式
+// @param fun
+// @return *ddd_repository.FindPagingResult[T]
+//
+func (r *Repository[T]) DoFilter(tenantId, filter string, fun func(filter map[string]interface{}) (*ddd_repository.FindPagingResult[T], bool, error)) *ddd_repository.FindPagingResult[T] {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	p := newSqlProcess(r.mapper, r.dialect)
+	if err := rsql.ParseProcess(filter, p); err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	where, args, err := p.GetFilter(tenantId)
+	if err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	filterData := map[string]interface{}{
+		WhereKey: where,
+		ArgsKey:  args,
+	}
+	data, _, err := fun(filterData)
+	if err != nil {
+		return ddd_repository.NewFindPagingResultWithError[T](err)
+	}
+	return data
+}
+
+func (r *Repository[T]) DoFindList(fun func() (*[]T, bool, error)) *ddd_repository.FindListResult[T] {
+	data, isFound, err := fun()
+	if errors.Is(err, sql.ErrNoRows) {
+		isFound = false
+		err = nil
+	}
+	return ddd_repository.NewFindListResult[T](data, isFound, err)
+}
+
+func (r *Repository[T]) DoFindOne(fun func() (T, bool, error)) *ddd_repository.FindOneResult[T] {
+	data, isFound, err := fun()
+	if errors.Is(err, sql.ErrNoRows) {
+		isFound = false
+		err = nil
+	}
+	return ddd_repository.NewFindOneResult[T](data, isFound, err)
+}
+
+func (r *Repository[T]) DoSet(fun func() (T, error)) *ddd_repository.SetResult[T] {
+	data, err := fun()
+	return ddd_repository.NewSetResult[T](data, err)
+}
+
+func (r *Repository[T]) setMany(ctx context.Context, entities []T, opts []*ddd_repository.SetOptions, set func(entity T) error) *ddd_repository.SetManyResult[T] {
+	for i, entity := range entities {
+		if err := assert.NotEmpty(entity.GetTenantId(), assert.NewOptions(fmt.Sprintf("entities[%d].tenantId is empty", i))); err != nil {
+			return ddd_repository.NewSetManyResultError[T](err)
+		}
+	}
+	ids := ddd_repository.GetEntityIds(entities)
+	items := ddd_repository.SetManyEach(ids, ddd_repository.MergeSetOptions(opts...).GetOrdered(), func(i int) error {
+		return set(entities[i])
+	})
+	return ddd_repository.NewSetManyResult[T](entities, items, nil)
+}
+
+//
+//  update
+//  @Description: 按租户与id更新所有列，创建人与创建时间列除外
+//  @param versionColumn 乐观锁版本号列，不为空时按原版本号过滤
+//  @param version 原版本号
+//  @return int64 更新的行数
+//
+func (r *Repository[T]) update(ctx context.Context, entity T, versionColumn string, version int64) (int64, error) {
+	values, err := r.mapper.values(entity)
+	if err != nil {
+		return 0, err
+	}
+	// 创建人与创建时间只在新建时写入
+	created := make(map[string]bool)
+	for _, field := range ddd_repository.GetCreatedAuditFields(entity) {
+		if column, ok := r.mapper.getColumn(field); ok {
+			created[column.name] = true
+		}
+	}
+	sets := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values)+3)
+	for i, name := range r.mapper.columnNames() {
+		if name == IdColumn || name == TenantIdColumn || created[name] {
+			continue
+		}
+		sets = append(sets, r.dialect.Quote(name)+" = ?")
+		args = append(args, values[i])
+	}
+	args = append(args, entity.GetTenantId(), entity.GetId())
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ? AND %s = ?", r.quoteTable(), strings.Join(sets, ", "),
+		r.dialect.Quote(TenantIdColumn), r.dialect.Quote(IdColumn))
+	if versionColumn != "" {
+		query += fmt.Sprintf(" AND %s = ?", r.dialect.Quote(versionColumn))
+		args = append(args, version)
+	}
+	result, err := r.exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+//
+//  getExecutor
+//  @Description: 在事务中时使用事务执行语句
+//
+func (r *Repository[T]) getExecutor(ctx context.Context) executor {
+	if tx := getSqlTx(ctx); tx != nil {
+		return tx
+	}
+	return r.db
+}
+
+func (r *Repository[T]) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return r.getExecutor(ctx).ExecContext(ctx, r.dialect.Rebind(query), args...)
+}
+
+//
+//  query
+//  @Description: 查询实体列表
+//  @param where WHERE 条件，可包含 ORDER BY 与 LIMIT
+//
+func (r *Repository[T]) query(ctx context.Context, where string, args ...interface{}) (*[]T, error) {
+	return r.queryColumns(ctx, r.mapper.columnNames(), where, args...)
+}
+
+//
+//  queryColumns
+//  @Description: 查询实体列表，只读取指定的列
+//  @param columns 列名
+//  @param where WHERE 条件，可包含 ORDER BY 与 LIMIT
+//
+func (r *Repository[T]) queryColumns(ctx context.Context, columns []string, where string, args ...interface{}) (*[]T, error) {
+	list, _, err := r.queryLast(ctx, columns, where, args...)
+	return list, err
+}
+
+//
+//  queryLast
+//  @Description: 查询实体列表，并返回最后一行各列的原始值，空值为nil，用于生成续查令牌
+//  @param columns 列名
+//  @param where WHERE 条件，可包含 ORDER BY 与 LIMIT
+//
+func (r *Repository[T]) queryLast(ctx context.Context, columns []string, where string, args ...interface{}) (*[]T, map[string]interface{}, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(r.quoteColumns(columns), ", "), r.quoteTable(), where)
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	list := r.NewEntityList()
+	var values []interface{}
+	for rows.Next() {
+		var entity T
+		entity, values, err = r.scanRow(rows, names)
+		if err != nil {
+			return nil, nil, err
+		}
+		*list = append(*list, entity)
+	}
+	last := make(map[string]interface{}, len(names))
+	for i, value := range values {
+		last[names[i]] = value
+	}
+	return list, last, rows.Err()
+}
+
+//
+//  scanEntity
+//  @Description: 将当前行转换为实体
+//  @param names 结果的列名
+//
+func (r *Repository[T]) scanEntity(rows *sql.Rows, names []string) (T, error) {
+	entity, _, err := r.scanRow(rows, names)
+	return entity, err
+}
+
+func (r *Repository[T]) scanRow(rows *sql.Rows, names []string) (T, []interface{}, error) {
+	values := make([]interface{}, len(names))
+	ptrs := make([]interface{}, len(names))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	entity := r.NewEntity()
+	if err := rows.Scan(ptrs...); err != nil {
+		return entity, nil, err
+	}
+	if err := r.mapper.scan(entity, names, values); err != nil {
+		return entity, nil, err
+	}
+	return entity, values, nil
+}
+
+func (r *Repository[T]) count(ctx context.Context, where string, args ...interface{}) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", r.quoteTable(), where)
+	rows, err := r.getExecutor(ctx).QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	var count int64
+	if rows.Next() {
+		if err := rows.Scan(&count); err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
+}
+
+//
+//  getMapWhere
+//  @Description: 按字段值相等生成 WHERE 条件，列按名称排序以保证语句稳定
+//
+func (r *Repository[T]) getMapWhere(tenantId string, filterMap map[string]interface{}) (string, []interface{}, error) {
+	items := []string{r.dialect.Quote(TenantIdColumn) + " = ?"}
+	args := []interface{}{tenantId}
+	names := make([]string, 0, len(filterMap))
+	for name := range filterMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		col, ok := r.mapper.getColumn(name)
+		if !ok {
+			return "", nil, errors.New(fmt.Sprintf("field %s does not exist in table %s", name, r.mapper.table))
+		}
+		value := filterMap[name]
+		if value == nil {
+			items = append(items, r.dialect.Quote(col.name)+" IS NULL")
+			continue
+		}
+		items = append(items, r.dialect.Quote(col.name)+" = ?")
+		args = append(args, value)
+	}
+	return strings.Join(items, " AND "), args, nil
+}
+
+func (r *Repository[T]) getSort(sort string) ([]sortItem, error) {
+	if len(sort) == 0 {
+		return nil, nil
+	}
+	//name:desc,id:asc
+	items := make([]sortItem, 0)
+	for _, s := range strings.Split(sort, ",") {
+		item := strings.Split(s, ":")
+		name := strings.Trim(item[0], " ")
+		col, ok := r.mapper.getColumn(name)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("sort field %s does not exist in table %s", name, r.mapper.table))
+		}
+		order := "asc"
+		if len(item) > 1 {
+			order = strings.Trim(strings.ToLower(item[1]), " ")
+		}
+		switch order {
+		case "asc":
+			items = append(items, sortItem{column: col.name})
+		case "desc":
+			items = append(items, sortItem{column: col.name, desc: true})
+		default:
+			return nil, errors.New("order " + order + " is error")
+		}
+	}
+	return items, nil
+}
+
+//
+//  getOrderBy
+//  @Description: 生成 ORDER BY 语句，空值排在最小值之前，使各数据库的排序与续查条件一致
+//
+func (r *Repository[T]) getOrderBy(items []sortItem) string {
+	list := make([]string, 0, len(items)*2)
+	for _, item := range items {
+		col := r.dialect.Quote(item.column)
+		switch {
+		case item.column == IdColumn && item.desc:
+			list = append(list, col+" DESC")
+		case item.column == IdColumn:
+			list = append(list, col+" ASC")
+		case item.desc:
+			list = append(list, "("+col+" IS NULL) ASC", col+" DESC")
+		default:
+			list = append(list, "("+col+" IS NULL) DESC", col+" ASC")
+		}
+	}
+	return strings.Join(list, ", ")
+}
+
+//
+//  getKeysetWhere
+//  @Description: 按续查令牌生成从上一页最后一条数据之后开始的条件。空值排在最小值之前，
+//  升序时空值之后为所有非空值，降序时非空值之后还有空值
+//  @param items 游标分页的排序
+//  @param continuationToken 续查令牌
+//  @return string
+//  @return []interface{}
+//  @return error
+//
+func (r *Repository[T]) getKeysetWhere(items []sortItem, continuationToken string) (string, []interface{}, error) {
+	values, err := parseContinuationToken(items, continuationToken)
+	if err != nil {
+		return "", nil, err
+	}
+	ors := make([]string, 0, len(items))
+	args := make([]interface{}, 0)
+	for i, item := range items {
+		conds := make([]string, 0, i+1)
+		condArgs := make([]interface{}, 0, i+1)
+		for j := 0; j < i; j++ {
+			col := r.dialect.Quote(items[j].column)
+			if values[j] == nil {
+				conds = append(conds, col+" IS NULL")
+			} else {
+				conds = append(conds, col+" = ?")
+				condArgs = append(condArgs, values[j])
+			}
+		}
+		col := r.dialect.Quote(item.column)
+		switch {
+		case values[i] == nil && item.desc:
+			continue
+		case values[i] == nil:
+			conds = append(conds, col+" IS NOT NULL")
+		case item.desc:
+			conds = append(conds, "("+col+" < ? OR "+col+" IS NULL)")
+			condArgs = append(condArgs, values[i])
+		default:
+			conds = append(conds, col+" > ?")
+			condArgs = append(condArgs, values[i])
+		}
+		ors = append(ors, "("+strings.Join(conds, " AND ")+")")
+		args = append(args, condArgs...)
+	}
+	if len(ors) == 0 {
+		return "1 = 0", nil, nil
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args, nil
+}
+
+//
+//  getSelectColumns
+//  @Description: 按返回字段获取查询的列，总是包含id列
+//  @param fields 返回字段，以逗号分隔，以-开头表示排除，为空时返回所有列
+//  @return []string
+//  @return error 字段不存在或同时包含返回与排除的字段
+//
+func (r *Repository[T]) getSelectColumns(fields string) ([]string, error) {
+	all := r.mapper.columnNames()
+	if len(strings.TrimSpace(fields)) == 0 {
+		return all, nil
+	}
+	include := make(map[string]bool)
+	exclude := make(map[string]bool)
+	for _, field := range strings.Split(fields, ",") {
+		name := strings.TrimSpace(field)
+		if len(name) == 0 {
+			continue
+		}
+		excluded := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		col, ok := r.mapper.getColumn(name)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("field %s does not exist in table %s", name, r.mapper.table))
+		}
+		if excluded {
+			exclude[col.name] = true
+		} else {
+			include[col.name] = true
+		}
+	}
+	if len(include) > 0 && len(exclude) > 0 {
+		return nil, errors.New(fmt.Sprintf("fields %s can not mix included and excluded fields", fields))
+	}
+	res := make([]string, 0, len(all))
+	for _, name := range all {
+		if name == IdColumn || include[name] || (len(exclude) > 0 && !exclude[name]) {
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+//
+//  ensureColumns
+//  @Description: 查询的列中添加缺少的排序列，使最后一条数据可以生成续查令牌
+//
+func ensureColumns(columns []string, items []sortItem) []string {
+	res := append([]string{}, columns...)
+	for _, item := range items {
+		found := false
+		for _, name := range res {
+			if name == item.column {
+				found = true
+				break
+			}
+		}
+		if !found {
+			res = append(res, item.column)
+		}
+	}
+	return res
+}
+
+//
+//  getLimitClause
+//  @Description: 生成 LIMIT 与 OFFSET 语句，都为nil时返回空。部分数据库的 OFFSET 必须与 LIMIT 一起使用
+//
+func getLimitClause(limit *int64, skip *int64) (string, []interface{}) {
+	if limit == nil && skip == nil {
+		return "", nil
+	}
+	l, s := int64(math.MaxInt64), int64(0)
+	if limit != nil && *limit > 0 {
+		l = *limit
+	}
+	if skip != nil {
+		s = *skip
+	}
+	return " LIMIT ? OFFSET ?", []interface{}{l, s}
+}
+
+func (r *Repository[T]) quoteTable() string {
+	return r.dialect.Quote(r.mapper.table)
+}
+
+func (r *Repository[T]) quoteColumns(names []string) []string {
+	res := make([]string, len(names))
+	for i, name := range names {
+		res[i] = r.dialect.Quote(name)
+	}
+	return res
+}
+
+//
+// AsFieldName
+// @Description: 转换为数据库规范的列名
+// @param name
+// @return string
+//
+func AsFieldName(name string) string {
+	return stringutils.SnakeString(name)
+}
@@ -0,0 +1,532 @@
+package ddd_sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+	"testing"
+	"time"
+)
+
+type Movie struct {
+	Id           string    `json:"id"`
+	TenantId     string    `json:"tenantId"`
+	Name         string    `json:"name"`
+	Year         int64     `json:"year"`
+	Rating       float64   `json:"rating"`
+	Released     bool      `json:"released"`
+	Genres       []string  `json:"genres"`
+	DirectorName string    `json:"directorName" db:"director"`
+	CreatedTime  time.Time `json:"createdTime"`
+	Remarks      string    `json:"remarks" db:"-"`
+}
+
+func (m *Movie) GetTenantId() string { return m.TenantId }
+func (m *Movie) GetId() string       { return m.Id }
+func (m *Movie) TableName() string   { return "movies" }
+
+var _ ddd_repository.Repository[*Movie] = (*Repository[*Movie])(nil)
+
+func newMovieRepository(t *testing.T) (*sql.DB, *Repository[*Movie]) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(`CREATE TABLE movies (
+		id TEXT NOT NULL, tenant_id TEXT NOT NULL, name TEXT, year INTEGER, rating REAL, released INTEGER,
+		genres TEXT, director TEXT, created_time DATETIME, PRIMARY KEY (tenant_id, id))`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos, err := NewRepository[*Movie](func() *Movie { return &Movie{} }, db, SQLite, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, repos
+}
+
+func insertMovies(t *testing.T, repos *Repository[*Movie]) {
+	ctx := context.Background()
+	created := time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)
+	movies := []*Movie{
+		{Id: "1", TenantId: "t1", Name: "Inception", Year: 2010, Rating: 8.8, Released: true, Genres: []string{"sci-fi", "action"}, DirectorName: "Nolan", CreatedTime: created},
+		{Id: "2", TenantId: "t1", Name: "Interstellar", Year: 2014, Rating: 8.6, Released: true, Genres: []string{"sci-fi"}, DirectorName: "Nolan", CreatedTime: created},
+		{Id: "3", TenantId: "t1", Name: "Kill Bill", Year: 2003, Rating: 8.2, Released: true, Genres: []string{"action"}, DirectorName: "Tarantino", CreatedTime: created},
+		{Id: "4", TenantId: "t1", Name: "Memento", Year: 2000, Rating: 8.4, Genres: []string{"thriller"}, DirectorName: "Nolan", CreatedTime: created},
+		{Id: "1", TenantId: "t2", Name: "Other Tenant", Year: 2010, DirectorName: "Nolan", CreatedTime: created},
+	}
+	for _, m := range movies {
+		assert.NoError(t, repos.Insert(ctx, m).GetError())
+	}
+}
+
+func TestRepository_InsertFind(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	assert.Error(t, repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1"}).GetError())
+
+	movie, ok, err := repos.FindById(ctx, "t1", "1").Result()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Inception", movie.Name)
+	assert.Equal(t, []string{"sci-fi", "action"}, movie.Genres)
+	assert.Equal(t, "Nolan", movie.DirectorName)
+	assert.True(t, movie.Released)
+	assert.True(t, movie.CreatedTime.Equal(time.Date(2022, 5, 1, 10, 0, 0, 0, time.UTC)))
+
+	_, ok, err = repos.FindById(ctx, "t2", "2").Result()
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	list, _, err := repos.FindListByMap(ctx, "t1", map[string]interface{}{"directorName": "Nolan"}).Result()
+	assert.NoError(t, err)
+	assert.Len(t, *list, 3)
+
+	_, _, err = repos.FindListByMap(ctx, "t1", map[string]interface{}{"unknown": 1}).Result()
+	assert.Error(t, err)
+
+	movie.Name = "Inception 2"
+	assert.NoError(t, repos.Update(ctx, movie).GetError())
+	movie, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "Inception 2", movie.Name)
+	other, _, _ := repos.FindById(ctx, "t2", "1").Result()
+	assert.Equal(t, "Other Tenant", other.Name)
+}
+
+func TestRepository_FindPaging(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	tests := []struct {
+		filter string
+		sort   string
+		ids    []string
+	}{
+		{filter: "", sort: "year:desc", ids: []string{"2", "1", "3", "4"}},
+		{filter: "director=='Nolan' and year>=2010", sort: "year:asc", ids: []string{"1", "2"}},
+		{filter: "directorName=in=('Tarantino') or year<2001", sort: "name", ids: []string{"3", "4"}},
+		{filter: "name=out=('Memento','Kill Bill')", sort: "id", ids: []string{"1", "2"}},
+		{filter: "name==~'in*'", sort: "id:desc", ids: []string{"2", "1"}},
+		{filter: "name!=~'e'", sort: "id", ids: []string{"3"}},
+		{filter: "name==~'%'", sort: "id", ids: []string{}},
+		{filter: "name==~'_n*'", sort: "id", ids: []string{}},
+		{filter: "name=search='100%'", sort: "id", ids: []string{}},
+		{filter: "(year>2005 and year<2012) or rating>8.5", sort: "id", ids: []string{"1", "2"}},
+		{filter: "released==true", sort: "id", ids: []string{"1", "2", "3"}},
+	}
+	for _, test := range tests {
+		query := ddd_repository.NewFindPagingQuery()
+		query.SetTenantId("t1")
+		query.SetFilter(test.filter)
+		query.SetSort(test.sort)
+		res := repos.FindPaging(ctx, query)
+		if !assert.NoError(t, res.GetError(), test.filter) {
+			continue
+		}
+		ids := make([]string, 0)
+		for _, m := range *res.GetData() {
+			ids = append(ids, m.Id)
+		}
+		assert.Equal(t, test.ids, ids, test.filter)
+	}
+
+	query := ddd_repository.NewFindPagingQuery()
+	query.SetTenantId("t1")
+	query.SetSort("id")
+	query.SetPageSize(3)
+	query.SetPageNum(1)
+	res := repos.FindPaging(ctx, query)
+	assert.NoError(t, res.GetError())
+	assert.Equal(t, int64(4), res.TotalRows)
+	assert.Len(t, *res.GetData(), 1)
+
+	query.SetFilter("unknown=='x'")
+	assert.Error(t, repos.FindPaging(ctx, query).GetError())
+}
+
+func TestRepository_FindPagingContinuationToken(t *testing.T) {
+	db, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+	_, err := db.Exec(`INSERT INTO movies (id, tenant_id, name) VALUES ('5', 't1', 'Unknown A'), ('6', 't1', 'Unknown B')`)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		sort string
+		ids  []string
+	}{
+		{sort: "director", ids: []string{"5", "6", "1", "2", "4", "3"}},
+		{sort: "director:desc", ids: []string{"3", "1", "2", "4", "5", "6"}},
+		{sort: "year:desc", ids: []string{"2", "1", "3", "4", "5", "6"}},
+		{sort: "", ids: []string{"1", "2", "3", "4", "5", "6"}},
+	}
+	for _, test := range tests {
+		for _, pageSize := range []int64{1, 2, 4} {
+			ids := make([]string, 0)
+			token := ""
+			for i := 0; i < 10; i++ {
+				query := ddd_repository.NewFindPagingQuery()
+				query.SetTenantId("t1")
+				query.SetSort(test.sort)
+				query.SetPageSize(pageSize)
+				query.SetContinuationToken(token)
+				res := repos.FindPaging(ctx, query)
+				if !assert.NoError(t, res.GetError(), test.sort) {
+					break
+				}
+				for _, m := range *res.GetData() {
+					ids = append(ids, m.Id)
+				}
+				if token = res.GetContinuationToken(); len(token) == 0 {
+					break
+				}
+			}
+			assert.Equal(t, test.ids, ids, "%s page size %d", test.sort, pageSize)
+		}
+	}
+
+	query := ddd_repository.NewFindPagingQuery()
+	query.SetTenantId("t1")
+	query.SetSort("name")
+	query.SetPageSize(2)
+	query.SetContinuationToken("invalid")
+	assert.Error(t, repos.FindPaging(ctx, query).GetError())
+
+	query.SetContinuationToken("")
+	token := repos.FindPaging(ctx, query).GetContinuationToken()
+	query.SetSort("year")
+	query.SetContinuationToken(token)
+	assert.Error(t, repos.FindPaging(ctx, query).GetError())
+}
+
+func TestRepository_FindOptions(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	list, _, err := repos.FindAll(ctx, "t1", ddd_repository.NewFindOptions().SetSkip(1).SetLimit(2)).Result()
+	assert.NoError(t, err)
+	if assert.Len(t, *list, 2) {
+		assert.Equal(t, "2", (*list)[0].Id)
+		assert.Equal(t, "3", (*list)[1].Id)
+	}
+
+	list, _, err = repos.FindAll(ctx, "t1", ddd_repository.NewFindOptions().SetFields("name")).Result()
+	assert.NoError(t, err)
+	for _, m := range *list {
+		assert.NotEmpty(t, m.Id)
+		assert.NotEmpty(t, m.Name)
+		assert.Empty(t, m.DirectorName)
+		assert.Zero(t, m.Year)
+	}
+
+	movie, _, err := repos.FindById(ctx, "t1", "1", ddd_repository.NewFindOptions().SetFields("-genres,-director")).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "Inception", movie.Name)
+	assert.Nil(t, movie.Genres)
+	assert.Empty(t, movie.DirectorName)
+
+	_, _, err = repos.FindAll(ctx, "t1", ddd_repository.NewFindOptions().SetFields("name,-year")).Result()
+	assert.Error(t, err)
+	_, _, err = repos.FindAll(ctx, "t1", ddd_repository.NewFindOptions().SetFields("unknown")).Result()
+	assert.Error(t, err)
+
+	query := ddd_repository.NewFindPagingQuery()
+	query.SetTenantId("t1")
+	query.SetSort("id")
+	query.SetPageSize(0)
+	res := repos.FindPaging(ctx, query, ddd_repository.NewFindOptions().SetLimit(1).SetSkip(3).SetFields("name"))
+	assert.NoError(t, res.GetError())
+	if assert.Len(t, *res.GetData(), 1) {
+		assert.Equal(t, "4", (*res.GetData())[0].Id)
+		assert.Empty(t, (*res.GetData())[0].DirectorName)
+	}
+}
+
+func TestRepository_Delete(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	assert.NoError(t, repos.DeleteByMap(ctx, "t1", map[string]interface{}{"director": "Nolan"}).GetError())
+	list, _, _ := repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 1)
+	list, _, _ = repos.FindAll(ctx, "t2").Result()
+	assert.Len(t, *list, 1)
+
+	assert.NoError(t, repos.DeleteById(ctx, "t1", "3").GetError())
+	assert.NoError(t, repos.DeleteAll(ctx, "t2").GetError())
+	list, _, _ = repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 0)
+	list, _, _ = repos.FindAll(ctx, "t2").Result()
+	assert.Len(t, *list, 0)
+}
+
+func TestSession_UseTransaction(t *testing.T) {
+	db, repos := newMovieRepository(t)
+	session := NewSession(db)
+	ctx := context.Background()
+
+	errRollback := errors.New("rollback")
+	err := ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+		assert.NoError(t, repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1", Name: "A"}).GetError())
+		_, ok, _ := repos.FindById(ctx, "t1", "1").Result()
+		assert.True(t, ok)
+		return errRollback
+	})
+	assert.Equal(t, errRollback, err)
+	_, ok, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.False(t, ok)
+
+	err = ddd_repository.StartSession(ctx, session, func(ctx context.Context) error {
+		return repos.Insert(ctx, &Movie{Id: "1", TenantId: "t1", Name: "A"}).GetError()
+	})
+	assert.NoError(t, err)
+	_, ok, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.True(t, ok)
+}
+
+func TestSqlProcess(t *testing.T) {
+	mapper, err := getEntityMapper(&Movie{}, "")
+	assert.NoError(t, err)
+
+	p := newSqlProcess(mapper, Postgres)
+	assert.NoError(t, rsql.ParseProcess("name=='A' and (year>2000 or genres=in=('x','y'))", p))
+	where, args, err := p.GetFilter("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, `"tenant_id" = ? AND ("name" = ? AND ("year" > ? OR "genres" IN (?, ?)))`, where)
+	assert.Equal(t, []interface{}{"t1", "A", int64(2000), "x", "y"}, args)
+
+	p = newSqlProcess(mapper, Postgres)
+	assert.NoError(t, rsql.ParseProcess("name=search='kill \"bill\"'", p))
+	where, args, err = p.GetFilter("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, `"tenant_id" = ? AND (LOWER("name") LIKE LOWER(?) ESCAPE '\' OR LOWER("name") LIKE LOWER(?) ESCAPE '\')`, where)
+	assert.Equal(t, []interface{}{"t1", "%kill%", "%bill%"}, args)
+
+	p = newSqlProcess(mapper, MySQL)
+	assert.NoError(t, rsql.ParseProcess("name==~'50%_off*'", p))
+	where, args, err = p.GetFilter("t1")
+	assert.NoError(t, err)
+	assert.Equal(t, "`tenant_id` = ? AND LOWER(`name`) LIKE LOWER(?) ESCAPE '\\\\'", where)
+	assert.Equal(t, []interface{}{"t1", `50\%\_off%`}, args)
+	assert.Equal(t, `"tenant_id" = $1 AND "name" = $2`, Postgres.Rebind(`"tenant_id" = ? AND "name" = ?`))
+}
+
+func TestRepository_InsertMany(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	movies := []*Movie{
+		{Id: "5", TenantId: "t1", Name: "Dunkirk"},
+		{Id: "1", TenantId: "t1", Name: "Duplicate"},
+		{Id: "6", TenantId: "t1", Name: "Tenet"},
+	}
+	res := repos.InsertMany(ctx, movies)
+	assert.Error(t, res.GetError())
+	assert.Len(t, res.GetSuccessItems(), 1)
+	failed := res.GetFailedItems()
+	assert.Len(t, failed, 2)
+	assert.Equal(t, 1, failed[0].Index)
+	assert.Equal(t, ddd_repository.ErrNotExecuted, failed[1].Error)
+
+	movies[0].Id, movies[2].Id = "7", "8"
+	res = repos.InsertMany(ctx, movies, ddd_repository.NewSetOptions().SetOrdered(false))
+	assert.Len(t, res.GetSuccessItems(), 2)
+	assert.Equal(t, "1", res.GetFailedItems()[0].Id)
+	_, ok, _ := repos.FindById(ctx, "t1", "8").Result()
+	assert.True(t, ok)
+}
+
+func TestRepository_UpsertManyDeleteByIds(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	res := repos.UpsertMany(ctx, []*Movie{
+		{Id: "1", TenantId: "t1", Name: "Inception (2010)"},
+		{Id: "9", TenantId: "t1", Name: "Oppenheimer"},
+	})
+	assert.NoError(t, res.GetError())
+	movie, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "Inception (2010)", movie.Name)
+	_, ok, _ := repos.FindById(ctx, "t1", "9").Result()
+	assert.True(t, ok)
+
+	res = repos.UpdateMany(ctx, []*Movie{{Id: "2", TenantId: "t1", Name: "Interstellar (2014)"}})
+	assert.NoError(t, res.GetError())
+
+	res = repos.DeleteByIds(ctx, "t1", []string{"1", "2", "9"})
+	assert.NoError(t, res.GetError())
+	list, _, _ := repos.FindAll(ctx, "t1").Result()
+	assert.Len(t, *list, 2)
+	_, ok, _ = repos.FindById(ctx, "t2", "1").Result()
+	assert.True(t, ok)
+}
+
+type Account struct {
+	Id         string `json:"id"`
+	TenantId   string `json:"tenantId"`
+	Balance    int64  `json:"balance"`
+	RowVersion int64  `json:"rowVersion" ddd:"version"`
+}
+
+func (a *Account) GetTenantId() string { return a.TenantId }
+func (a *Account) GetId() string       { return a.Id }
+
+func TestRepository_UpdateVersion(t *testing.T) {
+	db, _ := newMovieRepository(t)
+	_, err := db.Exec(`CREATE TABLE account (id TEXT NOT NULL, tenant_id TEXT NOT NULL, balance INTEGER, row_version INTEGER, PRIMARY KEY (tenant_id, id))`)
+	assert.NoError(t, err)
+	repos, err := NewRepository[*Account](func() *Account { return &Account{} }, db, SQLite, "")
+	assert.NoError(t, err)
+	ctx := context.Background()
+	assert.NoError(t, repos.Insert(ctx, &Account{Id: "1", TenantId: "t1"}).GetError())
+
+	first, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	second, _, _ := repos.FindById(ctx, "t1", "1").Result()
+
+	first.Balance = 10
+	assert.NoError(t, repos.Update(ctx, first).GetError())
+	assert.Equal(t, int64(1), first.RowVersion)
+
+	second.Balance = 20
+	err = repos.Update(ctx, second).GetError()
+	assert.True(t, ddd_errors.IsErrorVersionConflict(err))
+	assert.Equal(t, int64(0), second.RowVersion)
+
+	account, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, int64(10), account.Balance)
+	assert.Equal(t, int64(1), account.RowVersion)
+}
+
+type Note struct {
+	Id          string    `json:"id"`
+	TenantId    string    `json:"tenantId"`
+	Text        string    `json:"text"`
+	CreatedBy   string    `json:"createdBy" ddd:"createdBy"`
+	CreatedTime time.Time `json:"createdTime" ddd:"createdTime"`
+	UpdatedBy   string    `json:"updatedBy" ddd:"updatedBy"`
+}
+
+func (n *Note) GetTenantId() string { return n.TenantId }
+func (n *Note) GetId() string       { return n.Id }
+
+func TestRepository_UpsertManyAudit(t *testing.T) {
+	now := time.Date(2022, 6, 1, 8, 0, 0, 0, time.UTC)
+	ddd_repository.SetClock(ddd_repository.ClockFunc(func() time.Time { return now }))
+	defer ddd_repository.SetClock(nil)
+
+	db, _ := newMovieRepository(t)
+	_, err := db.Exec(`CREATE TABLE note (id TEXT NOT NULL, tenant_id TEXT NOT NULL, text TEXT, created_by TEXT, created_time DATETIME, updated_by TEXT, PRIMARY KEY (tenant_id, id))`)
+	assert.NoError(t, err)
+	repos, err := NewRepository[*Note](func() *Note { return &Note{} }, db, SQLite, "")
+	assert.NoError(t, err)
+	ctx := ddd_context.NewContext(context.Background(), map[string]string{"X-User-Id": "u1"}, nil)
+	assert.NoError(t, repos.UpsertMany(ctx, []*Note{{Id: "1", TenantId: "t1", Text: "a"}}).GetError())
+
+	created := now
+	now = now.Add(time.Hour)
+	ctx = ddd_context.NewContext(context.Background(), map[string]string{"X-User-Id": "u2"}, nil)
+	assert.NoError(t, repos.UpsertMany(ctx, []*Note{{Id: "1", TenantId: "t1", Text: "b"}}).GetError())
+	note, _, _ := repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "b", note.Text)
+	assert.Equal(t, "u1", note.CreatedBy)
+	assert.True(t, created.Equal(note.CreatedTime))
+	assert.Equal(t, "u2", note.UpdatedBy)
+
+	assert.NoError(t, repos.Update(ctx, &Note{Id: "1", TenantId: "t1", Text: "c"}).GetError())
+	note, _, _ = repos.FindById(ctx, "t1", "1").Result()
+	assert.Equal(t, "c", note.Text)
+	assert.Equal(t, "u1", note.CreatedBy)
+	assert.True(t, created.Equal(note.CreatedTime))
+}
+
+func TestRepository_Aggregate(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	count, err := repos.Count(ctx, "t1", "year>2005")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	rows, err := repos.Aggregate(ctx, "t1", "", []string{"directorName"}, []ddd_repository.Metric{
+		ddd_repository.NewCountMetric("count"),
+		ddd_repository.NewSumMetric("sumYear", "year"),
+		ddd_repository.NewMaxMetric("maxRating", "rating"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, "Nolan", rows[0].GetGroupString("directorName"))
+	assert.Equal(t, int64(3), rows[0].GetInt("count"))
+	assert.Equal(t, int64(6024), rows[0].GetInt("sumYear"))
+	assert.Equal(t, 8.8, rows[0].GetFloat("maxRating"))
+	assert.Equal(t, "Tarantino", rows[1].GetGroupString("directorName"))
+	assert.Equal(t, int64(1), rows[1].GetInt("count"))
+
+	rows, err = repos.Aggregate(ctx, "t1", "", nil, []ddd_repository.Metric{ddd_repository.NewAvgMetric("avgYear", "year")})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(rows))
+	assert.Equal(t, 2006.75, rows[0].GetFloat("avgYear"))
+
+	_, err = repos.Aggregate(ctx, "t1", "", []string{"unknown"}, []ddd_repository.Metric{ddd_repository.NewCountMetric("count")})
+	assert.Error(t, err)
+
+	values, err := repos.Distinct(ctx, "t1", "directorName", "year<2012")
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{"Nolan", "Tarantino"}, values)
+}
+
+func TestRepository_ForEach(t *testing.T) {
+	_, repos := newMovieRepository(t)
+	insertMovies(t, repos)
+	ctx := context.Background()
+
+	names := make([]string, 0)
+	err := repos.ForEach(ctx, "t1", "directorName=='Nolan'", func(movie *Movie) error {
+		names = append(names, movie.Name)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Inception", "Interstellar", "Memento"}, names)
+
+	count := 0
+	err = repos.ForEach(ctx, "t1", "", func(movie *Movie) error {
+		count++
+		if count == 2 {
+			return ddd_repository.ErrStopIteration
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	stream, err := repos.FindStream(ctx, "t1", "", ddd_repository.NewFindOptions().SetSkip(1).SetLimit(2))
+	assert.NoError(t, err)
+	ids := make([]string, 0)
+	for stream.Next(ctx) {
+		ids = append(ids, stream.Current().Id)
+	}
+	assert.NoError(t, stream.Err())
+	assert.NoError(t, stream.Close(ctx))
+	assert.Equal(t, []string{"2", "3"}, ids)
+
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = repos.ForEach(cancelCtx, "t1", "", func(movie *Movie) error {
+		cancel()
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+}
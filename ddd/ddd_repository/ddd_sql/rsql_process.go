@@ -0,0 +1,261 @@
+package ddd_sql
+
+import (
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"strings"
+)
+
+type whereGroup struct {
+	parent *whereGroup
+	isOr   bool
+	items  []string
+}
+
+func (g *whereGroup) sql() string {
+	if len(g.items) == 0 {
+		return ""
+	}
+	if len(g.items) == 1 {
+		return g.items[0]
+	}
+	sep := " AND "
+	if g.isOr {
+		sep = " OR "
+	}
+	return "(" + strings.Join(g.items, sep) + ")"
+}
+
+//
+// SqlProcess
+// @Description: rsql 处理器，将 rsql 表达式转换为带参数的 WHERE 条件，参数占位符为 ?
+//
+type SqlProcess struct {
+	mapper  *entityMapper
+	dialect Dialect
+	root    *whereGroup
+	current *whereGroup
+	args    []interface{}
+	err     error
+}
+
+func newSqlProcess(mapper *entityMapper, dialect Dialect) *SqlProcess {
+	root := &whereGroup{}
+	return &SqlProcess{
+		mapper:  mapper,
+		dialect: dialect,
+		root:    root,
+		current: root,
+		args:    make([]interface{}, 0),
+	}
+}
+
+//
+// GetFilter
+// @Description: 获取 WHERE 条件与参数，并加入租户条件
+// @receiver p
+// @param tenantId 租户id
+// @return string WHERE 条件，不含 WHERE 关键字
+// @return []interface{} 参数
+// @return error
+//
+func (p *SqlProcess) GetFilter(tenantId string) (string, []interface{}, error) {
+	if p.err != nil {
+		return "", nil, p.err
+	}
+	where := p.dialect.Quote(TenantIdColumn) + " = ?"
+	args := append([]interface{}{tenantId}, p.args...)
+	if expr := p.root.sql(); len(expr) > 0 {
+		where = where + " AND " + expr
+	}
+	return where, args, nil
+}
+
+func (p *SqlProcess) OnAndItem() {
+}
+
+func (p *SqlProcess) OnAndStart() {
+	p.current = &whereGroup{parent: p.current}
+}
+
+func (p *SqlProcess) OnAndEnd() {
+	p.endGroup()
+}
+
+func (p *SqlProcess) OnOrItem() {
+}
+
+func (p *SqlProcess) OnOrStart() {
+	p.current = &whereGroup{parent: p.current, isOr: true}
+}
+
+func (p *SqlProcess) OnOrEnd() {
+	p.endGroup()
+}
+
+func (p *SqlProcess) OnEquals(name string, value interface{}, rValue rsql.Value) {
+	p.addCompare(name, "=", rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) OnNotEquals(name string, value interface{}, rValue rsql.Value) {
+	p.addCompare(name, "<>", rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) OnLike(name string, value interface{}, rValue rsql.Value) {
+	p.addLike(name, rsql.GetValue(rValue), false)
+}
+
+func (p *SqlProcess) OnNotLike(name string, value interface{}, rValue rsql.Value) {
+	p.addLike(name, rsql.GetValue(rValue), true)
+}
+
+func (p *SqlProcess) OnGreaterThan(name string, value interface{}, rValue rsql.Value) {
+	p.addCompare(name, ">", rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) OnGreaterThanOrEquals(name string, value interface{}, rValue rsql.Value) {
+	p.addCompare(name, ">=", rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) OnLessThan(name string, value interface{}, rValue rsql.Value) {
+	p.addCompare(name, "<", rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) OnLessThanOrEquals(name string, value interface{}, rValue rsql.Value) {
+	p.addCompare(name, "<=", rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) OnIn(name string, value interface{}, rValue rsql.Value) {
+	p.addIn(name, rValue, false)
+}
+
+func (p *SqlProcess) OnNotIn(name string, value interface{}, rValue rsql.Value) {
+	p.addIn(name, rValue, true)
+}
+
+//
+// OnSearch
+// @Description: 全文检索，转换为字段对各检索词的模糊查询，任一词匹配即可
+// @param name 字段名称
+// @param value 检索内容，多个词以空格分隔
+// @param rValue
+//
+func (p *SqlProcess) OnSearch(name string, value interface{}, rValue rsql.Value) {
+	p.addSearch(name, rsql.GetValue(rValue))
+}
+
+func (p *SqlProcess) endGroup() {
+	group := p.current
+	if group.parent == nil {
+		return
+	}
+	p.current = group.parent
+	if expr := group.sql(); len(expr) > 0 {
+		p.current.items = append(p.current.items, expr)
+	}
+}
+
+func (p *SqlProcess) column(name string) (string, bool) {
+	col, ok := p.mapper.getColumn(name)
+	if !ok {
+		if p.err == nil {
+			p.err = errors.New(fmt.Sprintf("rsql field %s does not exist in table %s", name, p.mapper.table))
+		}
+		return "", false
+	}
+	return p.dialect.Quote(col.name), true
+}
+
+func (p *SqlProcess) addCompare(name string, op string, value interface{}) {
+	col, ok := p.column(name)
+	if !ok {
+		return
+	}
+	p.current.items = append(p.current.items, fmt.Sprintf("%s %s ?", col, op))
+	p.args = append(p.args, value)
+}
+
+//
+//  addLike
+//  @Description: 模糊查询，忽略大小写。值中的 * 转换为 %，没有通配符时按包含匹配；值中的 % _ \ 按原字符匹配
+//
+func (p *SqlProcess) addLike(name string, value interface{}, not bool) {
+	col, ok := p.column(name)
+	if !ok {
+		return
+	}
+	pattern := escapeLike(fmt.Sprintf("%v", value))
+	if strings.Contains(pattern, "*") {
+		pattern = strings.ReplaceAll(pattern, "*", "%")
+	} else {
+		pattern = "%" + pattern + "%"
+	}
+	op := "LIKE"
+	if not {
+		op = "NOT LIKE"
+	}
+	p.current.items = append(p.current.items, p.likeExpr(col, op))
+	p.args = append(p.args, pattern)
+}
+
+//
+//  likeExpr
+//  @Description: 忽略大小写的 LIKE 表达式，使用反斜杠作为转义符
+//
+func (p *SqlProcess) likeExpr(col string, op string) string {
+	return fmt.Sprintf("LOWER(%s) %s LOWER(?) ESCAPE %s", col, op, p.dialect.LikeEscape())
+}
+
+//
+//  escapeLike
+//  @Description: 转义 LIKE 通配符，使用户输入按原字符匹配
+//
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func (p *SqlProcess) addIn(name string, rValue rsql.Value, not bool) {
+	col, ok := p.column(name)
+	if !ok {
+		return
+	}
+	listValue, _ := rValue.(rsql.ListValue)
+	values := rsql.GetValueList(listValue)
+	if len(values) == 0 {
+		if not {
+			p.current.items = append(p.current.items, "1 = 1")
+		} else {
+			p.current.items = append(p.current.items, "1 = 0")
+		}
+		return
+	}
+	op := "IN"
+	if not {
+		op = "NOT IN"
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+	p.current.items = append(p.current.items, fmt.Sprintf("%s %s (%s)", col, op, placeholders))
+	p.args = append(p.args, values...)
+}
+
+func (p *SqlProcess) addSearch(name string, value interface{}) {
+	col, ok := p.column(name)
+	if !ok {
+		return
+	}
+	terms := strings.Fields(strings.ReplaceAll(fmt.Sprintf("%v", value), "\"", " "))
+	if len(terms) == 0 {
+		p.current.items = append(p.current.items, "1 = 0")
+		return
+	}
+	items := make([]string, len(terms))
+	for i, term := range terms {
+		items[i] = p.likeExpr(col, "LIKE")
+		p.args = append(p.args, "%"+escapeLike(term)+"%")
+	}
+	group := &whereGroup{items: items, isOr: true}
+	p.current.items = append(p.current.items, group.sql())
+}
@@ -0,0 +1,59 @@
+package ddd_sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+)
+
+type SqlSession struct {
+	db *sql.DB
+}
+
+type sqlTxKey struct {
+}
+
+// executor 可执行sql语句的对象，*sql.DB 或 *sql.Tx
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func NewSession(db *sql.DB) ddd_repository.Session {
+	return &SqlSession{db: db}
+}
+
+//
+// UseTransaction
+// @Description: 在数据库事务中执行，dbFunc 返回错误时回滚。已在事务中时加入当前事务
+// @receiver s
+// @param ctx
+// @param dbFunc
+// @return error
+//
+func (s *SqlSession) UseTransaction(ctx context.Context, dbFunc ddd_repository.SessionFunc) error {
+	if getSqlTx(ctx) != nil {
+		return dbFunc(ctx)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = dbFunc(context.WithValue(ctx, sqlTxKey{}, tx))
+	if err != nil {
+		if e1 := tx.Rollback(); e1 != nil {
+			return fmt.Errorf("%w; rollback: %v", err, e1)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func getSqlTx(ctx context.Context) *sql.Tx {
+	if ctx == nil {
+		return nil
+	}
+	tx, _ := ctx.Value(sqlTxKey{}).(*sql.Tx)
+	return tx
+}
@@ -0,0 +1,284 @@
+package ddd_repository
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	CreatedByField   = "createdBy"   // 实现 AuditEntity 接口时创建人的字段名称，也是结构标签 ddd:"createdBy"
+	CreatedTimeField = "createdTime" // 实现 AuditEntity 接口时创建时间的字段名称，也是结构标签 ddd:"createdTime"
+	UpdatedByField   = "updatedBy"   // 实现 AuditEntity 接口时更新人的字段名称，也是结构标签 ddd:"updatedBy"
+	UpdatedTimeField = "updatedTime" // 实现 AuditEntity 接口时更新时间的字段名称，也是结构标签 ddd:"updatedTime"
+
+	DefaultAuditUserHeader = "X-User-Id" // 默认从此请求头中获取操作人
+)
+
+//
+// AuditEntity
+// @Description: 带有审计字段的实体，仓储新建与更新时自动设置
+//
+type AuditEntity interface {
+	GetCreatedBy() string
+	SetCreatedBy(createdBy string)
+	GetCreatedTime() time.Time
+	SetCreatedTime(createdTime time.Time)
+	SetUpdatedBy(updatedBy string)
+	SetUpdatedTime(updatedTime time.Time)
+}
+
+//
+// Clock
+// @Description: 时钟，审计时间从此获取，测试时可替换
+//
+type Clock interface {
+	Now() time.Time
+}
+
+//
+// ClockFunc
+// @Description: 将方法转换为时钟
+//
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+var (
+	auditClock      Clock = ClockFunc(time.Now)
+	auditUserHeader       = DefaultAuditUserHeader
+)
+
+//
+// SetClock
+// @Description: 设置审计时间使用的时钟，为nil时恢复使用系统时间
+// @param clock 时钟
+//
+func SetClock(clock Clock) {
+	if clock == nil {
+		clock = ClockFunc(time.Now)
+	}
+	auditClock = clock
+}
+
+//
+// GetClock
+// @Description: 获取审计时间使用的时钟
+// @return Clock
+//
+func GetClock() Clock {
+	return auditClock
+}
+
+//
+// SetAuditUserHeader
+// @Description: 设置获取操作人的请求头，默认为 X-User-Id
+// @param header 请求头名称
+//
+func SetAuditUserHeader(header string) {
+	auditUserHeader = header
+}
+
+//
+// GetAuditUser
+// @Description: 从ctx的metadata中获取操作人，请求头名称不区分大小写
+// @param ctx 上下文
+// @return string 操作人，没有时为空
+//
+func GetAuditUser(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	metadata := *ddd_context.GetMetadataContext(ctx)
+	if user, ok := metadata[auditUserHeader]; ok {
+		return user
+	}
+	for k, v := range metadata {
+		if strings.EqualFold(k, auditUserHeader) {
+			return v
+		}
+	}
+	return ""
+}
+
+//
+// SetInsertAudit
+// @Description: 新建时设置审计字段。已有值的创建人与创建时间保留原值，更新人与更新时间与创建时相同
+// @param ctx 上下文，从中获取操作人
+// @param entity 实体，结构标签方式时须为结构指针
+//
+func SetInsertAudit(ctx context.Context, entity interface{}) {
+	user, now := GetAuditUser(ctx), auditClock.Now()
+	if e, ok := entity.(AuditEntity); ok {
+		if e.GetCreatedBy() == "" {
+			e.SetCreatedBy(user)
+		}
+		if e.GetCreatedTime().IsZero() {
+			e.SetCreatedTime(now)
+		}
+		e.SetUpdatedBy(user)
+		e.SetUpdatedTime(now)
+		return
+	}
+	for _, field := range getAuditFields(entity) {
+		switch field.tag {
+		case CreatedByField, CreatedTimeField:
+			if field.value.IsZero() {
+				field.set(user, now)
+			}
+		case UpdatedByField, UpdatedTimeField:
+			field.set(user, now)
+		}
+	}
+}
+
+//
+// SetUpdateAudit
+// @Description: 更新时设置更新人与更新时间
+// @param ctx 上下文，从中获取操作人
+// @param entity 实体，结构标签方式时须为结构指针
+// @return []string 设置的字段名称，json名称或字段名，可用于追加到更新掩码
+//
+func SetUpdateAudit(ctx context.Context, entity interface{}) []string {
+	user, now := GetAuditUser(ctx), auditClock.Now()
+	if e, ok := entity.(AuditEntity); ok {
+		e.SetUpdatedBy(user)
+		e.SetUpdatedTime(now)
+		return []string{UpdatedByField, UpdatedTimeField}
+	}
+	var names []string
+	for _, field := range getAuditFields(entity) {
+		if field.tag == UpdatedByField || field.tag == UpdatedTimeField {
+			field.set(user, now)
+			names = append(names, field.name)
+		}
+	}
+	return names
+}
+
+//
+// GetCreatedAuditFields
+// @Description: 获取实体的创建人与创建时间字段，新建或更新时用于只在新建时写入这些字段
+// @param entity 实体，结构标签方式时须为结构指针
+// @return []string 字段名称，json名称或字段名
+//
+func GetCreatedAuditFields(entity interface{}) []string {
+	if _, ok := entity.(AuditEntity); ok {
+		return []string{CreatedByField, CreatedTimeField}
+	}
+	var names []string
+	for _, field := range getAuditFields(entity) {
+		if field.tag == CreatedByField || field.tag == CreatedTimeField {
+			names = append(names, field.name)
+		}
+	}
+	return names
+}
+
+//
+// CopyCreatedAudit
+// @Description: 将已有数据的创建人与创建时间复制到实体，新建或更新已有数据时保留原创建信息
+// @param entity 实体，结构标签方式时须为结构指针
+// @param existing 已有数据，与实体类型相同
+//
+func CopyCreatedAudit(entity interface{}, existing interface{}) {
+	if e, ok := entity.(AuditEntity); ok {
+		if src, ok := existing.(AuditEntity); ok {
+			e.SetCreatedBy(src.GetCreatedBy())
+			e.SetCreatedTime(src.GetCreatedTime())
+		}
+		return
+	}
+	sources := make(map[string]*auditField)
+	for _, field := range getAuditFields(existing) {
+		sources[field.tag] = field
+	}
+	for _, field := range getAuditFields(entity) {
+		if field.tag != CreatedByField && field.tag != CreatedTimeField {
+			continue
+		}
+		if src, ok := sources[field.tag]; ok && src.value.Type() == field.value.Type() {
+			field.value.Set(src.value)
+		}
+	}
+}
+
+// 通过结构标签声明的审计字段
+type auditField struct {
+	tag   string
+	name  string
+	value reflect.Value
+}
+
+func (f *auditField) set(user string, now time.Time) {
+	switch f.tag {
+	case CreatedByField, UpdatedByField:
+		if f.value.Kind() == reflect.String {
+			f.value.SetString(user)
+		}
+	case CreatedTimeField, UpdatedTimeField:
+		setTimeValue(f.value, now)
+	}
+}
+
+//
+//  getAuditFields
+//  @Description: 获取实体中标记为 ddd:"createdBy"、ddd:"createdTime"、ddd:"updatedBy"、ddd:"updatedTime" 的字段，匿名结构的字段视为当前结构的字段
+//
+func getAuditFields(entity interface{}) []*auditField {
+	v := reflect.ValueOf(entity)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	return appendAuditFields(nil, v.Elem())
+}
+
+func appendAuditFields(fields []*auditField, v reflect.Value) []*auditField {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			fields = appendAuditFields(fields, v.Field(i))
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("ddd")
+		for _, name := range []string{CreatedByField, CreatedTimeField, UpdatedByField, UpdatedTimeField} {
+			if !hasTagOption(tag, name) {
+				continue
+			}
+			jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+			if jsonName == "" || jsonName == "-" {
+				jsonName = field.Name
+			}
+			fields = append(fields, &auditField{tag: name, name: jsonName, value: v.Field(i)})
+		}
+	}
+	return fields
+}
+
+//
+//  setTimeValue
+//  @Description: 设置时间字段，支持 time.Time、*time.Time 及可由 time.Time 转换的类型
+//
+func setTimeValue(v reflect.Value, now time.Time) {
+	timeValue := reflect.ValueOf(now)
+	if v.Kind() == reflect.Ptr {
+		if !timeValue.CanConvert(v.Type().Elem()) {
+			return
+		}
+		ptr := reflect.New(v.Type().Elem())
+		ptr.Elem().Set(timeValue.Convert(v.Type().Elem()))
+		v.Set(ptr)
+		return
+	}
+	if timeValue.CanConvert(v.Type()) {
+		v.Set(timeValue.Convert(v.Type()))
+	}
+}
@@ -0,0 +1,92 @@
+package ddd_repository
+
+import (
+	"reflect"
+	"strings"
+)
+
+const (
+	VersionField = "version" // 实现 VersionEntity 接口时版本号的字段名称
+	VersionTag   = "version" // 通过结构标签 ddd:"version" 指定版本号字段
+)
+
+//
+// VersionEntity
+// @Description: 带有乐观锁版本号的实体，版本号保存在 version 字段中
+//
+type VersionEntity interface {
+	GetVersion() int64
+	SetVersion(version int64)
+}
+
+//
+// EntityVersion
+// @Description: 实体的乐观锁版本号
+//
+type EntityVersion struct {
+	Name  string // 字段名称，json名称或字段名
+	Value int64  // 当前版本号
+	set   func(version int64)
+}
+
+//
+// Set
+// @Description: 设置实体的版本号
+// @param version 版本号
+//
+func (v *EntityVersion) Set(version int64) {
+	v.set(version)
+	v.Value = version
+}
+
+//
+// GetEntityVersion
+// @Description: 获取实体的乐观锁版本号。实体实现 VersionEntity 接口，或有标记为 ddd:"version" 的整数字段时启用乐观锁
+// @param entity 实体，结构标签方式时须为结构指针
+// @return *EntityVersion 版本号
+// @return bool 实体是否启用乐观锁
+//
+func GetEntityVersion(entity interface{}) (*EntityVersion, bool) {
+	if e, ok := entity.(VersionEntity); ok {
+		return &EntityVersion{Name: VersionField, Value: e.GetVersion(), set: e.SetVersion}, true
+	}
+	v := reflect.ValueOf(entity)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, false
+	}
+	v = v.Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() || !hasTagOption(field.Tag.Get("ddd"), VersionTag) {
+			continue
+		}
+		fieldValue := v.Field(i)
+		switch fieldValue.Kind() {
+		case reflect.Int, reflect.Int32, reflect.Int64:
+		default:
+			return nil, false
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+		return &EntityVersion{
+			Name:  name,
+			Value: fieldValue.Int(),
+			set: func(version int64) {
+				fieldValue.SetInt(version)
+			},
+		}, true
+	}
+	return nil, false
+}
+
+func hasTagOption(tag string, option string) bool {
+	for _, item := range strings.Split(tag, ",") {
+		if strings.TrimSpace(item) == option {
+			return true
+		}
+	}
+	return false
+}
@@ -13,7 +13,7 @@
 // group      : '(' or ')'
 // comparison : identifier comparator arguments
 // identifier : [a-zA-Z0-9]+('.'[a-zA-Z0-9]+)*
-// comparator : '==' | '!=' | '==~' | '!=~' | '>' | '>=' | '<' | '<=' | '=in=' | '=out='
+// comparator : '==' | '!=' | '==~' | '!=~' | '>' | '>=' | '<' | '<=' | '=in=' | '=out=' | '=search='
 // arguments  : '(' listValue ')' | value
 // value      : int | double | string | date | datetime | boolean
 // listValue  : value(','value)*
@@ -34,6 +34,8 @@ type FindPagingQuery interface {
 	GetSort() string
 	GetPageNum() int64
 	GetPageSize() int64
+	GetContinuationToken() string
+	GetIsTotalRows() bool
 
 	SetTenantId(string)
 	SetFields(string)
@@ -41,6 +43,8 @@ type FindPagingQuery interface {
 	SetSort(string)
 	SetPageNum(int64)
 	SetPageSize(int64)
+	SetContinuationToken(string)
+	SetIsTotalRows(bool)
 }
 
 func NewFindPagingQuery() FindPagingQuery {
@@ -49,12 +53,14 @@ func NewFindPagingQuery() FindPagingQuery {
 }
 
 type findPagingQuery struct {
-	TenantId string
-	Fields   string
-	Filter   string
-	Sort     string
-	PageNum  int64
-	PageSize int64
+	TenantId          string
+	Fields            string
+	Filter            string
+	Sort              string
+	PageNum           int64
+	PageSize          int64
+	ContinuationToken string // 游标分页的续查令牌，不为空时忽略PageNum，从上一页最后一条数据之后开始查询
+	IsTotalRows       *bool  // 是否统计总行数，为nil时统计
 }
 
 func (q *findPagingQuery) SetTenantId(value string) {
@@ -104,3 +110,19 @@ func (q *findPagingQuery) GetPageNum() int64 {
 func (q *findPagingQuery) GetPageSize() int64 {
 	return q.PageSize
 }
+
+func (q *findPagingQuery) SetContinuationToken(value string) {
+	q.ContinuationToken = value
+}
+
+func (q *findPagingQuery) SetIsTotalRows(value bool) {
+	q.IsTotalRows = &value
+}
+
+func (q *findPagingQuery) GetContinuationToken() string {
+	return q.ContinuationToken
+}
+
+func (q *findPagingQuery) GetIsTotalRows() bool {
+	return q.IsTotalRows == nil || *q.IsTotalRows
+}
@@ -5,15 +5,16 @@ import (
 )
 
 type FindPagingResult[T ddd.Entity] struct {
-	Data       *[]T   `json:"data"`
-	TotalRows  int64  `json:"totalRows"`
-	TotalPages int64  `json:"totalPages"`
-	PageNum    int64  `json:"pageNum"`
-	PageSize   int64  `json:"pageSize"`
-	Filter     string `json:"filter"`
-	Sort       string `json:"sort"`
-	Error      error  `json:"-"`
-	IsFound    bool   `json:"-"`
+	Data              *[]T   `json:"data"`
+	TotalRows         int64  `json:"totalRows"`
+	TotalPages        int64  `json:"totalPages"`
+	PageNum           int64  `json:"pageNum"`
+	PageSize          int64  `json:"pageSize"`
+	Filter            string `json:"filter"`
+	Sort              string `json:"sort"`
+	ContinuationToken string `json:"continuationToken,omitempty"` // 下一页的续查令牌，为空时没有下一页
+	Error             error  `json:"-"`
+	IsFound           bool   `json:"-"`
 }
 
 func NewFindPagingResult[T ddd.Entity](data *[]T, totalRows int64, query FindPagingQuery, err error) *FindPagingResult[T] {
@@ -26,7 +27,7 @@ func NewFindPagingResult[T ddd.Entity](data *[]T, totalRows int64, query FindPag
 			PageSize:   query.GetPageSize(),
 			Sort:       query.GetSort(),
 			Filter:     query.GetFilter(),
-			IsFound:    totalRows > 0,
+			IsFound:    totalRows > 0 || len(*data) > 0,
 			Error:      err,
 		}
 	}
@@ -127,6 +128,10 @@ func (f *FindPagingResult[T]) GetSort() string {
 	return f.Sort
 }
 
+func (f *FindPagingResult[T]) GetContinuationToken() string {
+	return f.ContinuationToken
+}
+
 type FindPagingResultOptions[T ddd.Entity] struct {
 	Data       *[]T
 	TotalRows  int64
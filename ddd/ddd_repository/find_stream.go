@@ -0,0 +1,49 @@
+package ddd_repository
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStopIteration ForEach 的回调返回此错误时停止遍历，ForEach 返回nil
+var ErrStopIteration = errors.New("stop iteration")
+
+//
+// FindStream
+// @Description: 查询结果的迭代器，逐条读取数据，不会一次加载全部结果。使用完后必须调用 Close
+//
+type FindStream[T interface{}] interface {
+	// Next 读取下一条数据，没有数据、出错或ctx取消时返回false
+	Next(ctx context.Context) bool
+	// Current 当前数据
+	Current() T
+	// Err 遍历中的错误，ctx取消时为ctx的错误
+	Err() error
+	// Close 释放游标
+	Close(ctx context.Context) error
+}
+
+//
+// ForEachStream
+// @Description: 遍历迭代器并在结束后关闭
+// @param ctx 上下文，取消时停止遍历并返回ctx的错误
+// @param stream 迭代器
+// @param fun 回调，返回 ErrStopIteration 时提前停止，返回其它错误时停止并返回该错误
+// @return error
+//
+func ForEachStream[T interface{}](ctx context.Context, stream FindStream[T], fun func(entity T) error) (err error) {
+	defer func() {
+		if closeErr := stream.Close(ctx); err == nil {
+			err = closeErr
+		}
+	}()
+	for stream.Next(ctx) {
+		if err := fun(stream.Current()); err != nil {
+			if errors.Is(err, ErrStopIteration) {
+				return nil
+			}
+			return err
+		}
+	}
+	return stream.Err()
+}
@@ -9,7 +9,11 @@ import (
 type Repository[T ddd.Entity] interface {
 	Insert(ctx context.Context, entity T, opts ...*SetOptions) *SetResult[T]
 	Update(ctx context.Context, entity T, opts ...*SetOptions) *SetResult[T]
+	InsertMany(ctx context.Context, entities []T, opts ...*SetOptions) *SetManyResult[T]
+	UpdateMany(ctx context.Context, entities []T, opts ...*SetOptions) *SetManyResult[T]
+	UpsertMany(ctx context.Context, entities []T, opts ...*SetOptions) *SetManyResult[T]
 	Delete(ctx context.Context, entity ddd.Entity, opts ...*SetOptions) *SetResult[T]
+	DeleteByIds(ctx context.Context, tenantId string, ids []string, opts ...*SetOptions) *SetManyResult[T]
 	DeleteById(ctx context.Context, tenantId string, id string, opts ...*SetOptions) *SetResult[T]
 	DeleteAll(ctx context.Context, tenantId string, opts ...*SetOptions) *SetResult[T]
 	DeleteByMap(ctx context.Context, tenantId string, data map[string]interface{}, opts ...*SetOptions) *SetResult[T]
@@ -19,6 +23,11 @@ type Repository[T ddd.Entity] interface {
 	FindListByMap(ctx context.Context, tenantId string, filterMap map[string]interface{}, opts ...*FindOptions) *FindListResult[T]
 	FindAll(ctx context.Context, tenantId string, opts ...*FindOptions) *FindListResult[T]
 	FindPaging(ctx context.Context, query FindPagingQuery, opts ...*FindOptions) *FindPagingResult[T]
+	Count(ctx context.Context, tenantId string, filter string) (int64, error)
+	Aggregate(ctx context.Context, tenantId string, filter string, groupBy []string, metrics []Metric) ([]*AggregateRow, error)
+	Distinct(ctx context.Context, tenantId string, field string, filter string) ([]interface{}, error)
+	FindStream(ctx context.Context, tenantId string, filter string, opts ...*FindOptions) (FindStream[T], error)
+	ForEach(ctx context.Context, tenantId string, filter string, fun func(entity T) error, opts ...*FindOptions) error
 	DoFilter(tenantId string, filter string, fun func(filter map[string]interface{}) (*FindPagingResult[T], bool, error)) *FindPagingResult[T]
 	DoFindList(fun func() (*[]T, bool, error)) *FindListResult[T]
 	DoFindOne(fun func() (T, bool, error)) *FindOneResult[T]
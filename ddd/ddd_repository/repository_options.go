@@ -2,8 +2,41 @@ package ddd_repository
 
 import "time"
 
+//
+// ReadPreference
+// @Description: 读取偏好，指定从主节点或从节点读取
+//
+type ReadPreference string
+
+const (
+	ReadPrimary            ReadPreference = "primary"
+	ReadPrimaryPreferred   ReadPreference = "primaryPreferred"
+	ReadSecondary          ReadPreference = "secondary"
+	ReadSecondaryPreferred ReadPreference = "secondaryPreferred"
+	ReadNearest            ReadPreference = "nearest"
+)
+
+//
+// Collation
+// @Description: 字符串比较规则，如按语言排序或忽略大小写
+//
+type Collation struct {
+	Locale          string `json:"locale"`          // 语言，如 zh、en
+	CaseLevel       bool   `json:"caseLevel"`       // 是否区分大小写
+	Strength        int    `json:"strength"`        // 比较级别 1-5，1只比较基本字符，2再比较重音，3再比较大小写
+	NumericOrdering bool   `json:"numericOrdering"` // 数字字符串是否按数值比较
+}
+
 type FindOptions struct {
-	MaxTime *time.Duration
+	MaxTime        *time.Duration
+	IncludeDeleted *bool           // 启用软删除的仓储中，是否包含已删除的数据，默认为false
+	Fields         *string         // 返回字段，以逗号分隔，以-开头表示排除，如 name,address.city 或 -password
+	Hint           *string         // 指定使用的索引名称
+	Collation      *Collation      // 字符串比较规则
+	ReadPreference *ReadPreference // 读取偏好
+	Limit          *int64          // 最多返回的数量，分页查询时以分页大小为准
+	Skip           *int64          // 跳过的数量，分页查询时以页码为准
+	BatchSize      *int32          // 流式查询时每批从数据库读取的数量
 }
 
 type FindOneOptions struct {
@@ -11,7 +44,11 @@ type FindOneOptions struct {
 }
 
 type SetOptions struct {
-	MaxTime *time.Duration
+	MaxTime          *time.Duration // Mongo 写入的最长执行时间，超时后取消写入
+	Ordered          *bool          // 批量写入时是否有序执行，有序时遇到错误停止，默认为true
+	Upsert           *bool          // 更新时数据不存在是否新建，默认为false
+	BypassValidation *bool          // 是否跳过数据库的文档校验，默认为false
+	WriteConcern     *string        // 写关注，majority 或确认写入的节点数，如 1，为空时使用数据库的设置
 }
 
 func MergeFindOptions(opts ...*FindOptions) *FindOptions {
@@ -20,20 +57,197 @@ func MergeFindOptions(opts ...*FindOptions) *FindOptions {
 		if o.MaxTime != nil {
 			res.MaxTime = o.MaxTime
 		}
+		if o.IncludeDeleted != nil {
+			res.IncludeDeleted = o.IncludeDeleted
+		}
+		if o.Fields != nil {
+			res.Fields = o.Fields
+		}
+		if o.Hint != nil {
+			res.Hint = o.Hint
+		}
+		if o.Collation != nil {
+			res.Collation = o.Collation
+		}
+		if o.ReadPreference != nil {
+			res.ReadPreference = o.ReadPreference
+		}
+		if o.Limit != nil {
+			res.Limit = o.Limit
+		}
+		if o.Skip != nil {
+			res.Skip = o.Skip
+		}
+		if o.BatchSize != nil {
+			res.BatchSize = o.BatchSize
+		}
 	}
 	return res
 }
 
+func NewFindOptions() *FindOptions {
+	return &FindOptions{}
+}
+
+func (o *FindOptions) SetIncludeDeleted(includeDeleted bool) *FindOptions {
+	o.IncludeDeleted = &includeDeleted
+	return o
+}
+
+func (o *FindOptions) GetIncludeDeleted() bool {
+	if o.IncludeDeleted == nil {
+		return false
+	}
+	return *o.IncludeDeleted
+}
+
+func (o *FindOptions) SetFields(fields string) *FindOptions {
+	o.Fields = &fields
+	return o
+}
+
+func (o *FindOptions) GetFields() string {
+	if o.Fields == nil {
+		return ""
+	}
+	return *o.Fields
+}
+
+func (o *FindOptions) SetMaxTime(maxTime time.Duration) *FindOptions {
+	o.MaxTime = &maxTime
+	return o
+}
+
+func (o *FindOptions) SetHint(hint string) *FindOptions {
+	o.Hint = &hint
+	return o
+}
+
+func (o *FindOptions) GetHint() string {
+	if o.Hint == nil {
+		return ""
+	}
+	return *o.Hint
+}
+
+func (o *FindOptions) SetCollation(collation *Collation) *FindOptions {
+	o.Collation = collation
+	return o
+}
+
+func (o *FindOptions) SetReadPreference(readPreference ReadPreference) *FindOptions {
+	o.ReadPreference = &readPreference
+	return o
+}
+
+func (o *FindOptions) GetReadPreference() ReadPreference {
+	if o.ReadPreference == nil {
+		return ""
+	}
+	return *o.ReadPreference
+}
+
+func (o *FindOptions) SetLimit(limit int64) *FindOptions {
+	o.Limit = &limit
+	return o
+}
+
+func (o *FindOptions) SetSkip(skip int64) *FindOptions {
+	o.Skip = &skip
+	return o
+}
+
+func (o *FindOptions) SetBatchSize(batchSize int32) *FindOptions {
+	o.BatchSize = &batchSize
+	return o
+}
+
+func (o *FindOptions) GetBatchSize() int32 {
+	if o.BatchSize == nil {
+		return 0
+	}
+	return *o.BatchSize
+}
+
 func MergeSetOptions(opts ...*SetOptions) *SetOptions {
 	res := &SetOptions{}
 	for _, o := range opts {
 		if o.MaxTime != nil {
 			res.MaxTime = o.MaxTime
 		}
+		if o.Ordered != nil {
+			res.Ordered = o.Ordered
+		}
+		if o.Upsert != nil {
+			res.Upsert = o.Upsert
+		}
+		if o.BypassValidation != nil {
+			res.BypassValidation = o.BypassValidation
+		}
+		if o.WriteConcern != nil {
+			res.WriteConcern = o.WriteConcern
+		}
 	}
 	return res
 }
 
+func NewSetOptions() *SetOptions {
+	return &SetOptions{}
+}
+
+func (o *SetOptions) SetOrdered(ordered bool) *SetOptions {
+	o.Ordered = &ordered
+	return o
+}
+
+func (o *SetOptions) GetOrdered() bool {
+	if o.Ordered == nil {
+		return true
+	}
+	return *o.Ordered
+}
+
+func (o *SetOptions) SetMaxTime(maxTime time.Duration) *SetOptions {
+	o.MaxTime = &maxTime
+	return o
+}
+
+func (o *SetOptions) SetUpsert(upsert bool) *SetOptions {
+	o.Upsert = &upsert
+	return o
+}
+
+func (o *SetOptions) GetUpsert() bool {
+	if o.Upsert == nil {
+		return false
+	}
+	return *o.Upsert
+}
+
+func (o *SetOptions) SetBypassValidation(bypassValidation bool) *SetOptions {
+	o.BypassValidation = &bypassValidation
+	return o
+}
+
+func (o *SetOptions) GetBypassValidation() bool {
+	if o.BypassValidation == nil {
+		return false
+	}
+	return *o.BypassValidation
+}
+
+func (o *SetOptions) SetWriteConcern(writeConcern string) *SetOptions {
+	o.WriteConcern = &writeConcern
+	return o
+}
+
+func (o *SetOptions) GetWriteConcern() string {
+	if o.WriteConcern == nil {
+		return ""
+	}
+	return *o.WriteConcern
+}
+
 /*
 type FindOptions struct {
 	Error       error
@@ -8,6 +8,19 @@ type Session interface {
 
 type SessionFunc func(ctx context.Context) error
 
-func StartSession(ctx context.Context, session Session, dbFunc SessionFunc) error {
-	return session.UseTransaction(ctx, dbFunc)
+//
+// StartSession
+// @Description: 在工作单元中执行事务。已在工作单元中时加入当前事务，不重试；否则开启新的工作单元，
+// 提交后执行 OnCommit 注册的方法，回滚后执行 OnRollback 注册的方法，Session 判断为临时错误时重试整个事务
+// @param ctx 上下文
+// @param session 事务
+// @param dbFunc 在事务中执行的方法，重试时会多次执行
+// @param opts 事务设置
+// @return error
+//
+func StartSession(ctx context.Context, session Session, dbFunc SessionFunc, opts ...*SessionOptions) error {
+	if _, ok := GetUnitOfWork(ctx); ok {
+		return session.UseTransaction(ctx, dbFunc)
+	}
+	return runUnitOfWork(ctx, session, dbFunc, opts...)
 }
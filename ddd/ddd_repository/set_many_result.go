@@ -0,0 +1,166 @@
+package ddd_repository
+
+import (
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+)
+
+// ErrNotExecuted 有序批量写入中，前面的写入失败后未执行的项
+var ErrNotExecuted = errors.New("not executed because a previous write failed")
+
+//
+// SetManyItem
+// @Description: 批量写入中单项的执行结果
+//
+type SetManyItem struct {
+	Index int    // 在传入列表中的序号
+	Id    string // 实体id
+	Error error  // 为nil时表示成功
+}
+
+func (i *SetManyItem) IsSuccess() bool {
+	return i.Error == nil
+}
+
+//
+// SetManyResult
+// @Description: 批量写入结果，包含每一项的执行结果
+//
+type SetManyResult[T ddd.Entity] struct {
+	err   error
+	data  []T
+	items []*SetManyItem
+}
+
+//
+// NewSetManyResult
+// @Description: 新建批量写入结果，任一项失败时 GetError() 返回汇总错误
+// @param data 实体列表
+// @param items 每一项的执行结果
+// @param err 整体错误，如连接失败
+// @return *SetManyResult[T]
+//
+func NewSetManyResult[T ddd.Entity](data []T, items []*SetManyItem, err error) *SetManyResult[T] {
+	res := &SetManyResult[T]{
+		data:  data,
+		items: items,
+		err:   err,
+	}
+	if res.err == nil {
+		if failed := res.GetFailedItems(); len(failed) > 0 {
+			res.err = errors.New(fmt.Sprintf("%d of %d items failed, first error at index %d: %s",
+				len(failed), len(items), failed[0].Index, failed[0].Error.Error()))
+		}
+	}
+	return res
+}
+
+func NewSetManyResultError[T ddd.Entity](err error) *SetManyResult[T] {
+	return &SetManyResult[T]{
+		err:   err,
+		items: make([]*SetManyItem, 0),
+	}
+}
+
+func (s *SetManyResult[T]) GetError() error {
+	return s.err
+}
+
+func (s *SetManyResult[T]) GetData() []T {
+	return s.data
+}
+
+func (s *SetManyResult[T]) GetItems() []*SetManyItem {
+	return s.items
+}
+
+func (s *SetManyResult[T]) GetSuccessItems() []*SetManyItem {
+	res := make([]*SetManyItem, 0)
+	for _, item := range s.items {
+		if item.IsSuccess() {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func (s *SetManyResult[T]) GetFailedItems() []*SetManyItem {
+	res := make([]*SetManyItem, 0)
+	for _, item := range s.items {
+		if !item.IsSuccess() {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+func (s *SetManyResult[T]) Result() ([]T, error) {
+	return s.data, s.err
+}
+
+func (s *SetManyResult[T]) OnSuccess(success OnSuccess[[]T]) *SetManyResult[T] {
+	if s.err == nil && success != nil {
+		s.err = success(s.data)
+	}
+	return s
+}
+
+func (s *SetManyResult[T]) OnError(err OnError) *SetManyResult[T] {
+	if s.err != nil && err != nil {
+		s.err = err(s.err)
+	}
+	return s
+}
+
+//
+// NewSetManyItems
+// @Description: 新建全部成功的执行结果列表，供仓储实现使用
+// @param ids 实体id列表
+// @return []*SetManyItem
+//
+func NewSetManyItems(ids []string) []*SetManyItem {
+	items := make([]*SetManyItem, len(ids))
+	for i, id := range ids {
+		items[i] = &SetManyItem{Index: i, Id: id}
+	}
+	return items
+}
+
+//
+// GetEntityIds
+// @Description: 获取实体id列表
+// @param entities 实体列表
+// @return []string
+//
+func GetEntityIds[T ddd.Entity](entities []T) []string {
+	ids := make([]string, len(entities))
+	for i, e := range entities {
+		ids[i] = e.GetId()
+	}
+	return ids
+}
+
+//
+// SetManyEach
+// @Description: 逐项执行写入，供不支持批量写入的仓储实现使用。有序执行时第一个失败项之后的项不再执行
+// @param ids 实体id列表
+// @param ordered 是否有序执行
+// @param write 写入第i项
+// @return []*SetManyItem 每一项的执行结果
+//
+func SetManyEach(ids []string, ordered bool, write func(i int) error) []*SetManyItem {
+	items := NewSetManyItems(ids)
+	failed := false
+	for i, item := range items {
+		if failed && ordered {
+			item.Error = ErrNotExecuted
+			continue
+		}
+		if err := write(i); err != nil {
+			item.Error = err
+			failed = true
+		}
+	}
+	return items
+}
@@ -0,0 +1,185 @@
+package ddd_repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+//
+// UnitOfWork
+// @Description: 工作单元，对应一次 StartSession 开启的事务。嵌套的 StartSession 加入当前工作单元，
+// 通过 OnCommit、OnRollback 注册的方法在最外层事务提交或回滚后执行
+//
+type UnitOfWork struct {
+	mu        sync.Mutex
+	commits   []CommitFunc
+	rollbacks []RollbackFunc
+}
+
+// CommitFunc 事务提交后执行的方法，ctx为开启事务时的上下文
+type CommitFunc func(ctx context.Context)
+
+// RollbackFunc 事务回滚后执行的方法，err为回滚的原因
+type RollbackFunc func(ctx context.Context, err error)
+
+//
+// TransientErrorChecker
+// @Description: Session 实现此接口时，StartSession 对临时错误重试整个事务
+//
+type TransientErrorChecker interface {
+	IsTransientError(err error) bool
+}
+
+type unitOfWorkKey struct {
+}
+
+const (
+	defaultMaxRetries   = 3
+	defaultRetryBackoff = 50 * time.Millisecond
+)
+
+//
+// SessionOptions
+// @Description: 事务设置
+//
+type SessionOptions struct {
+	MaxRetries   *int           // 临时错误的最大重试次数，默认为3，为0时不重试
+	RetryBackoff *time.Duration // 重试的等待时间，按重试次数递增，默认为50毫秒
+}
+
+func NewSessionOptions() *SessionOptions {
+	return &SessionOptions{}
+}
+
+func (o *SessionOptions) SetMaxRetries(maxRetries int) *SessionOptions {
+	o.MaxRetries = &maxRetries
+	return o
+}
+
+func (o *SessionOptions) GetMaxRetries() int {
+	if o.MaxRetries == nil {
+		return defaultMaxRetries
+	}
+	return *o.MaxRetries
+}
+
+func (o *SessionOptions) SetRetryBackoff(retryBackoff time.Duration) *SessionOptions {
+	o.RetryBackoff = &retryBackoff
+	return o
+}
+
+func (o *SessionOptions) GetRetryBackoff() time.Duration {
+	if o.RetryBackoff == nil {
+		return defaultRetryBackoff
+	}
+	return *o.RetryBackoff
+}
+
+func MergeSessionOptions(opts ...*SessionOptions) *SessionOptions {
+	res := &SessionOptions{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if o.MaxRetries != nil {
+			res.MaxRetries = o.MaxRetries
+		}
+		if o.RetryBackoff != nil {
+			res.RetryBackoff = o.RetryBackoff
+		}
+	}
+	return res
+}
+
+//
+// GetUnitOfWork
+// @Description: 获取ctx所在的工作单元
+// @param ctx 上下文
+// @return *UnitOfWork
+// @return bool 是否在工作单元中
+//
+func GetUnitOfWork(ctx context.Context) (*UnitOfWork, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	uow, ok := ctx.Value(unitOfWorkKey{}).(*UnitOfWork)
+	return uow, ok
+}
+
+//
+// OnCommit
+// @Description: 注册事务提交后执行的方法，如发布消息、使缓存失效。不在工作单元中时立即执行
+// @param ctx 上下文
+// @param fun 提交后执行的方法
+//
+func OnCommit(ctx context.Context, fun CommitFunc) {
+	uow, ok := GetUnitOfWork(ctx)
+	if !ok {
+		fun(ctx)
+		return
+	}
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	uow.commits = append(uow.commits, fun)
+}
+
+//
+// OnRollback
+// @Description: 注册事务回滚后执行的方法，临时错误重试时每次回滚都会执行本次注册的方法。不在工作单元中时忽略
+// @param ctx 上下文
+// @param fun 回滚后执行的方法
+//
+func OnRollback(ctx context.Context, fun RollbackFunc) {
+	uow, ok := GetUnitOfWork(ctx)
+	if !ok {
+		return
+	}
+	uow.mu.Lock()
+	defer uow.mu.Unlock()
+	uow.rollbacks = append(uow.rollbacks, fun)
+}
+
+func (u *UnitOfWork) commit(ctx context.Context) {
+	u.mu.Lock()
+	commits := u.commits
+	u.mu.Unlock()
+	for _, fun := range commits {
+		fun(ctx)
+	}
+}
+
+func (u *UnitOfWork) rollback(ctx context.Context, err error) {
+	u.mu.Lock()
+	rollbacks := u.rollbacks
+	u.mu.Unlock()
+	for _, fun := range rollbacks {
+		fun(ctx, err)
+	}
+}
+
+//
+//  runUnitOfWork
+//  @Description: 在新的工作单元中执行事务，Session 判断为临时错误时重试
+//
+func runUnitOfWork(ctx context.Context, session Session, dbFunc SessionFunc, opts ...*SessionOptions) error {
+	opt := MergeSessionOptions(opts...)
+	checker, _ := session.(TransientErrorChecker)
+	for attempt := 1; ; attempt++ {
+		uow := &UnitOfWork{}
+		err := session.UseTransaction(context.WithValue(ctx, unitOfWorkKey{}, uow), dbFunc)
+		if err == nil {
+			uow.commit(ctx)
+			return nil
+		}
+		uow.rollback(ctx, err)
+		if checker == nil || !checker.IsTransientError(err) || attempt > opt.GetMaxRetries() {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(opt.GetRetryBackoff() * time.Duration(attempt)):
+		}
+	}
+}
@@ -3,6 +3,7 @@ package ddd
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/applog"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
@@ -27,6 +28,12 @@ type EventStorage interface {
 
 var snapshotEventsMinCount = 20
 
+// 领域事件metadata中的业务链路id与起因id
+const (
+	EventMetadataCorrelationId = "correlationId"
+	EventMetadataCausationId   = "causationId"
+)
+
 type CallEventType int
 
 const (
@@ -80,6 +87,9 @@ func LoadAggregate(ctx context.Context, tenantId string, aggregateId string, agg
 		options := &LoadAggregateOptions{
 			eventStorageKey: "",
 		}
+		if aggregate != nil {
+			options.eventStorageKey = getAggregateTypeInfo(aggregate.GetAggregateType()).EventStorageKey
+		}
 		for _, item := range opts {
 			item(options)
 		}
@@ -100,11 +110,23 @@ func SaveSnapshot(ctx context.Context, tenantId string, aggregateType string, ag
 		return err
 	}
 
+	typeInfo := getAggregateTypeInfo(aggregateType)
+	if typeInfo.Snapshot != nil && !typeInfo.Snapshot.Enabled {
+		return nil
+	}
+	eventsMinCount := snapshotEventsMinCount
+	if typeInfo.Snapshot != nil && typeInfo.Snapshot.EventsMinCount > 0 {
+		eventsMinCount = typeInfo.Snapshot.EventsMinCount
+	}
+	if len(eventStorageKey) == 0 {
+		eventStorageKey = typeInfo.EventStorageKey
+	}
+
 	req := &daprclient.LoadEventsRequest{
 		TenantId:    tenantId,
 		AggregateId: aggregateId,
 	}
-	resp, err := LoadEvents(ctx, req, "")
+	resp, err := LoadEvents(ctx, req, eventStorageKey)
 	if err != nil {
 		return err
 	}
@@ -123,7 +145,7 @@ func SaveSnapshot(ctx context.Context, tenantId string, aggregateType string, ag
 		}
 	}
 	records := *resp.EventRecords
-	if records != nil && len(records) > snapshotEventsMinCount {
+	if records != nil && len(records) > eventsMinCount {
 		sequenceNumber := uint64(0)
 		for _, record := range *resp.EventRecords {
 			sequenceNumber = record.SequenceNumber
@@ -226,11 +248,14 @@ func callDaprEventMethod(ctx context.Context, callEventType CallEventType, aggre
 	aggregateId := event.GetAggregateId()
 	aggregateType := aggregate.GetAggregateType()
 
+	typeInfo := getAggregateTypeInfo(aggregateType)
 	metadata := make(map[string]string)
+	pubsubName := typeInfo.PubsubName
+	eventStorageKey := typeInfo.EventStorageKey
 	options := &ApplyEventOptions{
-		pubsubName:      &strEmpty,
+		pubsubName:      &pubsubName,
 		metadata:        &metadata,
-		eventStorageKey: &strEmpty,
+		eventStorageKey: &eventStorageKey,
 	}
 	for _, opt := range opts {
 		if opt.eventStorageKey != nil {
@@ -263,7 +288,7 @@ func callDaprEventMethod(ctx context.Context, callEventType CallEventType, aggre
 				EventId:      event.GetEventId(),
 				EventVersion: event.GetEventVersion(),
 				EventType:    event.GetEventType(),
-				Metadata:     *options.metadata,
+				Metadata:     newEventMetadata(ctx, event, *options.metadata),
 				PubsubName:   *options.pubsubName,
 				EventData:    event,
 				Topic:        event.GetEventType(),
@@ -286,13 +311,43 @@ func callDaprEventMethod(ctx context.Context, callEventType CallEventType, aggre
 		return nil, nil
 	})
 
-	go func() {
-		_ = callActorSaveSnapshot(ctx, tenantId, aggregateId, aggregateType)
-	}()
+	if err == nil && (typeInfo.Snapshot == nil || typeInfo.Snapshot.Enabled) {
+		eventStorageKey := *options.eventStorageKey
+		go func() {
+			_ = callActorSaveSnapshot(ctx, tenantId, aggregateId, aggregateType, eventStorageKey)
+		}()
+	}
 
 	return
 }
 
+//
+//  newEventMetadata
+//  @Description: 复制事件metadata，并写入业务链路id与起因id。
+//  业务链路id取自上下文，没有时以命令id作为链路起点；起因id为产生事件的命令id。
+//  @param ctx
+//  @param event
+//  @param metadata
+//  @return map[string]string
+//
+func newEventMetadata(ctx context.Context, event DomainEvent, metadata map[string]string) map[string]string {
+	res := make(map[string]string)
+	for k, v := range metadata {
+		res[k] = v
+	}
+	if _, ok := res[EventMetadataCorrelationId]; !ok {
+		correlationId := ddd_context.GetCorrelationId(ctx)
+		if len(correlationId) == 0 {
+			correlationId = event.GetCommandId()
+		}
+		res[EventMetadataCorrelationId] = correlationId
+	}
+	if _, ok := res[EventMetadataCausationId]; !ok {
+		res[EventMetadataCausationId] = event.GetCommandId()
+	}
+	return res
+}
+
 func applyEvent(ctx context.Context, eventStorage EventStorage, tenantId, aggregateId, aggregateType string, events []*daprclient.EventDto) error {
 	req := &daprclient.ApplyEventRequest{
 		TenantId:      tenantId,
@@ -370,18 +425,24 @@ func checkEvent(aggregate Aggregate, event DomainEvent) error {
 //  @param tenantId
 //  @param aggregateId
 //  @param aggregateType
+//  @param eventStorageKey
 //  @return error
 //
-func callActorSaveSnapshot(ctx context.Context, tenantId, aggregateId, aggregateType string) error {
-	client, err := daprclient.GetDaprDDDClient().DaprClient()
+func callActorSaveSnapshot(ctx context.Context, tenantId, aggregateId, aggregateType, eventStorageKey string) error {
+	daprDddClient := daprclient.GetDaprDDDClient()
+	if daprDddClient == nil {
+		return errors.New("daprDddClient is nil")
+	}
+	client, err := daprDddClient.DaprClient()
 	if err != nil {
 		return err
 	}
 	snapshotClient := NewAggregateSnapshotClient(client, aggregateType, aggregateId)
 	_, err = snapshotClient.SaveSnapshot(ctx, &SaveSnapshotRequest{
-		TenantId:      tenantId,
-		AggregateType: aggregateType,
-		AggregateId:   aggregateId,
+		TenantId:        tenantId,
+		AggregateType:   aggregateType,
+		AggregateId:     aggregateId,
+		EventStorageKey: eventStorageKey,
 	})
 	return err
 }
@@ -415,6 +476,18 @@ func CreateAggregate(ctx context.Context, aggregate Aggregate, cmd Command, opts
 	return callCommandHandler(ctx, aggregate, cmd)
 }
 
+//
+// CallCommandHandler
+// @Description: 调用聚合根命令处理方法，不加载聚合根
+// @param ctx
+// @param aggregate
+// @param cmd
+// @return error
+//
+func CallCommandHandler(ctx context.Context, aggregate Aggregate, cmd Command) error {
+	return callCommandHandler(ctx, aggregate, cmd)
+}
+
 func callCommandHandler(ctx context.Context, aggregate Aggregate, cmd Command) error {
 	cmdTypeName := reflect.ValueOf(cmd).Elem().Type().Name()
 	methodName := fmt.Sprintf("%s", cmdTypeName)
@@ -463,6 +536,18 @@ func CallEventHandler(ctx context.Context, handler interface{}, record *daprclie
 	return err
 }
 
+//
+// CallDomainEventHandler
+// @Description: 使用领域事件对象直接调用事件监听器
+// @param ctx
+// @param handler
+// @param event
+// @return error
+//
+func CallDomainEventHandler(ctx context.Context, handler interface{}, event DomainEvent) error {
+	return callEventHandler(ctx, handler, event.GetEventType(), event.GetEventVersion(), event)
+}
+
 func callEventHandler(ctx context.Context, handler interface{}, eventType string, eventRevision string, event interface{}) error {
 	methodName := getEventMethodName(eventType, eventRevision)
 	return CallMethod(handler, methodName, ctx, event)
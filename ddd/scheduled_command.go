@@ -0,0 +1,137 @@
+package ddd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/daprclient"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
+	"time"
+)
+
+//
+// ScheduleCommand
+// @Description: 定时执行命令，到期后通过 CommandAggregate 执行。命令类型需先使用 RegisterCommandType 注册
+// @param ctx 上下文，其中的元数据在执行命令时还原
+// @param cmd 命令
+// @param at 执行时间
+// @return error
+//
+func ScheduleCommand(ctx context.Context, cmd Command, at time.Time) error {
+	scheduled, err := newScheduledCommand(ctx, cmd, at)
+	if err != nil {
+		return err
+	}
+	client, err := newScheduledCommandClient(scheduled.TenantId)
+	if err != nil {
+		return err
+	}
+	_, err = client.Schedule(ctx, &ScheduleCommandRequest{Command: scheduled})
+	return err
+}
+
+//
+// CancelScheduledCommand
+// @Description: 按命令id取消定时命令
+// @param ctx
+// @param tenantId 租户id
+// @param commandId 命令id
+// @return bool 是否找到定时命令
+// @return error
+//
+func CancelScheduledCommand(ctx context.Context, tenantId string, commandId string) (bool, error) {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return false, err
+	}
+	if err := assert.NotEmpty(commandId, assert.NewOptions("commandId is empty")); err != nil {
+		return false, err
+	}
+	client, err := newScheduledCommandClient(tenantId)
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Cancel(ctx, &CancelScheduledCommandRequest{CommandId: commandId})
+	if err != nil {
+		return false, err
+	}
+	return resp.Found, nil
+}
+
+//
+// GetScheduledCommands
+// @Description: 获取租户未执行的定时命令，按执行时间排序
+// @param ctx
+// @param tenantId 租户id
+// @return []*ScheduledCommand
+// @return error
+//
+func GetScheduledCommands(ctx context.Context, tenantId string) ([]*ScheduledCommand, error) {
+	if err := assert.NotEmpty(tenantId, assert.NewOptions("tenantId is empty")); err != nil {
+		return nil, err
+	}
+	client, err := newScheduledCommandClient(tenantId)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.List(ctx, &ListScheduledCommandsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Commands, nil
+}
+
+func newScheduledCommand(ctx context.Context, cmd Command, at time.Time) (*ScheduledCommand, error) {
+	if err := assert.NotNil(cmd, assert.NewOptions("cmd is nil")); err != nil {
+		return nil, err
+	}
+	if err := assert.NotEmpty(cmd.GetTenantId(), assert.NewOptions("tenantId is empty")); err != nil {
+		return nil, err
+	}
+	if err := assert.NotEmpty(cmd.GetCommandId(), assert.NewOptions("commandId is empty")); err != nil {
+		return nil, err
+	}
+	commandKey, err := GetCommandKey(cmd)
+	if err != nil {
+		return nil, err
+	}
+	aggregateType, err := GetCommandAggregateType(commandKey)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return nil, err
+	}
+	metadata := make(map[string]string)
+	for k, v := range *ddd_context.GetMetadataContext(ctx) {
+		metadata[k] = v
+	}
+	aggregateId := ""
+	if id := cmd.GetAggregateId(); id != nil {
+		aggregateId = id.RootId()
+	}
+	return &ScheduledCommand{
+		TenantId:      cmd.GetTenantId(),
+		CommandId:     cmd.GetCommandId(),
+		CommandType:   commandKey,
+		AggregateType: aggregateType,
+		AggregateId:   aggregateId,
+		Command:       data,
+		Metadata:      metadata,
+		DueTime:       at,
+		CreatedTime:   time.Now(),
+	}, nil
+}
+
+func newScheduledCommandClient(tenantId string) (*ScheduledCommandActor, error) {
+	daprDddClient := daprclient.GetDaprDDDClient()
+	if daprDddClient == nil {
+		return nil, errors.New("daprDddClient is nil")
+	}
+	client, err := daprDddClient.DaprClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewScheduledCommandClient(client, tenantId), nil
+}
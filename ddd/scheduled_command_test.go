@@ -0,0 +1,76 @@
+package ddd
+
+import (
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
+	"github.com/stretchr/testify/assert"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type cancelReservationCommand struct {
+	CommandId     string `json:"commandId"`
+	TenantId      string `json:"tenantId"`
+	ReservationId string `json:"reservationId"`
+}
+
+func (c *cancelReservationCommand) NewDomainEvent() DomainEvent { return nil }
+func (c *cancelReservationCommand) GetCommandId() string        { return c.CommandId }
+func (c *cancelReservationCommand) GetTenantId() string         { return c.TenantId }
+func (c *cancelReservationCommand) GetAggregateId() AggregateId {
+	return NewAggregateId(c.ReservationId)
+}
+func (c *cancelReservationCommand) GetIsValidOnly() bool { return false }
+func (c *cancelReservationCommand) Validate() error      { return nil }
+
+func TestScheduledCommand_Serialize(t *testing.T) {
+	err := RegisterCommandType("test.ReservationAggregate", func() Command { return &cancelReservationCommand{} })
+	assert.NoError(t, err)
+	err = RegisterCommandType("test.ReservationAggregate", func() Command { return &cancelReservationCommand{} })
+	assert.Error(t, err)
+
+	ctx := ddd_context.NewContext(context.Background(), map[string]string{ddd_context.CorrelationIdHeader: "corr-1"}, nil)
+	at := time.Now().Add(30 * time.Minute)
+	cmd := &cancelReservationCommand{CommandId: "c1", TenantId: "t1", ReservationId: "r1"}
+
+	scheduled, err := newScheduledCommand(ctx, cmd, at)
+	assert.NoError(t, err)
+	assert.Equal(t, "test.ReservationAggregate.cancelReservationCommand", scheduled.CommandType)
+	assert.Equal(t, "test.ReservationAggregate", scheduled.AggregateType)
+	assert.Equal(t, "r1", scheduled.AggregateId)
+	assert.Equal(t, "corr-1", scheduled.Metadata[ddd_context.CorrelationIdHeader])
+
+	restored, err := NewCommand(scheduled.CommandType, scheduled.Command)
+	assert.NoError(t, err)
+	assert.Equal(t, cmd, restored)
+
+	_, err = NewCommand("unknownCommand", nil)
+	assert.Error(t, err)
+	_, err = NewCommand("cancelReservationCommand", nil)
+	assert.Error(t, err)
+}
+
+func TestCommandTypeRegistry_SameName(t *testing.T) {
+	type otherCommand struct{ cancelReservationCommand }
+	newCancel := func() Command { return &cancelReservationCommand{} }
+	newOther := func() Command { return &otherCommand{} }
+	r := newCommandTypeRegistry()
+	assert.NoError(t, r.add("test.ReservationAggregate", "CancelCommand", reflect.TypeOf(newCancel()), newCancel))
+	assert.NoError(t, r.add("test.OrderAggregate", "CancelCommand", reflect.TypeOf(newOther()), newOther))
+	assert.Error(t, r.add("test.OrderAggregate", "CancelCommand", reflect.TypeOf(newOther()), newOther))
+	assert.Error(t, r.add("test.InvoiceAggregate", "CancelCommand", reflect.TypeOf(newOther()), newOther))
+
+	item, err := r.getByGoType(reflect.TypeOf(newOther()))
+	assert.NoError(t, err)
+	assert.Equal(t, "test.OrderAggregate.CancelCommand", item.commandKey)
+	item, err = r.get("test.ReservationAggregate.CancelCommand")
+	assert.NoError(t, err)
+	assert.Equal(t, "test.ReservationAggregate", item.aggregateType)
+}
+
+func Test_getScheduledCommandRetryDelay(t *testing.T) {
+	assert.Equal(t, time.Minute, getScheduledCommandRetryDelay(1))
+	assert.Equal(t, 2*time.Minute, getScheduledCommandRetryDelay(2))
+	assert.Equal(t, 8*time.Minute, getScheduledCommandRetryDelay(4))
+}
@@ -3,6 +3,7 @@ package ddd
 import (
 	"context"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/daprclient"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_context"
 )
 
 // Subscribe dapr消息订阅项
@@ -67,6 +68,18 @@ func (h *subscribeHandler) CallQueryEventHandler(ctx context.Context, sctx Subsc
 		return err
 	}
 	return daprclient.NewEventRecordByJsonBytes(data).OnSuccess(func(eventRecord *daprclient.EventRecord) error {
-		return CallEventHandler(ctx, h.queryEventHandler, eventRecord)
+		return CallEventHandler(newEventContext(ctx, eventRecord), h.queryEventHandler, eventRecord)
 	}).GetError()
 }
+
+//
+//  newEventContext
+//  @Description: 将事件中的业务链路id写入上下文，并以事件id作为后续处理的起因id
+//  @param ctx
+//  @param eventRecord
+//  @return context.Context
+//
+func newEventContext(ctx context.Context, eventRecord *daprclient.EventRecord) context.Context {
+	correlationId := eventRecord.Metadata[EventMetadataCorrelationId]
+	return ddd_context.NewCorrelationContext(ctx, correlationId, eventRecord.EventId)
+}
@@ -0,0 +1,298 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/applog"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/stretchr/testify/assert"
+	"strings"
+)
+
+//
+// AggregateFixture
+// @Description: 聚合根行为测试夹具，采用 Given/When/Then 方式测试命令与事件，不依赖Dapr。
+// 夹具会把内存事件存储器注册为默认事件存储器及聚合根类型设置的事件存储器，并关闭日志，
+// 这些都是进程级的设置，因此使用夹具的测试不能并行执行。
+//
+type AggregateFixture struct {
+	t            assert.TestingT
+	ctx          context.Context
+	storage      *MemoryEventStorage
+	aggregate    ddd.Aggregate
+	err          error
+	whenCalled   bool
+	ignoreFields []string
+	restores     []func()
+}
+
+//
+// Given
+// @Description: 新建测试夹具，并使用历史事件还原聚合根状态。
+// 夹具替换进程级的事件存储器与日志记录器，t 支持 Cleanup 时（如 *testing.T）在测试结束后还原，否则需调用 Restore 还原
+// @param t 测试对象
+// @param aggregate 聚合根对象
+// @param pastEvents 历史事件
+// @return *AggregateFixture
+//
+func Given(t assert.TestingT, aggregate ddd.Aggregate, pastEvents ...ddd.DomainEvent) *AggregateFixture {
+	storage := NewMemoryEventStorage()
+	f := &AggregateFixture{
+		t:            t,
+		ctx:          context.Background(),
+		storage:      storage,
+		aggregate:    aggregate,
+		ignoreFields: make([]string, 0),
+	}
+	if c, ok := t.(interface{ Cleanup(func()) }); ok {
+		c.Cleanup(f.Restore)
+	}
+
+	logger := applog.GetLogger()
+	f.restores = append(f.restores, func() { applog.SetLogger(logger) })
+	offLogger := applog.NewLogger(nil)
+	offLogger.SetLevel(applog.OFF)
+	applog.SetLogger(offLogger)
+
+	f.registerEventStorage("")
+	if info, ok := ddd.GetAggregateTypeInfo(aggregate.GetAggregateType()); ok && len(info.EventStorageKey) > 0 {
+		f.registerEventStorage(info.EventStorageKey)
+	}
+	for _, event := range pastEvents {
+		if err := ddd.CallDomainEventHandler(f.ctx, aggregate, event); err != nil {
+			t.Errorf("Given() apply event %s error: %s", event.GetEventType(), err.Error())
+			return f
+		}
+		storage.AddEvents(event.GetTenantId(), event.GetAggregateId(), event)
+	}
+	return f
+}
+
+//
+// WithContext
+// @Description: 设置执行命令时使用的上下文
+// @receiver f
+// @param ctx 上下文
+// @return *AggregateFixture
+//
+func (f *AggregateFixture) WithContext(ctx context.Context) *AggregateFixture {
+	f.ctx = ctx
+	return f
+}
+
+//
+// WithEventStorageKey
+// @Description: 将内存事件存储器注册到指定的事件存储器名称，用于命令中通过 ApplyEventOptions 指定事件存储器的情况
+// @receiver f
+// @param keys 事件存储器名称
+// @return *AggregateFixture
+//
+func (f *AggregateFixture) WithEventStorageKey(keys ...string) *AggregateFixture {
+	for _, key := range keys {
+		f.registerEventStorage(key)
+	}
+	return f
+}
+
+//
+// Restore
+// @Description: 还原夹具替换的事件存储器与日志记录器，可重复调用
+// @receiver f
+//
+func (f *AggregateFixture) Restore() {
+	for i := len(f.restores) - 1; i >= 0; i-- {
+		f.restores[i]()
+	}
+	f.restores = nil
+}
+
+//
+// IgnoreFields
+// @Description: 比较事件时忽略的字段，如 eventId、createdTime，支持 data.updatedTime 形式的路径
+// @receiver f
+// @param fields json字段名称
+// @return *AggregateFixture
+//
+func (f *AggregateFixture) IgnoreFields(fields ...string) *AggregateFixture {
+	f.ignoreFields = append(f.ignoreFields, fields...)
+	return f
+}
+
+//
+// When
+// @Description: 执行聚合根命令
+// @receiver f
+// @param cmd 命令
+// @return *AggregateFixture
+//
+func (f *AggregateFixture) When(cmd ddd.Command) *AggregateFixture {
+	f.whenCalled = true
+	f.storage.ClearCapturedEvents()
+	f.err = ddd.CallCommandHandler(f.ctx, f.aggregate, cmd)
+	return f
+}
+
+//
+// ThenEvents
+// @Description: 断言命令执行成功，并且产生的事件与期望的事件一致
+// @receiver f
+// @param expected 期望的事件
+// @return *AggregateFixture
+//
+func (f *AggregateFixture) ThenEvents(expected ...ddd.DomainEvent) *AggregateFixture {
+	if !f.checkWhen("ThenEvents") {
+		return f
+	}
+	if f.err != nil {
+		f.t.Errorf("ThenEvents() expected events, but command returned error: %s", f.err.Error())
+		return f
+	}
+	expectedText, err := f.eventsText(expected)
+	if err != nil {
+		f.t.Errorf("ThenEvents() marshal expected events error: %s", err.Error())
+		return f
+	}
+	actualText, err := f.eventsText(f.GetEvents())
+	if err != nil {
+		f.t.Errorf("ThenEvents() marshal actual events error: %s", err.Error())
+		return f
+	}
+	assert.Equal(f.t, expectedText, actualText, "ThenEvents() events do not match")
+	return f
+}
+
+//
+// ThenError
+// @Description: 断言命令执行失败。expected 不为nil时，错误需与其相同或包含其错误信息
+// @receiver f
+// @param expected 期望的错误
+// @return *AggregateFixture
+//
+func (f *AggregateFixture) ThenError(expected error) *AggregateFixture {
+	if !f.checkWhen("ThenError") {
+		return f
+	}
+	if f.err == nil {
+		f.t.Errorf("ThenError() expected error, but command succeeded with %d event(s)", len(f.GetEvents()))
+		return f
+	}
+	if expected == nil {
+		return f
+	}
+	if errors.Is(f.err, expected) || strings.Contains(f.err.Error(), expected.Error()) {
+		return f
+	}
+	f.t.Errorf("ThenError() error does not match\nexpected: %s\nactual  : %s", expected.Error(), f.err.Error())
+	return f
+}
+
+//
+// GetEvents
+// @Description: 获取 When 执行后产生的事件
+// @receiver f
+// @return []ddd.DomainEvent
+//
+func (f *AggregateFixture) GetEvents() []ddd.DomainEvent {
+	res := make([]ddd.DomainEvent, 0)
+	for _, dto := range f.storage.GetCapturedEvents() {
+		if event, ok := dto.EventData.(ddd.DomainEvent); ok {
+			res = append(res, event)
+		}
+	}
+	return res
+}
+
+//
+// GetError
+// @Description: 获取 When 执行后的错误
+// @receiver f
+// @return error
+//
+func (f *AggregateFixture) GetError() error {
+	return f.err
+}
+
+//
+// GetAggregate
+// @Description: 获取聚合根对象
+// @receiver f
+// @return ddd.Aggregate
+//
+func (f *AggregateFixture) GetAggregate() ddd.Aggregate {
+	return f.aggregate
+}
+
+//
+//  registerEventStorage
+//  @Description: 将内存事件存储器注册到指定名称，并记录原有的事件存储器用于还原
+//  @receiver f
+//  @param key 事件存储器名称
+//
+func (f *AggregateFixture) registerEventStorage(key string) {
+	previous, err := ddd.GetEventStorage(key)
+	if err != nil {
+		previous = ddd.NewEmptyEventStorage()
+	}
+	f.restores = append(f.restores, func() { ddd.RegisterEventStorage(key, previous) })
+	ddd.RegisterEventStorage(key, f.storage)
+}
+
+func (f *AggregateFixture) checkWhen(funcName string) bool {
+	if !f.whenCalled {
+		f.t.Errorf("%s() must be called after When()", funcName)
+		return false
+	}
+	return true
+}
+
+//
+//  eventsText
+//  @Description: 将事件列表转换为可比较的文本，每个事件以事件类型与版本开头
+//  @receiver f
+//  @param events
+//  @return string
+//  @return error
+//
+func (f *AggregateFixture) eventsText(events []ddd.DomainEvent) (string, error) {
+	sb := strings.Builder{}
+	for i, event := range events {
+		if event == nil {
+			sb.WriteString(fmt.Sprintf("#%d <nil>\n", i))
+			continue
+		}
+		bs, err := json.Marshal(event)
+		if err != nil {
+			return "", err
+		}
+		data := make(map[string]interface{})
+		if err = json.Unmarshal(bs, &data); err != nil {
+			return "", err
+		}
+		for _, field := range f.ignoreFields {
+			removeField(data, strings.Split(field, "."))
+		}
+		bs, err = json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(fmt.Sprintf("#%d %s %s\n", i, event.GetEventType(), event.GetEventVersion()))
+		sb.Write(bs)
+		sb.WriteString("\n")
+	}
+	return sb.String(), nil
+}
+
+func removeField(data map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	if len(path) == 1 {
+		delete(data, path[0])
+		return
+	}
+	if child, ok := data[path[0]].(map[string]interface{}); ok {
+		removeField(child, path[1:])
+	}
+}
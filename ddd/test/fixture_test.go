@@ -0,0 +1,199 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/applog"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"testing"
+	"time"
+)
+
+func TestFixture_ThenEvents(t *testing.T) {
+	Given(t, &orderAggregate{}).
+		IgnoreFields("eventId", "createdTime").
+		When(&CreateOrderCommand{CommandId: "c1", TenantId: "t1", Id: "o1", Amount: 10}).
+		ThenEvents(&OrderCreatedEvent{TenantId: "t1", CommandId: "c1", Id: "o1", Amount: 10})
+}
+
+func TestFixture_GivenPastEvents(t *testing.T) {
+	agg := &orderAggregate{}
+	f := Given(t, agg, &OrderCreatedEvent{TenantId: "t1", CommandId: "c1", Id: "o1", Amount: 10}).
+		IgnoreFields("eventId", "createdTime").
+		When(&PayOrderCommand{CommandId: "c2", TenantId: "t1", Id: "o1"}).
+		ThenEvents(&OrderPaidEvent{TenantId: "t1", CommandId: "c2", Id: "o1", Amount: 10})
+	if !agg.Paid {
+		t.Error("aggregate event handler was not called")
+	}
+	if len(f.GetEvents()) != 1 {
+		t.Errorf("expected 1 event, got %d", len(f.GetEvents()))
+	}
+}
+
+func TestFixture_ThenError(t *testing.T) {
+	Given(t, &orderAggregate{}).
+		When(&PayOrderCommand{CommandId: "c2", TenantId: "t1", Id: "o1"}).
+		ThenError(errOrderNotCreated)
+}
+
+func TestFixture_ThenEventsMismatch(t *testing.T) {
+	mock := &mockT{}
+	f := Given(mock, &orderAggregate{})
+	defer f.Restore()
+	f.IgnoreFields("eventId", "createdTime").
+		When(&CreateOrderCommand{CommandId: "c1", TenantId: "t1", Id: "o1", Amount: 10}).
+		ThenEvents(&OrderCreatedEvent{TenantId: "t1", CommandId: "c1", Id: "o1", Amount: 20})
+	if !mock.failed {
+		t.Error("expected ThenEvents() to report a mismatch")
+	}
+}
+
+func TestFixture_EventStorageKey(t *testing.T) {
+	ddd.RegisterAggregateType("test.OrderAggregate", func() ddd.Aggregate { return &orderAggregate{} },
+		ddd.AggregateEventStorageKey("orders"))
+	f := Given(t, &orderAggregate{}).
+		WithEventStorageKey("archive").
+		IgnoreFields("eventId", "createdTime").
+		When(&CreateOrderCommand{CommandId: "c1", TenantId: "t1", Id: "o1", Amount: 10}).
+		ThenEvents(&OrderCreatedEvent{TenantId: "t1", CommandId: "c1", Id: "o1", Amount: 10})
+	for _, key := range []string{"orders", "archive"} {
+		storage, err := ddd.GetEventStorage(key)
+		if err != nil || storage != f.storage {
+			t.Errorf("fixture storage is not registered as %q", key)
+		}
+	}
+}
+
+func TestFixture_Restore(t *testing.T) {
+	logger := applog.GetLogger()
+	storage, storageErr := ddd.GetEventStorage("")
+	t.Run("given", func(t *testing.T) {
+		f := Given(t, &orderAggregate{}).WithEventStorageKey("restore")
+		if s, err := ddd.GetEventStorage("restore"); err != nil || s != f.storage {
+			t.Error("fixture storage is not registered")
+		}
+	})
+	if _, err := ddd.GetEventStorage("restore"); err == nil {
+		t.Error("fixture storage is not restored")
+	}
+	if s, err := ddd.GetEventStorage(""); s != storage || (err == nil) != (storageErr == nil) {
+		t.Error("default storage is not restored")
+	}
+	if applog.GetLogger() != logger {
+		t.Error("logger is not restored")
+	}
+}
+
+type mockT struct {
+	failed bool
+}
+
+func (m *mockT) Errorf(format string, args ...interface{}) {
+	m.failed = true
+}
+
+var errOrderNotCreated = errors.New("order is not created")
+
+type orderAggregate struct {
+	Id       string  `json:"id"`
+	TenantId string  `json:"tenantId"`
+	Amount   float64 `json:"amount"`
+	Paid     bool    `json:"paid"`
+}
+
+func (a *orderAggregate) GetTenantId() string         { return a.TenantId }
+func (a *orderAggregate) GetAggregateId() string      { return a.Id }
+func (a *orderAggregate) GetAggregateType() string    { return "test.OrderAggregate" }
+func (a *orderAggregate) GetAggregateVersion() string { return "1.0" }
+
+func (a *orderAggregate) CreateOrderCommand(ctx context.Context, cmd *CreateOrderCommand, metadata *map[string]string) error {
+	return ddd.CreateEvent(ctx, a, cmd.NewDomainEvent())
+}
+
+func (a *orderAggregate) PayOrderCommand(ctx context.Context, cmd *PayOrderCommand, metadata *map[string]string) error {
+	if len(a.Id) == 0 {
+		return errOrderNotCreated
+	}
+	event := cmd.NewDomainEvent().(*OrderPaidEvent)
+	event.Amount = a.Amount
+	return ddd.ApplyEvent(ctx, a, event)
+}
+
+func (a *orderAggregate) OnOrderCreatedEventV1s0(ctx context.Context, event *OrderCreatedEvent) error {
+	a.Id = event.Id
+	a.TenantId = event.TenantId
+	a.Amount = event.Amount
+	return nil
+}
+
+func (a *orderAggregate) OnOrderPaidEventV1s0(ctx context.Context, event *OrderPaidEvent) error {
+	a.Paid = true
+	return nil
+}
+
+type CreateOrderCommand struct {
+	CommandId string  `json:"commandId"`
+	TenantId  string  `json:"tenantId"`
+	Id        string  `json:"id"`
+	Amount    float64 `json:"amount"`
+}
+
+func (c *CreateOrderCommand) GetCommandId() string            { return c.CommandId }
+func (c *CreateOrderCommand) GetTenantId() string             { return c.TenantId }
+func (c *CreateOrderCommand) GetAggregateId() ddd.AggregateId { return ddd.NewAggregateId(c.Id) }
+func (c *CreateOrderCommand) GetIsValidOnly() bool            { return false }
+func (c *CreateOrderCommand) Validate() error                 { return nil }
+func (c *CreateOrderCommand) NewDomainEvent() ddd.DomainEvent {
+	return &OrderCreatedEvent{EventId: newId(), CreatedTime: time.Now(), TenantId: c.TenantId, CommandId: c.CommandId, Id: c.Id, Amount: c.Amount}
+}
+
+type PayOrderCommand struct {
+	CommandId string `json:"commandId"`
+	TenantId  string `json:"tenantId"`
+	Id        string `json:"id"`
+}
+
+func (c *PayOrderCommand) GetCommandId() string            { return c.CommandId }
+func (c *PayOrderCommand) GetTenantId() string             { return c.TenantId }
+func (c *PayOrderCommand) GetAggregateId() ddd.AggregateId { return ddd.NewAggregateId(c.Id) }
+func (c *PayOrderCommand) GetIsValidOnly() bool            { return false }
+func (c *PayOrderCommand) Validate() error                 { return nil }
+func (c *PayOrderCommand) NewDomainEvent() ddd.DomainEvent {
+	return &OrderPaidEvent{EventId: newId(), CreatedTime: time.Now(), TenantId: c.TenantId, CommandId: c.CommandId, Id: c.Id}
+}
+
+type OrderCreatedEvent struct {
+	EventId     string    `json:"eventId"`
+	CreatedTime time.Time `json:"createdTime"`
+	TenantId    string    `json:"tenantId"`
+	CommandId   string    `json:"commandId"`
+	Id          string    `json:"id"`
+	Amount      float64   `json:"amount"`
+}
+
+func (e *OrderCreatedEvent) GetTenantId() string       { return e.TenantId }
+func (e *OrderCreatedEvent) GetCommandId() string      { return e.CommandId }
+func (e *OrderCreatedEvent) GetEventId() string        { return e.EventId }
+func (e *OrderCreatedEvent) GetEventType() string      { return "test.OrderCreatedEvent" }
+func (e *OrderCreatedEvent) GetEventVersion() string   { return "1.0" }
+func (e *OrderCreatedEvent) GetAggregateId() string    { return e.Id }
+func (e *OrderCreatedEvent) GetCreatedTime() time.Time { return e.CreatedTime }
+func (e *OrderCreatedEvent) GetData() interface{}      { return nil }
+
+type OrderPaidEvent struct {
+	EventId     string    `json:"eventId"`
+	CreatedTime time.Time `json:"createdTime"`
+	TenantId    string    `json:"tenantId"`
+	CommandId   string    `json:"commandId"`
+	Id          string    `json:"id"`
+	Amount      float64   `json:"amount"`
+}
+
+func (e *OrderPaidEvent) GetTenantId() string       { return e.TenantId }
+func (e *OrderPaidEvent) GetCommandId() string      { return e.CommandId }
+func (e *OrderPaidEvent) GetEventId() string        { return e.EventId }
+func (e *OrderPaidEvent) GetEventType() string      { return "test.OrderPaidEvent" }
+func (e *OrderPaidEvent) GetEventVersion() string   { return "1.0" }
+func (e *OrderPaidEvent) GetAggregateId() string    { return e.Id }
+func (e *OrderPaidEvent) GetCreatedTime() time.Time { return e.CreatedTime }
+func (e *OrderPaidEvent) GetData() interface{}      { return nil }
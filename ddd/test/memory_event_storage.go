@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/daprclient"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"sync"
+)
+
+//
+// MemoryEventStorage
+// @Description: 内存事件存储器，记录所有写入的领域事件，不依赖Dapr
+//
+type MemoryEventStorage struct {
+	mu         sync.Mutex
+	pubsubName string
+	events     map[string][]ddd.DomainEvent
+	captured   []*daprclient.EventDto
+}
+
+//
+// NewMemoryEventStorage
+// @Description: 新建内存事件存储器
+// @return *MemoryEventStorage
+//
+func NewMemoryEventStorage() *MemoryEventStorage {
+	return &MemoryEventStorage{
+		pubsubName: "pubsub",
+		events:     make(map[string][]ddd.DomainEvent),
+		captured:   make([]*daprclient.EventDto, 0),
+	}
+}
+
+//
+// AddEvents
+// @Description: 添加历史事件，用于加载聚合根
+// @param tenantId 租户id
+// @param aggregateId 聚合根id
+// @param events 历史事件
+//
+func (s *MemoryEventStorage) AddEvents(tenantId string, aggregateId string, events ...ddd.DomainEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.getKey(tenantId, aggregateId)
+	s.events[key] = append(s.events[key], events...)
+}
+
+//
+// GetCapturedEvents
+// @Description: 获取通过Create/Apply/Delete写入的事件
+// @return []*daprclient.EventDto
+//
+func (s *MemoryEventStorage) GetCapturedEvents() []*daprclient.EventDto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	res := make([]*daprclient.EventDto, len(s.captured))
+	copy(res, s.captured)
+	return res
+}
+
+//
+// ClearCapturedEvents
+// @Description: 清空已记录的事件
+//
+func (s *MemoryEventStorage) ClearCapturedEvents() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.captured = make([]*daprclient.EventDto, 0)
+}
+
+func (s *MemoryEventStorage) LoadAggregate(ctx context.Context, tenantId string, aggregateId string, aggregate ddd.Aggregate) (ddd.Aggregate, bool, error) {
+	if aggregate == nil {
+		return nil, false, errors.New("aggregate is nil")
+	}
+	s.mu.Lock()
+	events := append([]ddd.DomainEvent{}, s.events[s.getKey(tenantId, aggregateId)]...)
+	s.mu.Unlock()
+	if len(events) == 0 {
+		return nil, false, nil
+	}
+	for _, event := range events {
+		if err := ddd.CallDomainEventHandler(ctx, aggregate, event); err != nil {
+			return nil, false, err
+		}
+	}
+	return aggregate, true, nil
+}
+
+func (s *MemoryEventStorage) LoadEvent(ctx context.Context, req *daprclient.LoadEventsRequest) (*daprclient.LoadEventsResponse, error) {
+	records := make([]daprclient.EventRecord, 0)
+	return &daprclient.LoadEventsResponse{
+		TenantId:      req.TenantId,
+		AggregateId:   req.AggregateId,
+		AggregateType: req.AggregateType,
+		EventRecords:  &records,
+	}, nil
+}
+
+func (s *MemoryEventStorage) ApplyEvent(ctx context.Context, req *daprclient.ApplyEventRequest) (*daprclient.ApplyEventResponse, error) {
+	s.capture(req.TenantId, req.AggregateId, req.Events...)
+	return &daprclient.ApplyEventResponse{}, nil
+}
+
+func (s *MemoryEventStorage) CreateEvent(ctx context.Context, req *daprclient.CreateEventRequest) (*daprclient.CreateEventResponse, error) {
+	s.capture(req.TenantId, req.AggregateId, req.Events...)
+	return &daprclient.CreateEventResponse{}, nil
+}
+
+func (s *MemoryEventStorage) DeleteEvent(ctx context.Context, req *daprclient.DeleteEventRequest) (*daprclient.DeleteEventResponse, error) {
+	s.capture(req.TenantId, req.AggregateId, req.Event)
+	return &daprclient.DeleteEventResponse{}, nil
+}
+
+func (s *MemoryEventStorage) SaveSnapshot(ctx context.Context, req *daprclient.SaveSnapshotRequest) (*daprclient.SaveSnapshotResponse, error) {
+	return &daprclient.SaveSnapshotResponse{}, nil
+}
+
+func (s *MemoryEventStorage) GetPubsubName() string {
+	return s.pubsubName
+}
+
+func (s *MemoryEventStorage) capture(tenantId, aggregateId string, events ...*daprclient.EventDto) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.getKey(tenantId, aggregateId)
+	for _, e := range events {
+		if e == nil {
+			continue
+		}
+		if len(e.PubsubName) == 0 {
+			e.PubsubName = s.pubsubName
+		}
+		s.captured = append(s.captured, e)
+		if domainEvent, ok := e.EventData.(ddd.DomainEvent); ok {
+			s.events[key] = append(s.events[key], domainEvent)
+		}
+	}
+}
+
+func (s *MemoryEventStorage) getKey(tenantId, aggregateId string) string {
+	return tenantId + "/" + aggregateId
+}
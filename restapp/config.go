@@ -161,14 +161,26 @@ func (c *Config) GetEnvConfig(envType string) (*EnvConfig, error) {
 }
 
 type MongoConfig struct {
-	Host         string `yaml:"host"`
-	Database     string `yaml:"dbname"`
-	UserName     string `yaml:"user"`
-	Password     string `yaml:"pwd"`
-	MaxPoolSize  uint64 `yaml:"maxPoolSize"`
-	ReplicaSet   string `yaml:"replicaSet"`
-	WriteConcern string `yaml:"writeConcern"`
-	ReadConcern  string `yaml:"readConcern"`
+	Host         string             `yaml:"host"`
+	Database     string             `yaml:"dbname"`
+	UserName     string             `yaml:"user"`
+	Password     string             `yaml:"pwd"`
+	MaxPoolSize  uint64             `yaml:"maxPoolSize"`
+	ReplicaSet   string             `yaml:"replicaSet"`
+	WriteConcern string             `yaml:"writeConcern"`
+	ReadConcern  string             `yaml:"readConcern"`
+	Migrate      MongoMigrateConfig `yaml:"migrate"`
+}
+
+//
+// MongoMigrateConfig
+// @Description: 启动时执行数据迁移的配置
+//
+type MongoMigrateConfig struct {
+	Enabled    bool   `yaml:"enabled"`    // 是否在启动时执行迁移
+	DryRun     bool   `yaml:"dryRun"`     // 只输出将要执行的迁移
+	Version    *int64 `yaml:"version"`    // 目标版本号，没有设置时升级到最新，小于已执行的版本时回退
+	Collection string `yaml:"collection"` // 迁移记录的集合名称，默认为 _migrations
 }
 
 func (m MongoConfig) IsEmpty() bool {
@@ -0,0 +1,558 @@
+package restapp
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kataras/iris/v12"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/types"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeCsv  = "text/csv; charset=utf-8"
+	ContentTypeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+	// ExportTag 实体字段上声明导出列的标签，格式为 export:"[列名][,format=时间格式]"，为 - 时不导出。
+	// 列名为空时使用json名称，format 用于 time.Time、types.JSONTime、types.JSONDate 类型的字段
+	ExportTag = "export"
+
+	defaultExportPageSize = 500
+	defaultExportFileName = "export"
+)
+
+type ExportFormat string
+
+const (
+	ExportCsv  ExportFormat = "csv"
+	ExportXlsx ExportFormat = "xlsx"
+)
+
+//
+// ExportOptions
+// @Description: 导出设置
+//
+type ExportOptions struct {
+	Format    *ExportFormat // 导出格式，默认为 csv
+	FileName  *string       // 下载的文件名，不含扩展名，默认为 export
+	SheetName *string       // xlsx 的工作表名称，默认为 Sheet1
+	PageSize  *int64        // 每次从仓储读取的数量，默认为500
+}
+
+func NewExportOptions() *ExportOptions {
+	return &ExportOptions{}
+}
+
+func (o *ExportOptions) SetFormat(format ExportFormat) *ExportOptions {
+	o.Format = &format
+	return o
+}
+
+func (o *ExportOptions) GetFormat() ExportFormat {
+	if o.Format == nil || len(*o.Format) == 0 {
+		return ExportCsv
+	}
+	return *o.Format
+}
+
+func (o *ExportOptions) SetFileName(fileName string) *ExportOptions {
+	o.FileName = &fileName
+	return o
+}
+
+func (o *ExportOptions) GetFileName() string {
+	if o.FileName == nil || len(*o.FileName) == 0 {
+		return defaultExportFileName
+	}
+	return *o.FileName
+}
+
+func (o *ExportOptions) SetSheetName(sheetName string) *ExportOptions {
+	o.SheetName = &sheetName
+	return o
+}
+
+func (o *ExportOptions) GetSheetName() string {
+	if o.SheetName == nil {
+		return ""
+	}
+	return *o.SheetName
+}
+
+func (o *ExportOptions) SetPageSize(pageSize int64) *ExportOptions {
+	o.PageSize = &pageSize
+	return o
+}
+
+func (o *ExportOptions) GetPageSize() int64 {
+	if o.PageSize == nil || *o.PageSize <= 0 {
+		return defaultExportPageSize
+	}
+	return *o.PageSize
+}
+
+func MergeExportOptions(opts ...*ExportOptions) *ExportOptions {
+	res := &ExportOptions{}
+	for _, o := range opts {
+		if o == nil {
+			continue
+		}
+		if o.Format != nil {
+			res.Format = o.Format
+		}
+		if o.FileName != nil {
+			res.FileName = o.FileName
+		}
+		if o.SheetName != nil {
+			res.SheetName = o.SheetName
+		}
+		if o.PageSize != nil {
+			res.PageSize = o.PageSize
+		}
+	}
+	return res
+}
+
+//
+// DoExport
+// @Description: 按分页查询导出数据，逐页读取并写入响应，不会一次加载全部结果。
+// 列按实体字段的 export 标签生成，query 的 Fields 指定导出的列与顺序，以-开头表示排除
+// @param ctx 上下文
+// @param repos 仓储
+// @param query 查询条件，使用 TenantId、Filter、Sort、Fields，分页设置被忽略
+// @param opts 导出设置
+// @return err 错误，开始写入后的错误无法再改变响应状态，只返回错误
+//
+func DoExport[T ddd.Entity](ctx iris.Context, repos ddd_repository.Repository[T], query ddd_repository.FindPagingQuery, opts ...*ExportOptions) (err error) {
+	started := false
+	defer func() {
+		if e := ddd_errors.GetRecoverError(recover()); e != nil {
+			err = e
+		}
+		if err != nil && !started {
+			SetError(ctx, err)
+		}
+	}()
+
+	opt := MergeExportOptions(opts...)
+	contentType, ext, err := getExportContentType(opt.GetFormat())
+	if err != nil {
+		return err
+	}
+	return writeExport[T](NewContext(ctx), ctx.ResponseWriter(), repos, query, opt, func() error {
+		started = true
+		ctx.ContentType(contentType)
+		ctx.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": opt.GetFileName() + ext}))
+		ctx.Header("Cache-Control", "no-cache")
+		ctx.Header("X-Accel-Buffering", "no")
+		ctx.StatusCode(http.StatusOK)
+		return nil
+	})
+}
+
+func getExportContentType(format ExportFormat) (string, string, error) {
+	switch format {
+	case ExportCsv:
+		return ContentTypeCsv, ".csv", nil
+	case ExportXlsx:
+		return ContentTypeXlsx, ".xlsx", nil
+	}
+	return "", "", errors.New(fmt.Sprintf("export format %s is not supported", format))
+}
+
+//
+//  writeExport
+//  @Description: 逐页读取数据并写入w，读取第一页成功后调用start，之后开始写入。w 实现 http.Flusher 时每页写入后刷新
+//  @param ctx 上下文
+//  @param w 写入目标
+//  @param repos 仓储
+//  @param query 查询条件
+//  @param opt 导出设置
+//  @param start 开始写入前执行，用于设置响应头
+//  @return error
+//
+func writeExport[T ddd.Entity](ctx context.Context, w io.Writer, repos ddd_repository.Repository[T], query ddd_repository.FindPagingQuery, opt *ExportOptions, start func() error) error {
+	columns, err := getExportColumns(reflect.TypeOf((*T)(nil)).Elem(), query.GetFields())
+	if err != nil {
+		return err
+	}
+	var writer exportWriter
+	err = findExportPages[T](ctx, repos, query, opt.GetPageSize(), func(data []T) error {
+		if writer == nil {
+			if err := start(); err != nil {
+				return err
+			}
+			if writer, err = newExportWriter(w, opt); err != nil {
+				return err
+			}
+			headers := make([]string, len(columns))
+			for i, c := range columns {
+				headers[i] = c.header
+			}
+			if err := writer.WriteHeader(headers); err != nil {
+				return err
+			}
+		}
+		for _, entity := range data {
+			if err := writer.WriteRow(getExportRow(reflect.ValueOf(entity), columns)); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+//
+//  findExportPages
+//  @Description: 逐页查询，仓储返回续查令牌时按令牌续查，否则按页码查询。第一页没有数据时也会调用fun
+//
+func findExportPages[T ddd.Entity](ctx context.Context, repos ddd_repository.Repository[T], query ddd_repository.FindPagingQuery, pageSize int64, fun func(data []T) error) error {
+	pageQuery := ddd_repository.NewFindPagingQuery()
+	pageQuery.SetTenantId(query.GetTenantId())
+	pageQuery.SetFilter(query.GetFilter())
+	pageQuery.SetSort(query.GetSort())
+	pageQuery.SetFields(getExportQueryFields(query.GetFields()))
+	pageQuery.SetPageSize(pageSize)
+	pageQuery.SetIsTotalRows(false)
+	for pageNum := int64(0); ; pageNum++ {
+		pageQuery.SetPageNum(pageNum)
+		res := repos.FindPaging(ctx, pageQuery)
+		if err := res.GetError(); err != nil {
+			return err
+		}
+		var data []T
+		if res.GetData() != nil {
+			data = *res.GetData()
+		}
+		if err := fun(data); err != nil {
+			return err
+		}
+		if int64(len(data)) < pageSize {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pageQuery.SetContinuationToken(res.GetContinuationToken())
+	}
+}
+
+// 查询时只排除字段，指定导出的列时由列决定输出内容，读取全部字段
+func getExportQueryFields(fields string) string {
+	if strings.HasPrefix(strings.TrimSpace(fields), "-") {
+		return fields
+	}
+	return ""
+}
+
+type exportColumn struct {
+	name   string
+	header string
+	format string
+	index  []int
+}
+
+//
+//  getExportColumns
+//  @Description: 按实体字段生成导出列，嵌入的结构体字段展开
+//  @param t 实体类型
+//  @param fields 导出的列，以逗号分隔的json名称，以-开头表示排除，为空时导出全部
+//  @return []*exportColumn
+//  @return error 指定的列不存在
+//
+func getExportColumns(t reflect.Type, fields string) ([]*exportColumn, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New(fmt.Sprintf("export type %s is not a struct", t.String()))
+	}
+	all := make([]*exportColumn, 0)
+	appendExportColumns(t, nil, &all)
+
+	names := make([]string, 0)
+	exclude := false
+	for _, name := range strings.Split(fields, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) == 0 {
+			continue
+		}
+		if strings.HasPrefix(name, "-") {
+			exclude = true
+			name = strings.TrimPrefix(name, "-")
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return all, nil
+	}
+	columns := make(map[string]*exportColumn, len(all))
+	for _, c := range all {
+		columns[c.name] = c
+	}
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return nil, errors.New(fmt.Sprintf("export field %s does not exist in %s", name, t.String()))
+		}
+	}
+	if exclude {
+		res := make([]*exportColumn, 0, len(all))
+		for _, c := range all {
+			if !containsString(names, c.name) {
+				res = append(res, c)
+			}
+		}
+		return res, nil
+	}
+	res := make([]*exportColumn, len(names))
+	for i, name := range names {
+		res[i] = columns[name]
+	}
+	return res, nil
+}
+
+func appendExportColumns(t reflect.Type, index []int, columns *[]*exportColumn) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fieldIndex := append(append([]int{}, index...), i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			appendExportColumns(field.Type, fieldIndex, columns)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		tag, ok := field.Tag.Lookup(ExportTag)
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" && !ok {
+			continue
+		}
+		if len(name) == 0 || name == "-" {
+			name = field.Name
+		}
+		c := &exportColumn{name: name, header: name, index: fieldIndex}
+		items := strings.Split(tag, ",")
+		if len(strings.TrimSpace(items[0])) > 0 {
+			c.header = strings.TrimSpace(items[0])
+		}
+		for _, item := range items[1:] {
+			if strings.HasPrefix(item, "format=") {
+				c.format = strings.TrimPrefix(item, "format=")
+			}
+		}
+		*columns = append(*columns, c)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+type exportCellType int
+
+const (
+	exportString exportCellType = iota
+	exportNumber
+	exportBool
+)
+
+type exportCell struct {
+	value    string
+	cellType exportCellType
+}
+
+func getExportRow(entity reflect.Value, columns []*exportColumn) []exportCell {
+	for entity.Kind() == reflect.Ptr {
+		if entity.IsNil() {
+			return make([]exportCell, len(columns))
+		}
+		entity = entity.Elem()
+	}
+	row := make([]exportCell, len(columns))
+	for i, c := range columns {
+		if v, ok := getExportFieldValue(entity, c.index); ok {
+			row[i] = formatExportValue(v, c.format)
+		}
+	}
+	return row
+}
+
+// 按字段索引取值，嵌入的结构体指针为nil时返回false
+func getExportFieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, idx := range index {
+		if i > 0 {
+			for v.Kind() == reflect.Ptr {
+				if v.IsNil() {
+					return reflect.Value{}, false
+				}
+				v = v.Elem()
+			}
+		}
+		v = v.Field(idx)
+	}
+	return v, true
+}
+
+//
+//  formatExportValue
+//  @Description: 将字段值格式化为单元格。时间按 format 或 types 包的json时间格式输出，数组以逗号连接，结构体输出json
+//
+func formatExportValue(v reflect.Value, format string) exportCell {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return exportCell{}
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return exportCell{}
+	}
+	switch value := v.Interface().(type) {
+	case types.JSONTime:
+		return formatExportTime(time.Time(value), format, types.GetTimeJSONFormat())
+	case types.JSONDate:
+		return formatExportTime(time.Time(value), format, types.GetDateJSONFormat())
+	case time.Time:
+		return formatExportTime(value, format, types.GetTimeJSONFormat())
+	case fmt.Stringer:
+		return exportCell{value: value.String()}
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return exportCell{value: strconv.FormatInt(v.Int(), 10), cellType: exportNumber}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return exportCell{value: strconv.FormatUint(v.Uint(), 10), cellType: exportNumber}
+	case reflect.Float32, reflect.Float64:
+		return exportCell{value: strconv.FormatFloat(v.Float(), 'f', -1, 64), cellType: exportNumber}
+	case reflect.Bool:
+		return exportCell{value: strconv.FormatBool(v.Bool()), cellType: exportBool}
+	case reflect.String:
+		return exportCell{value: v.String()}
+	case reflect.Slice, reflect.Array:
+		items := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if !isExportScalar(v.Index(i)) {
+				return formatExportJson(v)
+			}
+			items[i] = formatExportValue(v.Index(i), format).value
+		}
+		return exportCell{value: strings.Join(items, ",")}
+	}
+	return formatExportJson(v)
+}
+
+func formatExportTime(t time.Time, format string, defaultFormat string) exportCell {
+	if t.IsZero() {
+		return exportCell{}
+	}
+	if len(format) == 0 {
+		format = defaultFormat
+	}
+	return exportCell{value: t.Format(format)}
+}
+
+func formatExportJson(v reflect.Value) exportCell {
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return exportCell{value: fmt.Sprintf("%v", v.Interface())}
+	}
+	return exportCell{value: string(data)}
+}
+
+// 数组元素是否为可直接连接的值
+func isExportScalar(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	switch v.Interface().(type) {
+	case types.JSONTime, types.JSONDate, time.Time, fmt.Stringer:
+		return true
+	}
+	return v.Kind() != reflect.Struct && v.Kind() != reflect.Map && v.Kind() != reflect.Slice && v.Kind() != reflect.Array
+}
+
+type exportWriter interface {
+	WriteHeader(headers []string) error
+	WriteRow(row []exportCell) error
+	Flush() error
+	Close() error
+}
+
+func newExportWriter(w io.Writer, opt *ExportOptions) (exportWriter, error) {
+	switch opt.GetFormat() {
+	case ExportCsv:
+		return newCsvWriter(w)
+	case ExportXlsx:
+		return newXlsxWriter(w, opt.GetSheetName())
+	}
+	return nil, errors.New(fmt.Sprintf("export format %s is not supported", opt.GetFormat()))
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// 写入UTF-8 BOM，Excel 打开时可以正确识别中文
+func newCsvWriter(w io.Writer) (*csvWriter, error) {
+	if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: csv.NewWriter(w)}, nil
+}
+
+func (c *csvWriter) WriteHeader(headers []string) error {
+	return c.writer.Write(headers)
+}
+
+// 以 = + - @ 开头的文本前加 '，避免在 Excel 中作为公式执行
+func (c *csvWriter) WriteRow(row []exportCell) error {
+	record := make([]string, len(row))
+	for i, cell := range row {
+		record[i] = cell.value
+		if cell.cellType == exportString && len(cell.value) > 0 && strings.ContainsAny(cell.value[:1], "=+-@") {
+			record[i] = "'" + cell.value
+		}
+	}
+	return c.writer.Write(record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvWriter) Close() error {
+	return c.Flush()
+}
@@ -0,0 +1,117 @@
+package restapp
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository/ddd_memory"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+type exportBase struct {
+	Id       string `json:"id" export:"编号"`
+	TenantId string `json:"tenantId" export:"-"`
+}
+
+type exportOrder struct {
+	exportBase
+	Customer  string          `json:"customer" export:"客户"`
+	Amount    float64         `json:"amount" export:"金额"`
+	Paid      bool            `json:"paid"`
+	Tags      []string        `json:"tags"`
+	OrderTime *types.JSONTime `json:"orderTime" export:"下单时间,format=2006/01/02"`
+	Remark    string          `json:"-"`
+}
+
+func (o *exportOrder) GetTenantId() string { return o.TenantId }
+func (o *exportOrder) GetId() string       { return o.Id }
+
+func newExportRepository(t *testing.T) ddd_repository.Repository[*exportOrder] {
+	repos := ddd_memory.NewRepository[*exportOrder](func() *exportOrder { return &exportOrder{} }, ddd_memory.NewMemoryDB(), "orders")
+	orderTime := types.JSONTime(time.Date(2022, 6, 1, 8, 30, 0, 0, time.Local))
+	orders := []*exportOrder{
+		{exportBase: exportBase{Id: "1", TenantId: "t1"}, Customer: "张三", Amount: 12.5, Paid: true, Tags: []string{"a", "b"}, OrderTime: &orderTime},
+		{exportBase: exportBase{Id: "2", TenantId: "t1"}, Customer: "=cmd", Amount: 3},
+		{exportBase: exportBase{Id: "3", TenantId: "t1"}, Customer: "Lee, Jr.", Amount: 7},
+		{exportBase: exportBase{Id: "4", TenantId: "t2"}, Customer: "other", Amount: 1},
+	}
+	for _, o := range orders {
+		assert.NoError(t, repos.Insert(context.Background(), o).GetError())
+	}
+	return repos
+}
+
+func newExportQuery(fields string) ddd_repository.FindPagingQuery {
+	query := ddd_repository.NewFindPagingQuery()
+	query.SetTenantId("t1")
+	query.SetFilter("amount>1")
+	query.SetSort("id")
+	query.SetFields(fields)
+	return query
+}
+
+func TestWriteExport_Csv(t *testing.T) {
+	repos := newExportRepository(t)
+	buf := &bytes.Buffer{}
+	started := 0
+	opt := NewExportOptions().SetPageSize(2)
+	err := writeExport[*exportOrder](context.Background(), buf, repos, newExportQuery(""), opt, func() error {
+		started++
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, started)
+	assert.Equal(t, "\xEF\xBB\xBF"+
+		"编号,客户,金额,paid,tags,下单时间\n"+
+		"1,张三,12.5,true,\"a,b\",2022/06/01\n"+
+		"2,'=cmd,3,false,,\n"+
+		"3,\"Lee, Jr.\",7,false,,\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeExport[*exportOrder](context.Background(), buf, repos, newExportQuery("amount,id"), opt, func() error { return nil }))
+	assert.Equal(t, "\xEF\xBB\xBF金额,编号\n12.5,1\n3,2\n7,3\n", buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeExport[*exportOrder](context.Background(), buf, repos, newExportQuery("-tags,-orderTime,-paid"), opt, func() error { return nil }))
+	assert.True(t, strings.HasPrefix(buf.String(), "\xEF\xBB\xBF编号,客户,金额\n"))
+
+	buf.Reset()
+	err = writeExport[*exportOrder](context.Background(), buf, repos, newExportQuery("remark"), opt, func() error { return nil })
+	assert.Error(t, err)
+	assert.Equal(t, 0, buf.Len())
+}
+
+func TestWriteExport_Xlsx(t *testing.T) {
+	repos := newExportRepository(t)
+	buf := &bytes.Buffer{}
+	opt := NewExportOptions().SetFormat(ExportXlsx).SetSheetName("订单/2022")
+	assert.NoError(t, writeExport[*exportOrder](context.Background(), buf, repos, newExportQuery("id,customer,amount,paid"), opt, func() error { return nil }))
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if !assert.NoError(t, err) {
+		return
+	}
+	files := make(map[string]string)
+	for _, f := range reader.File {
+		rc, err := f.Open()
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		files[f.Name] = string(data)
+	}
+	assert.Contains(t, files["xl/workbook.xml"], `<sheet name="订单_2022"`)
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<row r="1"><c s="1" t="inlineStr"><is><t xml:space="preserve">编号</t></is></c>`)
+	assert.Contains(t, sheet, `<row r="2"><c t="inlineStr"><is><t xml:space="preserve">1</t></is></c><c t="inlineStr"><is><t xml:space="preserve">张三</t></is></c><c><v>12.5</v></c><c t="b"><v>1</v></c></row>`)
+	assert.Contains(t, sheet, `<t xml:space="preserve">=cmd</t>`)
+	assert.True(t, strings.HasSuffix(sheet, `<row r="4">`+
+		`<c t="inlineStr"><is><t xml:space="preserve">3</t></is></c>`+
+		`<c t="inlineStr"><is><t xml:space="preserve">Lee, Jr.</t></is></c>`+
+		`<c><v>7</v></c><c t="b"><v>0</v></c></row></sheetData></worksheet>`))
+}
@@ -1,6 +1,8 @@
 package restapp
 
 import (
+	"context"
+	"fmt"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/assert"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository"
 	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_repository/ddd_mongodb"
@@ -31,6 +33,50 @@ func initMongo(appMongoConfig *MongoConfig) {
 	_mongodb = mongodb
 }
 
+//
+//  ensureMongoIndexes
+//  @Description: 启动时确保已创建的Mongo仓储声明的索引，索引与声明不一致时只输出提示
+//
+func ensureMongoIndexes() error {
+	if _mongodb == nil {
+		return nil
+	}
+	reports, err := _mongodb.EnsureIndexes(context.Background())
+	if err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if len(report.Created) > 0 || report.HasDrift() {
+			fmt.Printf("mongo indexes %s\r\n", report.String())
+		}
+	}
+	return nil
+}
+
+//
+//  runMongoMigrations
+//  @Description: 启动时按配置执行已注册的Mongo数据迁移
+//
+func runMongoMigrations(config *MongoMigrateConfig) error {
+	if _mongodb == nil || !config.Enabled {
+		return nil
+	}
+	opts := ddd_mongodb.NewMigrateOptions().SetDryRun(config.DryRun).SetCollection(config.Collection)
+	migrator := _mongodb.NewMigrator(opts)
+	var result *ddd_mongodb.MigrateResult
+	var err error
+	if config.Version != nil {
+		result, err = migrator.MigrateTo(context.Background(), *config.Version)
+	} else {
+		result, err = migrator.Up(context.Background())
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("mongo migrations %s\r\n", result.String())
+	return nil
+}
+
 func GetMongoDB() *ddd_mongodb.MongoDB {
 	return _mongodb
 }
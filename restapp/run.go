@@ -65,6 +65,7 @@ var EmptyActors = func() *[]actor.Factory {
 var Actors = func() *[]actor.Factory {
 	return &[]actor.Factory{
 		aggregateSnapshotActorFactory,
+		scheduledCommandActorFactory,
 	}
 }
 
@@ -76,6 +77,14 @@ func aggregateSnapshotActorFactory() actor.Server {
 	return ddd.NewAggregateSnapshotActorService(client)
 }
 
+func scheduledCommandActorFactory() actor.Server {
+	client, err := daprclient.GetDaprDDDClient().DaprClient()
+	if err != nil {
+		panic(err)
+	}
+	return ddd.NewScheduledCommandActorService(client)
+}
+
 func RunWithConfig(envType string, configFile string, subsFunc func() *[]RegisterSubscribe,
 	controllersFunc func() *[]Controller, eventsFunc func() *[]RegisterEventType, actorsFunc func() *[]actor.Factory) (common.Service, error) {
 	config, err := NewConfigByFile(configFile)
@@ -99,6 +108,9 @@ func RubWithEnvConfig(config *EnvConfig, subsFunc func() *[]RegisterSubscribe,
 	controllersFunc func() *[]Controller, eventsFunc func() *[]RegisterEventType, actorsFunc func() *[]actor.Factory) (common.Service, error) {
 	if !config.Mongo.IsEmpty() {
 		initMongo(&config.Mongo)
+		if err := runMongoMigrations(&config.Mongo.Migrate); err != nil {
+			return nil, err
+		}
 	}
 
 	//创建dapr客户端
@@ -164,6 +176,10 @@ func Run(options *StartOptions, webRootPath string, subsFunc func() *[]RegisterS
 		AuthToken:      "",
 		WebRootPath:    webRootPath,
 	}
+	// 仓储在注册控制器与订阅时创建，此时可以确保索引
+	if err := ensureMongoIndexes(); err != nil {
+		return nil, err
+	}
 	service := NewService(options.DaprClient, serverOptions)
 	if err := service.Start(); err != nil {
 		return service, err
@@ -108,6 +108,9 @@ func (s *service) Start() error {
 	// register domain event types
 	app.Get("dapr/event-types", s.eventTypesHandler)
 
+	// register aggregate type catalog
+	app.Get("dapr/aggregate-types", s.aggregateTypesHandler)
+
 	//	register health check handler
 	app.Get("/healthz", s.healthHandler)
 
@@ -260,6 +263,11 @@ func (s *service) eventTypesHandler(ctx *context.Context) {
 
 }
 
+func (s *service) aggregateTypesHandler(ctx *context.Context) {
+	data := ddd.GetAggregateTypeInfos()
+	_, _ = ctx.JSON(data)
+}
+
 func (s *service) healthHandler(context *context.Context) {
 	context.StatusCode(http.StatusOK)
 }
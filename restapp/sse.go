@@ -0,0 +1,122 @@
+package restapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/kataras/iris/v12"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/ddd/ddd_errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ContentTypeEventStream = "text/event-stream"
+	HeaderLastEventId      = "Last-Event-ID"
+
+	sseHeartbeat = 15 * time.Second
+)
+
+//
+// SseEvent
+// @Description: 推送的数据实现此接口时，使用其返回值作为SSE的 id 与 event 字段
+//
+type SseEvent interface {
+	GetSseId() string
+	GetSseEvent() string
+}
+
+//
+// SseFunc
+// @Description: 打开推送的数据通道，通道关闭时结束推送
+// @param ctx 上下文，客户端断开时取消
+// @param lastEventId 客户端重连时请求头 Last-Event-ID 的值，用于续接
+//
+type SseFunc[T interface{}] func(ctx context.Context, lastEventId string) (<-chan T, error)
+
+//
+// DoSse
+// @Description: 以 Server-Sent Events 推送数据，数据以json格式写入 data 字段。客户端断开或通道关闭时返回
+// @param ctx 上下文
+// @param fun 打开数据通道的方法
+// @return err 错误
+//
+func DoSse[T interface{}](ctx iris.Context, fun SseFunc[T]) (err error) {
+	defer func() {
+		if e := ddd_errors.GetRecoverError(recover()); e != nil {
+			err = e
+		}
+	}()
+
+	flusher, ok := ctx.ResponseWriter().Flusher()
+	if !ok {
+		err = errors.New("response writer does not support flush")
+		SetError(ctx, err)
+		return err
+	}
+
+	restCtx, cancel := context.WithCancel(NewContext(ctx))
+	defer cancel()
+	go func() {
+		select {
+		case <-ctx.Request().Context().Done():
+			cancel()
+		case <-restCtx.Done():
+		}
+	}()
+
+	events, err := fun(restCtx, ctx.GetHeader(HeaderLastEventId))
+	if err != nil {
+		SetError(ctx, err)
+		return err
+	}
+
+	ctx.ContentType(ContentTypeEventStream)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.StatusCode(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-restCtx.Done():
+			return nil
+		case <-heartbeat.C:
+			if _, err := ctx.WriteString(": ping\n\n"); err != nil {
+				return err
+			}
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if err := writeSseEvent(ctx, event); err != nil {
+				return err
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeSseEvent(ctx iris.Context, event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	sb := strings.Builder{}
+	if e, ok := event.(SseEvent); ok {
+		if id := e.GetSseId(); len(id) > 0 {
+			sb.WriteString("id: " + id + "\n")
+		}
+		if name := e.GetSseEvent(); len(name) > 0 {
+			sb.WriteString("event: " + name + "\n")
+		}
+	}
+	sb.WriteString("data: " + string(data) + "\n\n")
+	_, err = ctx.WriteString(sb.String())
+	return err
+}
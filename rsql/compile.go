@@ -0,0 +1,429 @@
+package rsql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//
+// Predicate
+// @Description: 编译后的rsql表达式，判断结构体或map是否满足条件
+//
+type Predicate func(data interface{}) bool
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	dateTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05Z0700", "2006-01-02T15:04:05"}
+	stringLayouts   = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+)
+
+//
+// Compile
+// @Description: 将rsql表达式编译为内存过滤条件。字段按json标签、字段名称匹配，忽略大小写，支持 a.b 形式的嵌套路径；
+// 路径中的数组任一元素满足即可，与 mongodb 的数组查询语义一致。!=、!=~、=out= 在字段不存在时也满足
+// @param input rsql表达式，为空时所有数据都满足
+// @return Predicate
+// @return error 表达式错误
+//
+func Compile(input string) (Predicate, error) {
+	if len(strings.TrimSpace(input)) == 0 {
+		return func(data interface{}) bool { return true }, nil
+	}
+	expr, err := Parse(input)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("rsql %s expression error, %s", input, err.Error()))
+	}
+	p, err := compileExpression(expr)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("rsql %s compile error, %s", input, err.Error()))
+	}
+	return p, nil
+}
+
+func compileExpression(expr Expression) (Predicate, error) {
+	switch ex := expr.(type) {
+	case AndExpression:
+		items, err := compileExpressions(ex.Items)
+		if err != nil {
+			return nil, err
+		}
+		return func(data interface{}) bool {
+			for _, item := range items {
+				if !item(data) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	case OrExpression:
+		items, err := compileExpressions(ex.Items)
+		if err != nil {
+			return nil, err
+		}
+		return func(data interface{}) bool {
+			for _, item := range items {
+				if item(data) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case EqualsComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c == 0 }, false), nil
+	case NotEqualsComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c == 0 }, true), nil
+	case GreaterThanComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c > 0 }, false), nil
+	case GreaterThanOrEqualsComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c >= 0 }, false), nil
+	case LessThanComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c < 0 }, false), nil
+	case LessThanOrEqualsComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c <= 0 }, false), nil
+	case InComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c == 0 }, false), nil
+	case NotInComparison:
+		return newComparePredicate(ex.Comparison, func(c int) bool { return c == 0 }, true), nil
+	case LikeComparison:
+		return newLikePredicate(ex.Comparison, false)
+	case NotLikeComparison:
+		return newLikePredicate(ex.Comparison, true)
+	case SearchComparison:
+		return newSearchPredicate(ex.Comparison), nil
+	}
+	return nil, errors.New(fmt.Sprintf("expression %s is not supported", expr.ExpressionName()))
+}
+
+func compileExpressions(list []Expression) ([]Predicate, error) {
+	items := make([]Predicate, len(list))
+	for i, e := range list {
+		p, err := compileExpression(e)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = p
+	}
+	return items, nil
+}
+
+//
+//  newComparePredicate
+//  @Description: 比较字段值与表达式的值，表达式的值为列表时与任一元素比较
+//  @param comparison 比较表达式
+//  @param test 比较结果是否满足
+//  @param not 是否取反
+//
+func newComparePredicate(comparison Comparison, test func(int) bool, not bool) Predicate {
+	path := strings.Split(comparison.Identifier.Val, ".")
+	values := []Value{comparison.Val}
+	if list, ok := comparison.Val.(ListValue); ok {
+		values = list.Value
+	}
+	return func(data interface{}) bool {
+		ok := anyFieldValue(data, path, func(field interface{}) bool {
+			for _, v := range values {
+				if c, ok := compareValue(field, v); ok && test(c) {
+					return true
+				}
+			}
+			return false
+		})
+		return ok != not
+	}
+}
+
+// 模糊查询，值为忽略大小写的正则表达式
+func newLikePredicate(comparison Comparison, not bool) (Predicate, error) {
+	path := strings.Split(comparison.Identifier.Val, ".")
+	re, err := regexp.Compile("(?im)" + fmt.Sprintf("%v", GetValue(comparison.Val)))
+	if err != nil {
+		return nil, err
+	}
+	return func(data interface{}) bool {
+		ok := anyFieldValue(data, path, func(field interface{}) bool {
+			s, isStr := field.(string)
+			return isStr && re.MatchString(s)
+		})
+		return ok != not
+	}, nil
+}
+
+// 全文检索，字段中包含任一检索词即可，忽略大小写
+func newSearchPredicate(comparison Comparison) Predicate {
+	path := strings.Split(comparison.Identifier.Val, ".")
+	text := strings.ToLower(strings.ReplaceAll(fmt.Sprintf("%v", GetValue(comparison.Val)), "\"", " "))
+	terms := strings.Fields(text)
+	return func(data interface{}) bool {
+		return anyFieldValue(data, path, func(field interface{}) bool {
+			s, isStr := field.(string)
+			if !isStr {
+				return false
+			}
+			s = strings.ToLower(s)
+			for _, term := range terms {
+				if strings.Contains(s, term) {
+					return true
+				}
+			}
+			return false
+		})
+	}
+}
+
+//
+//  anyFieldValue
+//  @Description: 按路径取字段值，路径中遇到数组时展开，任一值满足match即可。
+//  值转换为 float64、string、bool、time.Time 或 nil 后传给match
+//
+func anyFieldValue(data interface{}, path []string, match func(field interface{}) bool) bool {
+	return anyValue(reflect.ValueOf(data), path, match)
+}
+
+func anyValue(v reflect.Value, path []string, match func(field interface{}) bool) bool {
+	v = indirect(v)
+	if v.IsValid() && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			if anyValue(v.Index(i), path, match) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return match(normalizeValue(v))
+	}
+	field, ok := getField(v, path[0])
+	if !ok {
+		return false
+	}
+	return anyValue(field, path[1:], match)
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+//
+//  getField
+//  @Description: 获取结构体字段或map的值。先按json名称、字段名称精确匹配，再忽略大小写与下划线匹配，id 与 _id 等同
+//
+func getField(v reflect.Value, name string) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return reflect.Value{}, false
+		}
+		if value := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key())); value.IsValid() {
+			return value, true
+		}
+		key := normalizeName(name)
+		iter := v.MapRange()
+		for iter.Next() {
+			if normalizeName(iter.Key().String()) == key {
+				return iter.Value(), true
+			}
+		}
+	case reflect.Struct:
+		if value, ok := getStructField(v, name, func(s string) string { return s }); ok {
+			return value, true
+		}
+		return getStructField(v, name, normalizeName)
+	}
+	return reflect.Value{}, false
+}
+
+func getStructField(v reflect.Value, name string, normalize func(string) string) (reflect.Value, bool) {
+	name = normalize(name)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "-" {
+			continue
+		}
+		if (len(jsonName) > 0 && normalize(jsonName) == name) || (len(jsonName) == 0 && normalize(field.Name) == name) {
+			return v.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.Anonymous || len(field.Tag.Get("json")) > 0 {
+			continue
+		}
+		if embedded := indirect(v.Field(i)); embedded.IsValid() && embedded.Kind() == reflect.Struct {
+			if value, ok := getStructField(embedded, name, normalize); ok {
+				return value, true
+			}
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, "_"), "_", ""))
+}
+
+//
+//  normalizeValue
+//  @Description: 数字转换为 float64，可转换为 time.Time 的类型（如 types.JSONTime）转换为 time.Time
+//
+func normalizeValue(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().ConvertibleTo(timeType) && v.Kind() == reflect.Struct {
+		return v.Convert(timeType).Interface()
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	if v.CanInterface() {
+		return v.Interface()
+	}
+	return nil
+}
+
+//
+//  compareValue
+//  @Description: 比较字段值与表达式的值，按表达式值的类型转换字段值：数字与可解析为数字的字符串按数值比较，
+//  日期按字段时间的日期部分比较，日期时间与可解析为时间的字符串按时间比较
+//  @return int 比较结果，-1 小于，0 等于，1 大于
+//  @return bool 是否可以比较
+//
+func compareValue(field interface{}, value Value) (int, bool) {
+	if field == nil {
+		return 0, false
+	}
+	switch v := value.(type) {
+	case IntegerValue:
+		return compareNumber(field, float64(v.Value))
+	case DoubleValue:
+		return compareNumber(field, v.Value)
+	case BooleanValue:
+		switch f := field.(type) {
+		case bool:
+			return compareBool(f, v.Value), true
+		case string:
+			if b, err := strconv.ParseBool(f); err == nil {
+				return compareBool(b, v.Value), true
+			}
+		}
+		return 0, false
+	case DateValue:
+		t, ok := toTime(field)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(t.In(time.Local).Format("2006-01-02"), v.Value), true
+	case DateTimeValue:
+		t, ok := toTime(field)
+		if !ok {
+			return 0, false
+		}
+		dt, ok := parseTime(v.Value, dateTimeLayouts)
+		if !ok {
+			return 0, false
+		}
+		return compareTime(t, dt), true
+	case StringValue:
+		switch f := field.(type) {
+		case string:
+			return strings.Compare(f, v.Value), true
+		case float64:
+			if n, err := strconv.ParseFloat(v.Value, 64); err == nil {
+				return compareFloat(f, n), true
+			}
+		case bool:
+			if b, err := strconv.ParseBool(v.Value); err == nil {
+				return compareBool(f, b), true
+			}
+		case time.Time:
+			if t, ok := parseTime(v.Value, stringLayouts); ok {
+				return compareTime(f, t), true
+			}
+		}
+	}
+	return 0, false
+}
+
+func compareNumber(field interface{}, value float64) (int, bool) {
+	switch f := field.(type) {
+	case float64:
+		return compareFloat(f, value), true
+	case string:
+		if n, err := strconv.ParseFloat(strings.TrimSpace(f), 64); err == nil {
+			return compareFloat(n, value), true
+		}
+	}
+	return 0, false
+}
+
+func compareFloat(a, b float64) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
+}
+
+func compareBool(a, b bool) int {
+	if a == b {
+		return 0
+	} else if !a {
+		return -1
+	}
+	return 1
+}
+
+func compareTime(a, b time.Time) int {
+	if a.Before(b) {
+		return -1
+	} else if a.After(b) {
+		return 1
+	}
+	return 0
+}
+
+func toTime(field interface{}) (time.Time, bool) {
+	switch f := field.(type) {
+	case time.Time:
+		return f, !f.IsZero()
+	case string:
+		return parseTime(f, stringLayouts)
+	}
+	return time.Time{}, false
+}
+
+// 没有时区的时间按本地时间解析，与 types.JSONTime 一致
+func parseTime(s string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
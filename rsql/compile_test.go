@@ -0,0 +1,125 @@
+package rsql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type jsonTime time.Time
+
+type baseEntity struct {
+	Id       string `json:"id"`
+	TenantId string `json:"tenantId"`
+}
+
+type director struct {
+	LastName string `json:"lastName"`
+}
+
+type award struct {
+	Name string `json:"name"`
+	Year int32  `json:"year"`
+}
+
+type movie struct {
+	baseEntity
+	Title     string    `json:"title"`
+	Year      int       `json:"year"`
+	Rating    *float64  `json:"rating"`
+	Released  bool      `json:"released"`
+	Genres    []string  `json:"genres"`
+	Director  *director `json:"director"`
+	Awards    []award   `json:"awards"`
+	Premiere  *jsonTime `json:"premiere"`
+	CreatedBy string    `json:"created_by"`
+	Secret    string    `json:"-"`
+}
+
+func TestCompile(t *testing.T) {
+	rating := 8.8
+	premiere := jsonTime(time.Date(2010, 7, 8, 20, 30, 0, 0, time.Local))
+	inception := &movie{
+		baseEntity: baseEntity{Id: "1", TenantId: "t1"},
+		Title:      "Inception",
+		Year:       2010,
+		Rating:     &rating,
+		Released:   true,
+		Genres:     []string{"sci-fi", "action"},
+		Director:   &director{LastName: "Nolan"},
+		Awards:     []award{{Name: "Oscar", Year: 2011}, {Name: "BAFTA", Year: 2011}},
+		Premiere:   &premiere,
+		CreatedBy:  "admin",
+		Secret:     "x",
+	}
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{"", true},
+		{"id=='1' and tenantId=='t1'", true},
+		{"title=='Inception' and year==2010", true},
+		{"year>2005 and year<=2010 and rating>=8.5", true},
+		{"year=='2010'", true},
+		{"rating<8", false},
+		{"released==true", true},
+		{"genres=in=('drama','action')", true},
+		{"genres=out=('drama','action')", false},
+		{"genres!='action'", false},
+		{"director.lastName=='Nolan'", true},
+		{"director.LASTNAME=='Nolan'", true},
+		{"awards.name=='BAFTA' and awards.year==2011", true},
+		{"awards.year=in=(2009,2010)", false},
+		{"premiere==2010-07-08", true},
+		{"premiere>2010-07-08", false},
+		{"premiere>2010-07-08T20:00:00 and premiere<'2010-07-09'", true},
+		{"createdBy=='admin' and created_by=='admin'", true},
+		{"secret=='x'", false},
+		{"missing!='x' and missing=out=(1,2)", true},
+		{"missing=='x'", false},
+		{"title==~'^incep' and title!=~'matrix'", true},
+		{"title=search='matrix \"INCEPTION\"'", true},
+		{"(title=='Memento' or year==2010) and director.lastName=='Nolan'", true},
+	}
+	for _, test := range tests {
+		predicate, err := Compile(test.filter)
+		if !assert.NoError(t, err, test.filter) {
+			continue
+		}
+		assert.Equal(t, test.match, predicate(inception), test.filter)
+	}
+
+	_, err := Compile("title==")
+	assert.Error(t, err)
+	_, err = Compile("title==~'('")
+	assert.Error(t, err)
+}
+
+func TestCompile_Map(t *testing.T) {
+	doc := map[string]interface{}{
+		"_id":        "1",
+		"tenant_id":  "t1",
+		"year":       float64(2010),
+		"created_at": "2022-06-01 08:30:00",
+		"tags":       []interface{}{"a", "b"},
+		"director":   map[string]interface{}{"last_name": "Nolan"},
+	}
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{"id=='1' and tenantId=='t1'", true},
+		{"year==2010 and year=in=(2009,2010)", true},
+		{"createdAt>=2022-06-01 and createdAt<2022-06-01T09:00:00", true},
+		{"createdAt>'2022-06-01 09:00:00'", false},
+		{"tags=='b'", true},
+		{"director.lastName=='Nolan'", true},
+	}
+	for _, test := range tests {
+		predicate, err := Compile(test.filter)
+		if !assert.NoError(t, err, test.filter) {
+			continue
+		}
+		assert.Equal(t, test.match, predicate(doc), test.filter)
+	}
+}
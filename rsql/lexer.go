@@ -30,6 +30,7 @@ const (
 	LessOrEqualsToken    = TokenType("LessOrEqualsToken")
 	InToken              = TokenType("InToken")
 	NotInToken           = TokenType("NotInToken")
+	SearchToken          = TokenType("SearchToken")
 	CommaToken           = TokenType("CommaToken")
 	EOFToken             = TokenType("EOFToken")
 )
@@ -261,6 +262,8 @@ func (t *Lexer) processReserved() Token {
 		return t.generateToken(InToken, idx+4)
 	} else if t.isString(idx, "=out=") {
 		return t.generateToken(NotInToken, idx+5)
+	} else if t.isString(idx, "=search=") {
+		return t.generateToken(SearchToken, idx+8)
 	}
 	return unknownToken()
 }
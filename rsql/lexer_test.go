@@ -89,13 +89,14 @@ func TestLexer_Parse_String(t *testing.T) {
 }
 
 func TestLexer_Parse_Reserved(t *testing.T) {
-	lex := NewLexer(`== != !=~ ==~ =in= =out= > >= < <= , ( )`)
+	lex := NewLexer(`== != !=~ ==~ =in= =out= =search= > >= < <= , ( )`)
 	assert.Equal(t, lex.nextToken().Type, EqualsToken)
 	assert.Equal(t, lex.nextToken().Type, NotEqualsToken)
 	assert.Equal(t, lex.nextToken().Type, NotLikeToken)
 	assert.Equal(t, lex.nextToken().Type, LikeToken)
 	assert.Equal(t, lex.nextToken().Type, InToken)
 	assert.Equal(t, lex.nextToken().Type, NotInToken)
+	assert.Equal(t, lex.nextToken().Type, SearchToken)
 	assert.Equal(t, lex.nextToken().Type, GreaterToken)
 	assert.Equal(t, lex.nextToken().Type, GreaterOrEqualsToken)
 	assert.Equal(t, lex.nextToken().Type, LessToken)
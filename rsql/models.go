@@ -90,3 +90,7 @@ func (InComparison) ExpressionName() string { return "=in=" }
 type NotInComparison struct{ Comparison }
 
 func (NotInComparison) ExpressionName() string { return "=out=" }
+
+type SearchComparison struct{ Comparison }
+
+func (SearchComparison) ExpressionName() string { return "=search=" }
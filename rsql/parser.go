@@ -207,6 +207,8 @@ func comparison(tokens *iterator) (Expression, error) {
 			lv = tmp
 		}
 		return NotInComparison{Comparison{id, lv}}, nil
+	case SearchToken:
+		return SearchComparison{Comparison{id, args}}, nil
 	}
 	return nil, fmt.Errorf("'comparator not managed for expression")
 }
@@ -35,6 +35,8 @@ func TestParse_Comparison(t *testing.T) {
 	assert.IsType(t, InComparison{}, v)
 	v, _ = Parse("toto=out=(42,43)")
 	assert.IsType(t, NotInComparison{}, v)
+	v, _ = Parse("toto=search='star wars'")
+	assert.IsType(t, SearchComparison{}, v)
 }
 
 func TestParse_And(t *testing.T) {
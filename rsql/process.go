@@ -22,6 +22,7 @@ type Process interface {
 	OnLessThanOrEquals(name string, value interface{}, rValue Value)
 	OnIn(name string, value interface{}, rValue Value)
 	OnNotIn(name string, value interface{}, rValue Value)
+	OnSearch(name string, value interface{}, rValue Value)
 }
 
 type process struct {
@@ -64,6 +65,10 @@ func (p *process) OnNotIn(name string, value interface{}, rValue Value) {
 	p.str = fmt.Sprintf("%s %s not in %v", p.str, name, value)
 }
 
+func (p *process) OnSearch(name string, value interface{}, rValue Value) {
+	p.str = fmt.Sprintf("%s %s search %v", p.str, name, value)
+}
+
 func (p *process) OnEquals(name string, value interface{}, rValue Value) {
 	p.str = fmt.Sprintf("%s %s=%v", p.str, name, value)
 }
@@ -193,6 +198,12 @@ func parseProcess(expr Expression, process Process) error {
 		value := getValue(ex.Comparison.Val)
 		process.OnNotIn(name, value, ex.Comparison.Val)
 		break
+	case SearchComparison:
+		ex, _ := expr.(SearchComparison)
+		name := ex.Comparison.Identifier.Val
+		value := getValue(ex.Comparison.Val)
+		process.OnSearch(name, value, ex.Comparison.Val)
+		break
 	}
 	return nil
 }
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/liuxd6825/dapr-go-ddd-sdk/rsql"
+	"sort"
 )
 
 type Item interface {
@@ -166,6 +168,30 @@ func (t *Items[T]) Get(id string) (T, bool) {
 	return item, ok
 }
 
+//
+// Filter
+// @Description: 按rsql表达式过滤明细，表达式与查询接口的 filter 语法相同
+// @param filter rsql表达式，为空时返回全部
+// @return []T 满足条件的明细，按Id排序
+// @return error 表达式错误
+//
+func (t *Items[T]) Filter(filter string) ([]T, error) {
+	predicate, err := rsql.Compile(filter)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]T, 0)
+	for _, item := range t.items {
+		if predicate(item) {
+			res = append(res, item)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].GetId() < res[j].GetId()
+	})
+	return res, nil
+}
+
 func (t *Items[T]) MapData() map[string]T {
 	return t.items
 }
@@ -0,0 +1,53 @@
+package types
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type orderItem struct {
+	Id        string   `json:"id"`
+	Product   string   `json:"product"`
+	Quantity  int      `json:"quantity"`
+	OrderTime JSONTime `json:"orderTime"`
+}
+
+func (o *orderItem) GetId() string {
+	return o.Id
+}
+
+func TestItems_Filter(t *testing.T) {
+	ctx := context.Background()
+	items := NewItems[*orderItem](func() interface{} { return &orderItem{} })
+	orderTime := JSONTime(time.Date(2022, 6, 1, 8, 30, 0, 0, time.Local))
+	for _, item := range []*orderItem{
+		{Id: "3", Product: "pen", Quantity: 10, OrderTime: orderTime},
+		{Id: "1", Product: "book", Quantity: 2, OrderTime: orderTime},
+		{Id: "2", Product: "pencil", Quantity: 5},
+	} {
+		if _, err := items.AddMapper(ctx, item.Id, item); err != nil {
+			t.Error(err)
+		}
+	}
+
+	res, err := items.Filter("product==~'^pen' and quantity>=5")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(res) != 2 || res[0].Id != "2" || res[1].Id != "3" {
+		t.Errorf("filter result is error, %v", res)
+	}
+
+	res, err = items.Filter("orderTime==2022-06-01")
+	if err != nil {
+		t.Error(err)
+	}
+	if len(res) != 2 || res[0].Id != "1" || res[1].Id != "3" {
+		t.Errorf("filter date result is error, %v", res)
+	}
+
+	if _, err = items.Filter("quantity=="); err == nil {
+		t.Error("filter expression error expected")
+	}
+}